@@ -1,27 +1,127 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/soerenschneider/gobot-bme280/internal"
 	"github.com/soerenschneider/gobot-bme280/internal/config"
 	"gobot.io/x/gobot/v2/drivers/i2c"
-	"gobot.io/x/gobot/v2/platforms/mqtt"
-	"gobot.io/x/gobot/v2/platforms/raspi"
+	"gopkg.in/natefinch/lumberjack.v2"
 )
 
 const (
-	cliConfFile = "config"
-	cliVersion  = "version"
+	cliConfFile             = "config"
+	cliVersion              = "version"
+	cliPlacement            = "placement"
+	cliMqttHost             = "mqtt-host"
+	cliTopic                = "topic"
+	cliInterval             = "interval"
+	cliGpioAddress          = "gpio-address"
+	cliBench                = "bench"
+	cliSelfTest             = "selftest"
+	cliCalibrateTemperature = "calibrate-temp"
+	cliCalibrateHumidity    = "calibrate-humidity"
+	cliCalibratePressure    = "calibrate-pressure"
+	cliMaxRuntime           = "max-runtime"
+	cliConfOptional         = "config-optional"
+
+	startupBackoffInitial = 1 * time.Second
+	startupBackoffMax     = 60 * time.Second
+
+	// shutdownTimeout is the time in-flight scrapes of the metrics are given on shutdown
+	shutdownTimeout = 2 * time.Second
+
+	exitCodeUnexpected       = 1
+	exitCodeConfigParse      = 2
+	exitCodeConfigValidation = 3
+	exitCodeStartup          = 4
+	exitCodePublishFailures  = 5
+	exitCodeSelfTest         = 6
+	exitCodeCalibration      = 7
 )
 
+// configFiles collects the values of a repeatable flag.
+type configFiles []string
+
+func (c *configFiles) String() string {
+	return strings.Join(*c, ",")
+}
+
+func (c *configFiles) Set(value string) error {
+	*c = append(*c, value)
+	return nil
+}
+
+// existing returns the files that exist, the missing files are skipped.
+func (c configFiles) existing() configFiles {
+	var ret configFiles
+	for _, file := range c {
+		if _, err := os.Stat(file); errors.Is(err, fs.ErrNotExist) {
+			slog.Info("Config file does not exist, skipping it", "file", file)
+			continue
+		}
+		ret = append(ret, file)
+	}
+	return ret
+}
+
+// referenceValue is the value of a calibration flag, which is only calibrated against if the flag is set.
+type referenceValue struct {
+	value float64
+	set   bool
+}
+
+func (r *referenceValue) String() string {
+	if !r.set {
+		return ""
+	}
+	return strconv.FormatFloat(r.value, 'f', -1, 64)
+}
+
+func (r *referenceValue) Set(value string) error {
+	parsed, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		return err
+	}
+	r.value, r.set = parsed, true
+	return nil
+}
+
 func main() {
-	var configFile string
-	flag.StringVar(&configFile, cliConfFile, "", "File to read configuration from")
+	var files configFiles
+	flag.Var(&files, cliConfFile, "File to read configuration from, can be repeated to overlay multiple files")
 	version := flag.Bool(cliVersion, false, "Print version and exit")
+	bench := flag.Duration(cliBench, 0, "Read the sensor as fast as possible for the given duration, print statistics and exit")
+	selfTest := flag.Bool(cliSelfTest, false, "Read the sensor once, print the values and exit, without connecting to MQTT or serving metrics")
+	references := map[string]*referenceValue{"temperature": {}, "humidity": {}, "pressure": {}}
+	flag.Var(references["temperature"], cliCalibrateTemperature, "Read the temperature, print the offset to match the given reference temperature and exit")
+	flag.Var(references["humidity"], cliCalibrateHumidity, "Read the humidity, print the offset to match the given reference humidity and exit")
+	flag.Var(references["pressure"], cliCalibratePressure, "Read the pressure, print the offset to match the given reference pressure and exit")
+	maxRuntime := flag.Duration(cliMaxRuntime, 0, "Shut down gracefully after running for the given duration")
+	confOptional := flag.Bool(cliConfOptional, false, "Skip missing config files and rely on the environment instead")
+
+	var overrides config.Config
+	flag.StringVar(&overrides.Placement, cliPlacement, "", "Placement of the sensor, overrides the config")
+	flag.StringVar(&overrides.Host, cliMqttHost, "", "MQTT broker, overrides the config")
+	flag.StringVar(&overrides.Topic, cliTopic, "", "MQTT topic, overrides the config")
+	flag.IntVar(&overrides.IntervalSecs, cliInterval, 0, "Interval in seconds for sensor readings, overrides the config")
+	flag.IntVar(&overrides.GpioAddress, cliGpioAddress, 0, "Address of the sensor, overrides the config")
 
 	flag.Parse()
 
@@ -30,64 +130,619 @@ func main() {
 		os.Exit(0)
 	}
 
-	log.Printf("Started %s, version %s, commit %s", config.BotName, internal.BuildVersion, internal.CommitHash)
-	conf, err := config.Read(configFile)
+	slog.Info("Started "+config.BotName, "version", internal.BuildVersion, "commit", internal.CommitHash)
+	if *confOptional {
+		files = files.existing()
+	}
+	conf, err := config.Read(files...)
 	if err != nil {
-		log.Fatalf("could not read config: %v", err)
+		fatal(exitCodeConfigParse, "Could not read config", "err", err)
 	}
+	applyFlagOverrides(conf, overrides)
+	if err := conf.ResolvePlacement(); err != nil {
+		fatal(exitCodeConfigParse, "Could not resolve placement", "err", err)
+	}
+	var logWriter io.Writer = os.Stderr
+	calibrate := references["temperature"].set || references["humidity"].set || references["pressure"].set
+	// the self-test and the calibration only log to stderr, so they do not write to the log file of a running bot
+	if len(conf.LogFile) > 0 && !*selfTest && !calibrate {
+		slog.Info("Writing logs to file", "file", conf.LogFile)
+		logFile := &lumberjack.Logger{
+			Filename:   conf.LogFile,
+			MaxSize:    conf.LogFileMaxSizeMb,
+			MaxBackups: conf.LogFileMaxBackups,
+		}
+		guard := internal.NewDiskGuard(conf.Placement, conf.MinFreeDiskMb)
+		logWriter = guard.Writer(logFile, conf.LogFile, "log", os.Stderr)
+	}
+	slog.SetDefault(slog.New(internal.NewLogHandler(logWriter, conf.LogLevel, conf.LogFormat)))
 	config.PrintFields(conf)
-	log.Println("Validating config...")
+	slog.Info("Validating config")
 	if err := config.Validate(conf); err != nil {
-		log.Fatalf("Could not validate config: %v", err)
+		fatal(exitCodeConfigValidation, "Could not validate config", "err", err)
+	}
+	if conf.UsesFastInterval() {
+		slog.Warn("!!! Reading the sensor every few seconds as allowed by AllowFastInterval, this is meant for bench tests and not for production !!!", "interval_s", conf.IntervalSecs)
+	}
+	for _, warning := range conf.DependencyWarnings() {
+		slog.Warn(warning)
+	}
+	slog.Info("Using placement", "placement", conf.Placement)
+	if sanitized := conf.TopicPlacement(); sanitized != conf.Placement {
+		slog.Warn("Placement contains characters not allowed in topics", "topic_placement", sanitized)
 	}
+	sensorConfs := conf.SensorConfigs()
+	for i := range sensorConfs {
+		sensorConfs[i].FormatTopic()
+	}
+	conf.FormatTopic()
+	slog.Info("Effective config", "hash", conf.Hash())
 
-	run(conf)
+	if *selfTest {
+		runSelfTest(sensorConfs)
+		os.Exit(0)
+	}
+	if calibrate {
+		runCalibration(sensorConfs, references)
+		os.Exit(0)
+	}
+	if *bench > 0 {
+		runBenchmark(conf, *bench)
+		os.Exit(0)
+	}
+
+	run(sensorConfs, *maxRuntime)
+}
+
+func runBenchmark(conf *config.Config, duration time.Duration) {
+	driver := startDriver(conf)
+	slog.Info("Benchmarking sensor", "duration", duration)
+	result := internal.Benchmark(driver, conf.Placement, duration)
+	fmt.Println(result)
 }
 
-func run(conf *config.Config) {
+// runSelfTest reads each sensor once and prints the values, exiting with a non-zero code if a sensor can not be read
+// or returns implausible values.
+func runSelfTest(confs []config.Config) {
+	failed := false
+	for i := range confs {
+		conf := &confs[i]
+		driver := startDriver(conf)
+		var busLock *internal.BusLock
+		if len(conf.BusLockFile) > 0 {
+			busLock = internal.NewBusLock(conf.BusLockFile)
+		}
+
+		slog.Info("Testing sensor", "placement", conf.Placement, "bus", conf.GpioBus, "address", fmt.Sprintf("0x%02x", conf.GpioAddress))
+		result, err := internal.SelfTest(driver, conf.SensorType, busLock)
+		if err != nil {
+			failed = true
+			if hint := internal.StartupErrorHint(err); len(hint) > 0 {
+				slog.Error("Self-test failed", "placement", conf.Placement, "err", err, "likely_cause", hint)
+			} else {
+				slog.Error("Self-test failed", "placement", conf.Placement, "err", err)
+			}
+			continue
+		}
+		if len(confs) > 1 {
+			fmt.Printf("placement=%s %s\n", conf.Placement, result)
+		} else {
+			fmt.Println(result)
+		}
+	}
+	if failed {
+		os.Exit(exitCodeSelfTest)
+	}
+}
+
+// runCalibration reads each sensor and prints the offsets to configure in MeasurementOffsets, so the published values
+// match the given references, exiting with a non-zero code if a sensor can not be read.
+func runCalibration(confs []config.Config, references map[string]*referenceValue) {
+	failed := false
+	for i := range confs {
+		conf := &confs[i]
+		driver := startDriver(conf)
+		var busLock *internal.BusLock
+		if len(conf.BusLockFile) > 0 {
+			busLock = internal.NewBusLock(conf.BusLockFile)
+		}
+
+		for _, measurement := range []string{"temperature", "humidity", "pressure"} {
+			if !references[measurement].set {
+				continue
+			}
+			slog.Info("Calibrating sensor", "placement", conf.Placement, "measurement", measurement)
+			result, err := internal.Calibrate(driver, measurement, references[measurement].value, conf.SensorConfig, busLock)
+			if err != nil {
+				failed = true
+				slog.Error("Calibration failed", "placement", conf.Placement, "err", err)
+				continue
+			}
+			if len(confs) > 1 {
+				fmt.Printf("placement=%s %s\n", conf.Placement, result)
+			} else {
+				fmt.Println(result)
+			}
+		}
+	}
+	if failed {
+		os.Exit(exitCodeCalibration)
+	}
+}
+
+// startDriver connects to the sensor and starts its driver, exiting if the sensor can not be reached.
+func startDriver(conf *config.Config) *i2c.BME280Driver {
+	adaptor := buildI2cAdaptor(conf)
+	driver := i2c.NewBME280Driver(adaptor, internal.DriverOptions(conf.SensorConfig)...)
+	if err := adaptor.Connect(); err != nil {
+		fatalStartup("Could not connect to adaptor", err)
+	}
+	if err := driver.Start(); err != nil {
+		fatalStartup("Could not start driver", err)
+	}
+	return driver
+}
+
+// buildI2cAdaptor returns the adaptor of the configured platform, accessing the bus at the configured device path if
+// set.
+func buildI2cAdaptor(conf *config.Config) internal.I2cAdaptor {
+	adaptor, err := internal.NewPlatformAdaptor(conf.Platform)
+	if err != nil {
+		fatalStartup("Could not build platform adaptor", err)
+	}
+	if len(conf.I2cDevicePath) > 0 {
+		slog.Info("Using i2c device", "device", conf.I2cDevicePath)
+		return internal.NewI2cDeviceAdaptor(adaptor, conf.I2cDevicePath)
+	}
+	return adaptor
+}
+
+// applyFlagOverrides overwrites the config values for all flags that have explicitly been set.
+func applyFlagOverrides(conf *config.Config, overrides config.Config) {
+	flag.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case cliPlacement:
+			conf.Placement = overrides.Placement
+		case cliMqttHost:
+			conf.Host = overrides.Host
+		case cliTopic:
+			conf.Topic = overrides.Topic
+		case cliInterval:
+			conf.IntervalSecs = overrides.IntervalSecs
+		case cliGpioAddress:
+			conf.GpioAddress = overrides.GpioAddress
+		}
+	})
+}
+
+// run reads the sensors of the given configs until the process is shut down. The adaptors that are not specific to a
+// sensor are built from the first config and shared between the sensors.
+func run(sensorConfs []config.Config, maxRuntime time.Duration) {
+	conf := &sensorConfs[0]
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var metricsServer *http.Server
 	if conf.MetricConfig != "" {
-		go internal.StartMetricsServer(conf.MetricConfig)
+		var err error
+		metricsServer, err = internal.StartMetricsServer(conf.MetricConfig, conf.MetricsBindFallback)
+		switch {
+		case err == nil:
+			slog.Info("Serving metrics", "addr", metricsServer.Addr)
+		case conf.MetricsBindFallback == config.MetricsBindFallbackDisable:
+			slog.Warn("Could not start metrics listener, continuing without metrics", "err", err)
+		default:
+			fatal(exitCodeUnexpected, "Could not start metrics listener", "err", err)
+		}
 	}
 
-	log.Println("Building adaptors and drivers")
-	raspberry := raspi.NewAdaptor()
-	driver := i2c.NewBME280Driver(raspberry, i2c.WithBus(conf.GpioBus), i2c.WithAddress(conf.GpioAddress))
+	if conf.StartupDelaySecs > 0 {
+		delay := time.Duration(conf.StartupDelaySecs) * time.Second
+		slog.Info("Waiting before connecting", "delay", delay)
+		time.Sleep(delay)
+	}
+
+	slog.Info("Building adaptors and drivers")
+	adaptor := buildI2cAdaptor(conf)
 
 	var mqttAdaptor internal.WeatherBotMqttAdaptor
+	clientPerSensor := conf.MqttConfig.ClientPerSensor && len(sensorConfs) > 1
 	if !conf.MqttConfig.Disabled {
-		log.Println("Building MQTT adaptor")
+		slog.Info("Building MQTT adaptor")
+		mq := buildMqttAdaptor(conf)
+		useStatusTopic(mq, conf, clientPerSensor)
+		registerConnectMessages(mq, conf)
+		mqttAdaptor = mq
+	} else {
+		slog.Info("No MQTT host defined, not connecting to MQTT broker")
+	}
+
+	var remoteWrite internal.MetricsSink
+	if conf.RemoteWriteConfig.Enabled() {
+		slog.Info("Building remote-write sink")
+		remoteWrite = internal.NewRemoteWriteSink(conf.RemoteWriteConfig)
+	}
+
+	var busLock *internal.BusLock
+	if len(conf.BusLockFile) > 0 {
+		slog.Info("Locking file while reading the sensor", "file", conf.BusLockFile)
+		busLock = internal.NewBusLock(conf.BusLockFile)
+	}
+
+	var csvSink *internal.CsvSink
+	if len(conf.CsvFile) > 0 {
+		slog.Info("Writing readings to csv file", "file", conf.CsvFile)
+		csvSink = internal.NewCsvSink(conf.CsvFile, conf.CsvFileMaxSizeMb, conf.FlushEveryN, conf.CsvColumnList())
+	}
 
-		clientId := fmt.Sprintf("%s_%s", config.BotName, conf.Placement)
-		mq := mqtt.NewAdaptor(conf.MqttConfig.Host, clientId)
-		mq.SetAutoReconnect(true)
-		mq.SetQoS(1)
+	var influxSink *internal.InfluxSink
+	if conf.InfluxConfig.Enabled() {
+		slog.Info("Building InfluxDB sink")
+		influxSink = internal.NewInfluxSink(conf.InfluxConfig, conf.TimestampPrecision)
+	}
 
-		if conf.MqttConfig.UsesSslCerts() {
-			log.Println("Setting TLS client cert and key...")
-			mq.SetClientCert(conf.MqttConfig.ClientCertFile)
-			mq.SetClientKey(conf.MqttConfig.ClientKeyFile)
+	var syslogSink internal.MeasurementSink
+	if conf.Syslog {
+		slog.Info("Sending readings to syslog")
+		syslogSink = internal.NewSyslogSink(conf.SyslogNetwork, conf.SyslogAddress, conf.SyslogFacility)
+	}
 
-			if len(conf.MqttConfig.ServerCaFile) > 0 {
-				log.Println("Setting server CA...")
-				mq.SetServerCert(conf.MqttConfig.ServerCaFile)
+	var voltage internal.VoltageSource
+	if len(conf.VoltageFile) > 0 {
+		slog.Info("Reading voltage from file", "file", conf.VoltageFile)
+		voltage = internal.NewFileVoltageSource(conf.VoltageFile, conf.VoltageScale)
+	}
+
+	bots := make([]*internal.WeatherBotAdaptors, 0, len(sensorConfs))
+	for i := range sensorConfs {
+		conf := &sensorConfs[i]
+		var alert *internal.AlertWebhook
+		if conf.AlertConfig.Enabled() {
+			slog.Info("Building alert webhook")
+			var err error
+			alert, err = internal.NewAlertWebhook(conf.AlertConfig)
+			if err != nil {
+				fatal(exitCodeStartup, "Could not build alert webhook", "err", err)
 			}
 		}
 
-		mqttAdaptor = mq
-	} else {
-		log.Println("No MQTT host defined, not connecting to MQTT broker")
+		var kafkaSink internal.MessageSink
+		if conf.KafkaConfig.Enabled() {
+			slog.Info("Building Kafka sink")
+			var err error
+			kafkaSink, err = internal.NewKafkaSink(conf.KafkaConfig, conf.Placement)
+			if err != nil {
+				fatal(exitCodeStartup, "Could not build Kafka sink", "err", err)
+			}
+		}
+
+		var metadata map[string]string
+		if len(conf.MetadataFile) > 0 {
+			var err error
+			metadata, err = internal.LoadMetadata(conf.MetadataFile, conf.GpioBus, conf.GpioAddress)
+			if err != nil {
+				fatal(exitCodeStartup, "Could not load metadata of the sensor", "err", err)
+			}
+			if metadata == nil {
+				slog.Warn("No metadata for sensor", "sensor", fmt.Sprintf("%d:%#x", conf.GpioBus, conf.GpioAddress), "file", conf.MetadataFile)
+			}
+		}
+
+		sensorMqttAdaptor := mqttAdaptor
+		if mqttAdaptor != nil && clientPerSensor && len(bots) > 0 {
+			// the first sensor uses the client that publishes the messages shared by all sensors
+			slog.Info("Building MQTT adaptor of sensor", "placement", conf.Placement)
+			mq := buildMqttAdaptor(conf)
+			useStatusTopic(mq, conf, true)
+			sensorMqttAdaptor = mq
+		}
+
+		var spool *internal.Spool
+		if sensorMqttAdaptor != nil && len(conf.MqttConfig.SpoolDir) > 0 {
+			path := filepath.Join(conf.MqttConfig.SpoolDir, conf.TopicPlacement()+".spool")
+			slog.Info("Spooling unpublished readings", "file", path)
+			var err error
+			spool, err = internal.NewSpool(path, conf.MqttConfig.SpoolMaxBytes)
+			if err != nil {
+				fatal(exitCodeStartup, "Could not open spool", "err", err)
+			}
+		}
+
+		adaptors := &internal.WeatherBotAdaptors{
+			Driver:      i2c.NewBME280Driver(adaptor, internal.DriverOptions(conf.SensorConfig)...),
+			Adaptor:     adaptor,
+			MqttAdaptor: sensorMqttAdaptor,
+			Spool:       spool,
+			Syslog:      syslogSink,
+			Kafka:       kafkaSink,
+			BusLock:     busLock,
+			Alert:       alert,
+			DiskGuard:   internal.NewDiskGuard(conf.Placement, conf.MinFreeDiskMb),
+			Voltage:     voltage,
+			Metadata:    metadata,
+			Config:      *conf,
+		}
+		// nil pointers would not be nil sinks, so the sinks that are flushed on shutdown are only assigned if configured
+		if csvSink != nil {
+			adaptors.Csv = csvSink
+		}
+		if influxSink != nil {
+			adaptors.Influx = influxSink
+		}
+		if len(bots) == 0 {
+			// a push contains the metrics of all sensors, so only the first sensor pushes them
+			adaptors.RemoteWrite = remoteWrite
+		}
+		bots = append(bots, adaptors)
+
+		if len(conf.MqttConfig.CommandTopic) > 0 {
+			adaptors.CommandTopic = conf.MqttConfig.CommandTopic
+			if len(sensorConfs) > 1 {
+				adaptors.CommandTopic += "/" + conf.TopicPlacement()
+			}
+		}
+
+		if mq, ok := sensorMqttAdaptor.(*internal.MqttAdaptor); ok {
+			if len(conf.MqttConfig.StatusTopic) > 0 {
+				placement := conf.Placement
+				adaptors.AvailabilityChanged = func(available bool) {
+					mq.SetAvailable(placement, available)
+				}
+			}
+			if spool != nil {
+				mq.OnConnect(adaptors.DrainSpool)
+			}
+			if len(adaptors.CommandTopic) > 0 {
+				commandTopic := conf.MqttConfig.PrefixedTopic(adaptors.CommandTopic)
+				mq.Subscribe(commandTopic+"/"+internal.CommandRead, adaptors.HandleReadCommand)
+				mq.Subscribe(commandTopic+"/"+internal.CommandInterval, adaptors.HandleIntervalCommand)
+			}
+		}
+	}
+
+	if mq, ok := mqttAdaptor.(*internal.MqttAdaptor); ok && len(conf.MqttConfig.VentilationReferenceTopic) > 0 {
+		// a topic can only be subscribed once, so the reference is handed to all sensors by a single subscription
+		mq.Subscribe(conf.MqttConfig.VentilationReferenceTopic, func(payload []byte) {
+			for _, adaptors := range bots {
+				adaptors.UpdateOutdoorReference(payload)
+			}
+		})
+	}
+
+	// until the robot is started, signals terminate the process right away
+	onStarted := []func(){
+		func() {
+			handleSignals(cancel)
+		},
+	}
+	if conf.StartTimeoutSecs > 0 {
+		timeout := time.Duration(conf.StartTimeoutSecs) * time.Second
+		deadline := time.AfterFunc(timeout, func() {
+			fatal(exitCodeStartup, "Could not start bot in time, connecting the adaptors or starting the sensor driver is hanging", "timeout", timeout)
+		})
+		onStarted = append(onStarted, func() {
+			deadline.Stop()
+		})
+	}
+	if maxRuntime > 0 {
+		onStarted = append(onStarted, func() {
+			slog.Info("Shutting down after max runtime", "max_runtime", maxRuntime)
+			time.AfterFunc(maxRuntime, func() {
+				slog.Info("Reached max runtime, shutting down gracefully", "max_runtime", maxRuntime)
+				interrupt()
+			})
+		})
+	}
+	bots[0].Started = func() {
+		for _, f := range onStarted {
+			f()
+		}
+	}
+	var exitCode int32
+	for _, adaptors := range bots {
+		adaptors.PublishFailuresExceeded = func() {
+			slog.Error("Exceeded consecutive publish failures, shutting down", "max_failures", conf.MaxConsecutivePublishFailures)
+			atomic.StoreInt32(&exitCode, exitCodePublishFailures)
+			interrupt()
+		}
+	}
+
+	var readingsSocket *internal.ReadingsSocket
+	if len(conf.ReadingsSocketPath) > 0 {
+		var err error
+		if readingsSocket, err = internal.ListenReadingsSocket(conf.ReadingsSocketPath); err != nil {
+			fatalStartup("Could not listen on readings socket", err)
+		}
+		slog.Info("Serving readings on socket", "path", conf.ReadingsSocketPath)
+	}
+
+	bot := internal.AssembleBot(ctx, bots...)
+	// only connecting the adaptors and starting the drivers is retried, the robot is stopped separately so an error
+	// while shutting down does not restart it
+	err := retry(conf.StartupRetryMax, func() error {
+		return bot.Start(false)
+	})
+	if err != nil {
+		fatalStartup("Could not start bot", err)
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+	<-signals
+	if err := bot.Stop(); err != nil {
+		slog.Error("Could not stop bot", "err", err)
+	}
+	if readingsSocket != nil {
+		if err := readingsSocket.Close(); err != nil {
+			slog.Warn("Could not close readings socket", "err", err)
+		}
+	}
+
+	if csvSink != nil {
+		if err := csvSink.Flush(); err != nil {
+			slog.Error("Could not flush buffered readings to csv file", "err", err)
+		}
 	}
+	if influxSink != nil {
+		if err := influxSink.Flush(); err != nil {
+			slog.Error("Could not flush buffered measurements to InfluxDB", "err", err)
+		}
+	}
+	if metricsServer != nil {
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), shutdownTimeout)
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			slog.Warn("Could not shut down metrics listener", "err", err)
+		}
+		cancelShutdown()
+	}
+	if code := atomic.LoadInt32(&exitCode); code != 0 {
+		os.Exit(int(code))
+	}
+}
 
-	adaptors := &internal.WeatherBotAdaptors{
-		Driver:      driver,
-		Adaptor:     raspberry,
-		MqttAdaptor: mqttAdaptor,
-		Config:      *conf,
+// handleSignals invokes cancel once the process receives an interrupt or SIGTERM, so no further readings are started.
+// gobot only stops the robot on an interrupt, so SIGTERM, which is sent when stopping a container, is forwarded as an
+// interrupt to trigger the same graceful shutdown.
+func handleSignals(cancel context.CancelFunc) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		sig := <-signals
+		slog.Info("Received signal, shutting down gracefully", "signal", sig)
+		cancel()
+		if sig == syscall.SIGTERM {
+			interrupt()
+		}
+	}()
+}
+
+// interrupt sends an interrupt to this process, triggering the same graceful shutdown as pressing Ctrl+C.
+func interrupt() {
+	proc, err := os.FindProcess(os.Getpid())
+	if err == nil {
+		err = proc.Signal(os.Interrupt)
 	}
+	if err != nil {
+		fatal(exitCodeUnexpected, "Could not shut down", "err", err)
+	}
+}
 
-	bot := internal.AssembleBot(adaptors)
-	err := bot.Start()
+// fatalStartup exits with the startup exit code, mentioning the likely cause of common errors.
+func fatalStartup(msg string, err error) {
+	if hint := internal.StartupErrorHint(err); len(hint) > 0 {
+		fatal(exitCodeStartup, msg, "err", err, "likely_cause", hint)
+	}
+	fatal(exitCodeStartup, msg, "err", err)
+}
+
+// registerConnectMessages registers the messages that are published after connecting to the broker.
+// buildMqttAdaptor builds the MQTT adaptor of the sensor, whose placement is part of the client id.
+func buildMqttAdaptor(conf *config.Config) *internal.MqttAdaptor {
+	clientId := fmt.Sprintf("%s_%s", config.BotName, conf.Placement)
+	tlsConfig, err := internal.BuildTlsConfig(conf.MqttConfig)
 	if err != nil {
-		log.Fatalf("Could not start bot: %v", err)
+		fatal(exitCodeStartup, "Could not build TLS config", "err", err)
+	}
+	if tlsConfig != nil {
+		slog.Info("Using TLS client cert and key")
+	}
+
+	publishTimeout := time.Duration(conf.MqttConfig.PublishTimeoutMs) * time.Millisecond
+	return internal.NewMqttAdaptor(conf.MqttConfig.Host, clientId, tlsConfig, conf.MqttConfig.Qos, publishTimeout)
+}
+
+// useStatusTopic publishes the status of the client to the status topic. Clients can not share a last will, so with a
+// client per sensor, each client publishes the status of its sensor below the status topic.
+func useStatusTopic(mq *internal.MqttAdaptor, conf *config.Config, perSensor bool) {
+	if len(conf.MqttConfig.StatusTopic) == 0 {
+		return
+	}
+	statusTopic := conf.MqttConfig.StatusTopic
+	if perSensor {
+		statusTopic += "/" + conf.TopicPlacement()
+	}
+	statusTopic = conf.MqttConfig.PrefixedTopic(statusTopic)
+	mq.UseStatusTopic(statusTopic, conf.MqttConfig.StatusPayloadOnline, conf.MqttConfig.StatusPayloadOffline, conf.MqttConfig.StatusPayloadLost)
+}
+
+func registerConnectMessages(mq *internal.MqttAdaptor, conf *config.Config) {
+	if conf.MqttConfig.UsesBirthMessage() {
+		birthTopic := conf.MqttConfig.PrefixedTopic(conf.MqttConfig.BirthTopic)
+		mq.OnConnect(func() {
+			if !mq.PublishRetained(birthTopic, []byte(conf.MqttConfig.BirthPayload)) {
+				slog.Warn("Could not publish birth message", "topic", birthTopic)
+			}
+		})
+	}
+
+	if conf.MqttConfig.PublishSchema {
+		schemaTopic := conf.MqttConfig.PrefixedTopic(conf.MqttConfig.FieldTopic("schema"))
+		schema, err := internal.NewSchema(*conf).AsJson()
+		if err != nil {
+			fatal(exitCodeStartup, "Could not build schema", "err", err)
+		}
+		mq.OnConnect(func() {
+			if !mq.PublishRetained(schemaTopic, schema) {
+				slog.Warn("Could not publish schema", "topic", schemaTopic)
+			}
+		})
+	}
+
+	if conf.MqttConfig.PublishConfigSnapshot {
+		snapshotTopic := conf.MqttConfig.PrefixedTopic(internal.ConfigSnapshotTopic)
+		snapshot, err := internal.NewConfigSnapshot(*conf).AsJson()
+		if err != nil {
+			fatal(exitCodeStartup, "Could not build config snapshot", "err", err)
+		}
+		mq.OnConnect(func() {
+			if !mq.PublishRetained(snapshotTopic, snapshot) {
+				slog.Warn("Could not publish config snapshot", "topic", snapshotTopic)
+			}
+		})
+	}
+
+	if conf.MqttConfig.PublishStartupTest {
+		startupTopic := conf.MqttConfig.PrefixedTopic(internal.StartupTestTopic)
+		msg, err := internal.NewStartupTest(conf.Placement).AsJson()
+		if err != nil {
+			fatal(exitCodeStartup, "Could not build startup test message", "err", err)
+		}
+		var once sync.Once
+		mq.OnConnect(func() {
+			once.Do(func() {
+				if mq.Publish(startupTopic, msg) {
+					slog.Info("Published startup test message", "topic", startupTopic)
+				} else {
+					slog.Warn("Could not publish startup test message", "topic", startupTopic)
+				}
+			})
+		})
+	}
+}
+
+// fatal logs the message and exits with the exit code of the failed stage.
+func fatal(code int, msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(code)
+}
+
+// retry invokes op until it succeeds or maxRetries additional attempts have failed, backing off exponentially
+// between the attempts.
+func retry(maxRetries int, op func() error) error {
+	backoff := startupBackoffInitial
+	var err error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			slog.Warn("Attempt failed, retrying", "attempt", attempt, "attempts", maxRetries+1, "err", err, "backoff", backoff)
+			time.Sleep(backoff)
+			backoff *= 2
+			if backoff > startupBackoffMax {
+				backoff = startupBackoffMax
+			}
+		}
+
+		if err = op(); err == nil {
+			return nil
+		}
 	}
+	return err
 }
@@ -0,0 +1,135 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+const (
+	alertTimeout = 5 * time.Second
+
+	alertStatusFiring   = "firing"
+	alertStatusResolved = "resolved"
+)
+
+// AlertWebhook notifies a webhook once the consecutive read errors reach the configured threshold and again once the
+// sensor has recovered. Recovery is only notified after the readings have been successful for the debounce period,
+// so a flapping sensor does not cause a flood of alerts.
+type AlertWebhook struct {
+	conf     config.AlertConfig
+	template *template.Template
+	client   *http.Client
+
+	firing       bool
+	healthySince time.Time
+}
+
+// alertPayload is the JSON body of the webhook and the data the template is executed with.
+type alertPayload struct {
+	Status            string   `json:"status"`
+	Placement         string   `json:"placement"`
+	ConsecutiveErrors int      `json:"consecutive_errors"`
+	Errors            []string `json:"errors,omitempty"`
+	Timestamp         int64    `json:"timestamp"`
+}
+
+func NewAlertWebhook(conf config.AlertConfig) (*AlertWebhook, error) {
+	tmpl, err := conf.AlertTemplate()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse alert template: %w", err)
+	}
+
+	return &AlertWebhook{
+		conf:     conf,
+		template: tmpl,
+		client:   &http.Client{Timeout: alertTimeout},
+	}, nil
+}
+
+// record returns the alert to send after a reading, or nil if the state of the sensor did not change.
+func (a *AlertWebhook) record(placement string, consecutiveErrors int, errs []string, now time.Time) *alertPayload {
+	threshold := a.conf.AlertThreshold
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	if consecutiveErrors > 0 {
+		a.healthySince = time.Time{}
+		if a.firing || consecutiveErrors < threshold {
+			return nil
+		}
+		a.firing = true
+		return &alertPayload{
+			Status:            alertStatusFiring,
+			Placement:         placement,
+			ConsecutiveErrors: consecutiveErrors,
+			Errors:            nonEmpty(errs),
+			Timestamp:         now.Unix(),
+		}
+	}
+
+	if !a.firing {
+		return nil
+	}
+	if a.healthySince.IsZero() {
+		a.healthySince = now
+	}
+	if now.Sub(a.healthySince) < time.Duration(a.conf.AlertDebounceSecs)*time.Second {
+		return nil
+	}
+	a.firing = false
+	a.healthySince = time.Time{}
+	return &alertPayload{
+		Status:    alertStatusResolved,
+		Placement: placement,
+		Timestamp: now.Unix(),
+	}
+}
+
+// send posts the alert to the webhook, rendered using the template if configured.
+func (a *AlertWebhook) send(alert alertPayload) error {
+	body, err := a.render(alert)
+	if err != nil {
+		return err
+	}
+
+	resp, err := a.client.Post(a.conf.AlertWebhookUrl, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("alert webhook returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func (a *AlertWebhook) render(alert alertPayload) ([]byte, error) {
+	if a.template == nil {
+		return json.Marshal(alert)
+	}
+
+	var buf bytes.Buffer
+	if err := a.template.Execute(&buf, alert); err != nil {
+		return nil, fmt.Errorf("could not render alert template: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func nonEmpty(values []string) []string {
+	var ret []string
+	for _, val := range values {
+		if len(strings.TrimSpace(val)) > 0 {
+			ret = append(ret, val)
+		}
+	}
+	return ret
+}
@@ -0,0 +1,91 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func TestAlertWebhook_record(t *testing.T) {
+	alert, err := NewAlertWebhook(config.AlertConfig{AlertThreshold: 2, AlertDebounceSecs: 60})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Unix(1700000000, 0)
+	steps := []struct {
+		consecutiveErrors int
+		after             time.Duration
+		want              string
+	}{
+		{consecutiveErrors: 1, want: ""},
+		{consecutiveErrors: 2, want: alertStatusFiring},
+		{consecutiveErrors: 3, want: ""},
+		{consecutiveErrors: 0, want: ""},
+		{consecutiveErrors: 1, after: 30 * time.Second, want: ""},
+		{consecutiveErrors: 0, after: 60 * time.Second, want: ""},
+		{consecutiveErrors: 0, after: 90 * time.Second, want: ""},
+		{consecutiveErrors: 0, after: 120 * time.Second, want: alertStatusResolved},
+		{consecutiveErrors: 0, after: 150 * time.Second, want: ""},
+		{consecutiveErrors: 2, after: 180 * time.Second, want: alertStatusFiring},
+	}
+	for i, step := range steps {
+		got := alert.record("loc", step.consecutiveErrors, []string{"", "boom"}, now.Add(step.after))
+		status := ""
+		if got != nil {
+			status = got.Status
+		}
+		if status != step.want {
+			t.Fatalf("step %d: got status %q, want %q", i, status, step.want)
+		}
+	}
+}
+
+func TestAlertWebhook_send(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     string
+	}{
+		{
+			name: "json",
+			want: `{"status":"firing","placement":"loc","consecutive_errors":3,"errors":["boom"],"timestamp":1700000000}`,
+		},
+		{
+			name:     "template",
+			template: `{"text": "{{ .Placement }} is {{ .Status }}"}`,
+			want:     `{"text": "loc is firing"}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body []byte
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ = io.ReadAll(r.Body)
+			}))
+			defer server.Close()
+
+			alert, err := NewAlertWebhook(config.AlertConfig{AlertWebhookUrl: server.URL, AlertWebhookTemplate: tt.template})
+			if err != nil {
+				t.Fatal(err)
+			}
+			payload := alertPayload{
+				Status:            alertStatusFiring,
+				Placement:         "loc",
+				ConsecutiveErrors: 3,
+				Errors:            []string{"boom"},
+				Timestamp:         1700000000,
+			}
+			if err := alert.send(payload); err != nil {
+				t.Fatalf("send() error = %v", err)
+			}
+			if string(body) != tt.want {
+				t.Errorf("send() body = %s, want %s", body, tt.want)
+			}
+		})
+	}
+}
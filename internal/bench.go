@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+)
+
+type BenchmarkResult struct {
+	Reads      int
+	Errors     int
+	Duration   time.Duration
+	MinLatency time.Duration
+	MaxLatency time.Duration
+	AvgLatency time.Duration
+}
+
+func (r BenchmarkResult) String() string {
+	readsPerSec := float64(r.Reads) / r.Duration.Seconds()
+	return fmt.Sprintf("reads=%d errors=%d reads/s=%.2f latency min=%v max=%v avg=%v",
+		r.Reads, r.Errors, readsPerSec, r.MinLatency, r.MaxLatency, r.AvgLatency)
+}
+
+// Benchmark reads all values from the sensor as fast as possible for the given duration.
+func Benchmark(sensor WeatherBotSensor, placement string, duration time.Duration) BenchmarkResult {
+	result := BenchmarkResult{}
+	var total time.Duration
+
+	start := time.Now()
+	for time.Since(start) < duration {
+		latency, err := timedRead(sensor, placement)
+		result.Reads++
+		if err != nil {
+			result.Errors++
+		}
+
+		total += latency
+		if result.MinLatency == 0 || latency < result.MinLatency {
+			result.MinLatency = latency
+		}
+		if latency > result.MaxLatency {
+			result.MaxLatency = latency
+		}
+	}
+
+	result.Duration = time.Since(start)
+	if result.Reads > 0 {
+		result.AvgLatency = total / time.Duration(result.Reads)
+	}
+	return result
+}
+
+// timedRead reads all values from the sensor once and records the duration of the read.
+func timedRead(sensor WeatherBotSensor, placement string) (time.Duration, error) {
+	start := time.Now()
+	_, errHumidity := sensor.Humidity()
+	_, errPressure := sensor.Pressure()
+	_, errTemperature := sensor.Temperature()
+	latency := time.Since(start)
+	metricReadDuration.WithLabelValues(placement).Observe(latency.Seconds())
+
+	for _, err := range []error{errHumidity, errPressure, errTemperature} {
+		if err != nil {
+			return latency, err
+		}
+	}
+	return latency, nil
+}
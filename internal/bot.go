@@ -1,18 +1,53 @@
 package internal
 
 import (
+	"context"
+	"log/slog"
+	"math"
+	"runtime/debug"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/robfig/cron/v3"
 	"github.com/soerenschneider/gobot-bme280/internal/config"
 	"gobot.io/x/gobot/v2"
 )
 
+const (
+	regCtrlMeas      = 0xF4
+	ctrlMeasModeMask = 0x03
+
+	sensorModeSleep   = 0x00
+	sensorModeForced  = 0x01
+	sensorModeForced2 = 0x02
+	sensorModeNormal  = 0x03
+
+	// wakeDelay is the pause after waking the sensor up, long enough for the first conversion with the maximum
+	// oversampling of all channels to finish
+	wakeDelay = 120 * time.Millisecond
+
+	// sampleDelay is the pause between multiple samples of a single reading, long enough for the sensor to finish
+	// a new conversion
+	sampleDelay = 50 * time.Millisecond
+
+	// publishRetryDelay is the pause between retries of a failed publish of a measurement
+	publishRetryDelay = 100 * time.Millisecond
+
+	// scheduleGapHorizon is the period in which the longest gap between the activations of a schedule is searched,
+	// long enough to cover daily and weekly schedules
+	scheduleGapHorizon = 8 * 24 * time.Hour
+)
+
 type WeatherBotSensor interface {
 	gobot.Driver
 	Altitude() (alt float32, err error)
 	Pressure() (press float32, err error)
 	Temperature() (temp float32, err error)
 	Humidity() (humidity float32, err error)
+	Read(register string) (val int, err error)
+	Write(register string, val int) error
 }
 
 type WeatherBotMqttAdaptor interface {
@@ -20,57 +55,1191 @@ type WeatherBotMqttAdaptor interface {
 	Publish(topic string, msg []byte) bool
 }
 
+// MeasurementSink receives the published measurements of the placement.
+type MeasurementSink interface {
+	Write(m Measurement, placement string) error
+}
+
+// FileSink is a MeasurementSink writing to the file at its path, whose writes are skipped while the free disk space
+// is below the threshold.
+type FileSink interface {
+	MeasurementSink
+	Path() string
+}
+
+// MessageSink receives the published measurements as JSON.
+type MessageSink interface {
+	Publish(msg []byte) error
+}
+
+// MetricsSink receives the metrics after each update.
+type MetricsSink interface {
+	Push() error
+}
+
 type WeatherBotAdaptors struct {
-	Adaptor     gobot.Connection
-	Driver      WeatherBotSensor
+	Adaptor gobot.Connection
+	Driver  WeatherBotSensor
+	// Reader reads the values of the sensor, by default the values are read from the Driver
+	Reader      SensorReader
 	MqttAdaptor WeatherBotMqttAdaptor
-	Config      config.Config
+	RemoteWrite MetricsSink
+	Csv         FileSink
+	Spool       *Spool
+	Syslog      MeasurementSink
+	Kafka       MessageSink
+	Influx      MeasurementSink
+	BusLock     *BusLock
+	Alert       *AlertWebhook
+	DiskGuard   *DiskGuard
+	Voltage     VoltageSource
+	// CommandTopic is the topic without the topic prefix below which the commands for the sensor are received and
+	// their responses are published
+	CommandTopic string
+	// Metadata of the physical sensor, added to the published measurements
+	Metadata map[string]string
+	// Started is invoked once the adaptors are connected and the drivers are started
+	Started func()
+	// PublishFailuresExceeded is invoked once the consecutive failed publishes of measurements exceed
+	// MaxConsecutivePublishFailures
+	PublishFailuresExceeded func()
+	// AvailabilityChanged is invoked with false once AvailabilityGraceIntervals readings in a row failed or were
+	// missed, and with true on the next successful reading
+	AvailabilityChanged func(available bool)
+	Config              config.Config
+
+	// mu serializes readings, which may also be triggered by commands
+	mu                sync.Mutex
+	health            *healthTracker
+	intervalChanges   chan time.Duration
+	stability         *stabilityGate
+	smoother          *smoother
+	stuck             *stuckDetector
+	offlineBuffer     *offlineBuffer
+	policy            *publishPolicy
+	previous          *Measurement
+	watchdog          *systemdWatchdog
+	clockSynced       bool
+	lastSuccess       time.Time
+	metricsStarted    bool
+	outdoor           outdoorReference
+	readings          int
+	consecutiveErrors int
+	publishFailures   int
+	readDensity       *readDensity
+	pressureTendency  *tendencyWindow
+	extremes          *extremesTracker
+	// done is closed once the bot is shutting down
+	done <-chan struct{}
+	// humidityUnsupported is set if the sensor is a BMP280, which can not measure humidity
+	humidityUnsupported bool
+	// unavailable is set once AvailabilityChanged has been invoked with false
+	unavailable atomic.Bool
+	// asleepCtrl is the content of the ctrl_meas register without the power mode while the sensor is asleep
+	asleepCtrl *int
 }
 
-func AssembleBot(bot *WeatherBotAdaptors) *gobot.Robot {
+// AssembleBot builds the robot reading the sensors of the given bots, which share the adaptor of the first bot and,
+// unless they have an MQTT adaptor of their own, its MQTT adaptor. Once ctx is done, no further readings are started,
+// so the robot can be stopped without waiting for the next interval.
+func AssembleBot(ctx context.Context, bots ...*WeatherBotAdaptors) *gobot.Robot {
+	first := bots[0]
 	versionInfo.WithLabelValues(BuildVersion, CommitHash).Set(1)
-	work := func() {
-		bot.readAndPublishMeasurement()
-		gobot.Every(time.Duration(bot.Config.IntervalSecs)*time.Second, func() {
-			bot.readAndPublishMeasurement()
-			metricsHeartbeat.WithLabelValues(bot.Config.Placement).SetToCurrentTime()
+	if first.Config.TemperatureUnit == config.TemperatureUnitFahrenheit {
+		if err := useFahrenheit(); err != nil {
+			slog.Warn("Could not export temperatures in degrees fahrenheit", "err", err)
+		}
+	}
+	if err := useSummaries(first.Config.MetricSummaries, first.Config.SummaryQuantiles()); err != nil {
+		slog.Warn("Could not export measurements as summaries", "err", err)
+	}
+
+	var adaptors []gobot.Connection
+	if first.Adaptor != nil {
+		adaptors = append(adaptors, first.Adaptor)
+	}
+	var connected []func() bool
+	devices := make([]gobot.Device, 0, len(bots))
+	for i, bot := range bots {
+		if bot.MqttAdaptor != nil && (i == 0 || bot.MqttAdaptor != first.MqttAdaptor) {
+			adaptors = append(adaptors, bot.MqttAdaptor)
+			if mq, ok := bot.MqttAdaptor.(interface{ IsConnected() bool }); ok {
+				connected = append(connected, mq.IsConnected)
+			}
+		}
+		if len(bot.Config.MetricPrefix) > 0 {
+			useMetricPrefix(bot.Config.Placement, bot.Config.MetricPrefix)
+		}
+		bot.exportInfo()
+		bot.setup()
+		bot.done = ctx.Done()
+		probes.register(bot.Config.Placement)
+		if bot.Driver != nil {
+			devices = append(devices, bot.Driver)
+		}
+	}
+	if len(connected) > 0 {
+		probes.useBroker(func() bool {
+			for _, isConnected := range connected {
+				if !isConnected() {
+					return false
+				}
+			}
+			return true
 		})
 	}
 
-	adaptors := []gobot.Connection{bot.Adaptor}
-	if bot.MqttAdaptor != nil {
-		adaptors = append(adaptors, bot.MqttAdaptor)
+	work := func() {
+		if first.Started != nil {
+			first.Started()
+		}
+		probes.start()
+
+		// each sensor is read in its own loop, so a hanging sensor does not delay the readings of the others
+		var wg sync.WaitGroup
+		for _, bot := range bots[1:] {
+			wg.Add(1)
+			go func(bot *WeatherBotAdaptors) {
+				defer wg.Done()
+				bot.work(ctx)
+			}(bot)
+		}
+		first.work(ctx)
+		wg.Wait()
 	}
+
 	robot := gobot.NewRobot(config.BotName,
 		adaptors,
-		[]gobot.Device{bot.Driver},
+		devices,
 		work,
 	)
 
 	return robot
 }
 
+// exportInfo exports the info metrics describing the sensor.
+func (bot *WeatherBotAdaptors) exportInfo() {
+	configInfo.WithLabelValues(bot.Config.Placement, bot.Config.Hash()).Set(1)
+	sensorInfo.WithLabelValues(bot.Config.Placement, bot.Config.EffectiveSensorId()).Set(1)
+	unitsInfo.WithLabelValues(bot.Config.Placement, temperatureUnitName(bot.Config.TemperatureUnit), "pa", "percent").Set(1)
+	if len(bot.Metadata) > 0 {
+		if err := registerMetadataInfo(bot.Config.Placement, bot.Metadata); err != nil {
+			slog.Warn("Could not export metadata of the sensor", "placement", bot.Config.Placement, "err", err)
+		}
+	}
+}
+
+// work reads the sensor until ctx is done.
+func (bot *WeatherBotAdaptors) work(ctx context.Context) {
+	// the schedule has already been validated, so this is not expected to fail
+	schedule, err := bot.Config.CronSchedule()
+	if err != nil {
+		slog.Warn("Could not parse schedule, falling back to the interval", "placement", bot.Config.Placement, "err", err)
+	}
+
+	bot.watchdog.start()
+	bot.reportHealth(ctx)
+	if bot.Driver != nil {
+		bot.configureSensorModel()
+		bot.updateSensorMode()
+	}
+	if bot.Config.PublishPlaceholders {
+		bot.publishPlaceholders()
+	}
+	var lastReading time.Time
+	tick := func() {
+		if !lastReading.IsZero() {
+			metricLoopSleep.WithLabelValues(bot.Config.Placement).Set(time.Since(lastReading).Seconds())
+		}
+		bot.readAndPublishRecovering()
+		lastReading = time.Now()
+		metricsHeartbeat.WithLabelValues(bot.Config.Placement).SetToCurrentTime()
+	}
+
+	if schedule != nil {
+		slog.Info("Reading sensor according to schedule", "placement", bot.Config.Placement, "schedule", bot.Config.Schedule)
+		everySchedule(ctx, schedule, tick)
+		return
+	}
+
+	var delay time.Duration
+	if bot.Config.AlignToClock {
+		delay = untilAligned(time.Now(), time.Duration(bot.Config.IntervalSecs)*time.Second)
+		slog.Info("Aligning readings to the clock", "placement", bot.Config.Placement, "first_reading_in", delay)
+	}
+	if bot.Config.PhaseOffsetMs > 0 {
+		// spreads the readings of multiple sensors on a bus within the interval
+		offset := time.Duration(bot.Config.PhaseOffsetMs) * time.Millisecond
+		slog.Info("Offsetting readings", "placement", bot.Config.Placement, "offset", offset)
+		delay += offset
+	}
+	if delay > 0 {
+		metricLoopSleep.WithLabelValues(bot.Config.Placement).Set(delay.Seconds())
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return
+		}
+	}
+	tick()
+	everyInterval(ctx, time.Duration(bot.Config.IntervalSecs)*time.Second, bot.intervalChanges, tick)
+}
+
+// reportHealth updates the health score every healthUpdatePeriod until ctx is done.
+func (station *WeatherBotAdaptors) reportHealth(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(healthUpdatePeriod)
+		defer ticker.Stop()
+		for {
+			select {
+			case now := <-ticker.C:
+				metricHealth.WithLabelValues(station.Config.Placement).Set(station.health.current(now))
+				// half an interval of slack for the reading that is due
+				if grace := station.Config.MqttConfig.AvailabilityGraceIntervals; grace > 0 && station.health.missedIntervals(now) > float64(grace)+0.5 {
+					station.updateAvailability(false)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// updateAvailability invokes AvailabilityChanged if the availability changed.
+func (station *WeatherBotAdaptors) updateAvailability(available bool) {
+	if station.AvailabilityChanged == nil || station.unavailable.Swap(!available) == !available {
+		return
+	}
+	station.AvailabilityChanged(available)
+}
+
+// setup initializes the state that is kept across intervals. It is separate from AssembleBot so single intervals can
+// be driven by calling readAndPublishMeasurement directly.
+func (station *WeatherBotAdaptors) setup() {
+	interval := time.Duration(station.Config.IntervalSecs) * time.Second
+	station.health = newHealthTracker(interval)
+	station.readDensity = newReadDensity(selfHeatingWindow)
+	station.pressureTendency = newTendencyWindow(tendencyPeriod)
+	station.stability = newStabilityGate(station.Config.StabilitySamples, station.Config.StabilityThreshold)
+	station.smoother = newSmoother(station.Config.SmoothingWindow)
+	station.stuck = newStuckDetector(station.Config.StuckReadsThreshold)
+	station.offlineBuffer = newOfflineBuffer(station.Config.OfflineBufferSize)
+	station.policy = newPublishPolicy(station.Config.Deadbands, station.Config.HeartbeatIntervals)
+	maxAge := 2 * interval
+	if schedule, err := station.Config.CronSchedule(); err == nil && schedule != nil {
+		// readings are up to the longest gap between the activations of the schedule apart
+		if gap := longestGap(schedule, time.Now()); gap > 0 {
+			maxAge = 2 * gap
+		}
+	}
+	station.watchdog = newSystemdWatchdog(maxAge)
+	station.intervalChanges = make(chan time.Duration, 1)
+	// until the first successful reading, the age is measured from the start
+	station.lastSuccess = time.Now()
+	station.extremes = temperatureExtremes.forPlacement(station.Config.Placement)
+	station.extremes.schedule(station.Config.ExtremesResetTime(), time.Now())
+}
+
+// readAndPublishRecovering reads and publishes a measurement and recovers from panics, so a bug in an optional feature
+// only affects a single interval instead of crashing the process.
+func (station *WeatherBotAdaptors) readAndPublishRecovering() {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("Recovered from panic while reading and publishing a measurement", "placement", station.Config.Placement, "panic", r, "stack", string(debug.Stack()))
+			metricPanics.WithLabelValues(station.Config.Placement).Inc()
+		}
+	}()
+	station.readAndPublishMeasurement()
+}
+
 func (station *WeatherBotAdaptors) readAndPublishMeasurement() {
+	station.mu.Lock()
+	defer station.mu.Unlock()
+
 	measurement := station.readMeasurement()
-	metricFromMeasurement(measurement, station.Config.Placement)
+	latestReadings.record(station.Config.Placement, measurement)
+	if station.Config.LogSensor {
+		station.logReading(measurement)
+	}
+	instant := measurement
+	measurement = station.smoother.smooth(measurement)
+	if station.stuck.enabled() {
+		station.checkStuck(instant)
+	}
+	for _, name := range measurement.failed {
+		metricChannelErrors.WithLabelValues(station.Config.Placement, name).Inc()
+	}
+	if len(measurement.Errors) > 0 {
+		metricSensorErrors.WithLabelValues(station.Config.Placement).Inc()
+		station.consecutiveErrors++
+	} else {
+		station.consecutiveErrors = 0
+		station.watchdog.success(time.Now())
+		station.lastSuccess = time.Now()
+		metricLastRead.WithLabelValues(station.Config.Placement).Set(float64(station.lastSuccess.Unix()))
+	}
+	metricConsecutiveErrors.WithLabelValues(station.Config.Placement).Set(float64(station.consecutiveErrors))
+	if grace := station.Config.MqttConfig.AvailabilityGraceIntervals; grace > 0 {
+		station.updateAvailability(station.consecutiveErrors < grace)
+	}
+	probes.record(station.Config.Placement, station.consecutiveErrors, station.Config.ReadinessMaxFailedReads)
+	station.notifyAlert(measurement.Errors)
+	if station.MqttAdaptor != nil && station.Config.PublishAge {
+		age := strconv.FormatFloat(time.Since(station.lastSuccess).Seconds(), 'f', 0, 64)
+		station.publish(station.Config.MqttConfig.FieldTopic("age_seconds"), []byte(age))
+	}
+	if station.isReconnectDue() {
+		station.reconnectSensor()
+	}
+	health := station.health.record(len(measurement.Errors) == 0, time.Now())
+
+	stable := true
+	if len(measurement.Errors) == 0 {
+		stable = station.stability.record(float64(measurement.Temperature))
+	}
+	if stable {
+		station.metricsStarted = true
+	}
+	reportMeasurement := station.metricsStarted || !station.Config.WithholdMetricsUntilStable
+
+	if len(measurement.Errors) == 0 && reportMeasurement && station.Config.ExposesMetric("temperature") {
+		min, max := station.extremes.record(float64(measurement.Temperature), time.Now())
+		metricTemperatureMin.WithLabelValues(station.Config.Placement).Set(toTemperatureUnit(min, station.Config.TemperatureUnit))
+		metricTemperatureMax.WithLabelValues(station.Config.Placement).Set(toTemperatureUnit(max, station.Config.TemperatureUnit))
+	}
+
+	if station.isMetricsUpdateDue() {
+		if reportMeasurement {
+			metricFromMeasurement(measurement.InTemperatureUnit(station.Config.TemperatureUnit), station.Config.Placement, station.Config.ExposesMetric)
+			if station.smoother.enabled() {
+				metricInstantFromMeasurement(instant.InTemperatureUnit(station.Config.TemperatureUnit), station.Config.Placement, station.Config.ExposesMetric)
+				station.metricStddevFromSmoother()
+			}
+			if station.Config.CorrectsTemperature() && !instant.Failed("temperature") && station.Config.ExposesMetric("temperature") {
+				metricTemperatureRaw.WithLabelValues(station.Config.Placement).Set(float64(instant.rawTemperature))
+			}
+			if station.Config.PublishComfortIndex && len(measurement.Errors) == 0 {
+				metricComfortIndex.WithLabelValues(station.Config.Placement).Set(station.comfortIndex(measurement))
+			}
+		}
+		metricHealth.WithLabelValues(station.Config.Placement).Set(health)
+		station.pushMetrics()
+	}
+	station.readings++
+
+	if !stable {
+		measurement.logger().Info("Readings are stabilizing, not publishing", "placement", station.Config.Placement)
+		return
+	}
+
+	var deltas []delta
+	if station.Config.PublishDelta && len(measurement.Errors) == 0 {
+		if station.previous != nil {
+			deltas = measurementDeltas(*station.previous, measurement)
+			for i := range deltas {
+				if deltas[i].name == "temperature" {
+					deltas[i].value = toTemperatureDeltaUnit(deltas[i].value, station.Config.TemperatureUnit)
+				}
+				deltas[i].value = roundTo(deltas[i].value, station.Config.DerivedDecimalPlaces)
+			}
+			for _, d := range deltas {
+				metricDelta.WithLabelValues(station.Config.Placement, d.name).Set(d.value)
+			}
+		}
+		station.previous = &measurement
+	}
+
+	dew, absolute, humidityDerivedOk := 0.0, 0.0, false
+	if (station.Config.PublishDewPoint || station.Config.PublishAbsoluteHumidity) && len(measurement.Errors) == 0 {
+		dew, absolute, humidityDerivedOk = humidityDerived(float64(measurement.Temperature), float64(measurement.Humidity))
+		dew = roundTo(toTemperatureUnit(dew, station.Config.TemperatureUnit), station.Config.DerivedDecimalPlaces)
+		absolute = roundTo(absolute, station.Config.DerivedDecimalPlaces)
+		if humidityDerivedOk && station.Config.PublishDewPoint {
+			metricDewPoint.WithLabelValues(station.Config.Placement).Set(dew)
+		}
+		if humidityDerivedOk && station.Config.PublishAbsoluteHumidity {
+			metricAbsoluteHumidity.WithLabelValues(station.Config.Placement).Set(absolute)
+		}
+	}
+
+	tendency, tendencyOk := 0.0, false
+	if station.Config.PublishPressureTendency && !measurement.Failed("pressure") {
+		station.pressureTendency.record(float64(measurement.Pressure)/100, time.Now())
+		if tendency, tendencyOk = station.pressureTendency.rate(tendencyPeriod); tendencyOk {
+			tendency = roundTo(tendency, station.Config.DerivedDecimalPlaces)
+			metricPressureTendency.WithLabelValues(station.Config.Placement).Set(tendency)
+		}
+	}
+
+	if station.Config.FailPartial && len(measurement.Errors) > 0 {
+		measurement.logger().Warn("Discarding partial measurement, not publishing", "placement", station.Config.Placement)
+		return
+	}
+
+	published := measurement.InTemperatureUnit(station.Config.TemperatureUnit).Scaled(station.Config.MeasurementScales, station.Config.MeasurementOffsets)
+	if station.Config.DecimalPlaces > 0 {
+		published = published.Rounded(station.Config.DecimalPlaces)
+	}
+	if station.Csv != nil {
+		allowed, changed := station.DiskGuard.check(station.Csv.Path(), "csv")
+		if !allowed && changed {
+			slog.Warn("Free disk space below threshold, not writing measurements to csv file")
+		} else if allowed && changed {
+			slog.Info("Free disk space above threshold again, writing measurements to csv file")
+		}
+		if allowed {
+			if err := station.Csv.Write(published, station.Config.Placement); err != nil {
+				measurement.logger().Warn("Could not write measurement to csv file", "err", err)
+			}
+		}
+	}
+
+	if station.Syslog != nil {
+		if err := station.Syslog.Write(published, station.Config.Placement); err != nil {
+			measurement.logger().Warn("Could not send measurement to syslog", "err", err)
+		}
+	}
+
+	if station.Kafka != nil {
+		msg, _ := published.AsJson()
+		if err := station.Kafka.Publish(msg); err != nil {
+			measurement.logger().Warn("Could not produce measurement to Kafka", "err", err)
+			metricsKafkaPublishErrors.WithLabelValues(station.Config.Placement).Inc()
+		}
+	}
+
+	if station.Influx != nil {
+		if err := station.Influx.Write(published, station.Config.Placement); err != nil {
+			measurement.logger().Warn("Could not write measurements to InfluxDB", "err", err)
+			metricsInfluxWriteErrors.WithLabelValues(station.Config.Placement).Inc()
+		}
+	}
+
+	if station.MqttAdaptor != nil && !station.isClockSynced() {
+		measurement.logger().Warn("System clock does not look synced yet, not publishing", "now", time.Now())
+		return
+	}
 
 	if station.MqttAdaptor != nil {
-		msg, _ := measurement.AsJson()
-		success := station.MqttAdaptor.Publish(station.Config.MqttConfig.Topic, msg)
+		if !station.policy.shouldPublish(published) {
+			if station.Config.LogSensor {
+				measurement.logger().Info("Measurement within deadbands, not publishing", "placement", station.Config.Placement)
+			}
+		} else if station.Config.PayloadFormat == config.PayloadFormatJson {
+			msg, _ := readingPayloadJson(measurement, station.Config.Placement, station.Config.DecimalPlaces)
+			station.publishMeasurement(msg)
+		} else if station.Config.PayloadFormat == config.PayloadFormatBoth {
+			// the measurement object is buffered while the broker is unavailable, so only its publish is recorded
+			station.publishFields(published)
+			msg, _ := published.AsJson()
+			station.publishMeasurement(msg)
+		} else if station.Config.MqttConfig.UsesFieldTopics() {
+			station.recordPublish(station.publishFields(published))
+		} else {
+			msg, _ := published.AsJson()
+			station.publishMeasurement(msg)
+		}
+
+		for _, d := range deltas {
+			value := strconv.FormatFloat(d.value, 'f', -1, 32)
+			station.publish(station.Config.MqttConfig.FieldTopic(station.Config.MeasurementName(d.name)+"/delta"), []byte(value))
+		}
+		if tendencyOk {
+			value := strconv.FormatFloat(tendency, 'f', -1, 64)
+			station.publish(station.Config.MqttConfig.FieldTopic(station.Config.MeasurementName("pressure")+"/tendency"), []byte(value))
+		}
+		if humidityDerivedOk && station.Config.PublishDewPoint {
+			station.publish(station.Config.MqttConfig.FieldTopic("dew_point"), []byte(strconv.FormatFloat(dew, 'f', -1, 64)))
+		}
+		if humidityDerivedOk && station.Config.PublishAbsoluteHumidity {
+			station.publish(station.Config.MqttConfig.FieldTopic("absolute_humidity"), []byte(strconv.FormatFloat(absolute, 'f', -1, 64)))
+		}
 
-		if success {
-			metricsMessagesPublished.WithLabelValues(station.Config.Placement).Inc()
+		if station.Config.PublishComfort && len(measurement.Errors) == 0 {
+			station.publishComfort(measurement)
+		}
+		if len(station.Config.VentilationReferenceTopic) > 0 && len(measurement.Errors) == 0 {
+			station.publishVentilation(measurement)
+		}
+		if len(station.Config.CondensationSurfaces) > 0 && len(measurement.Errors) == 0 {
+			station.publishCondensation(measurement)
+		}
+		if station.Config.PublishComfortIndex && len(measurement.Errors) == 0 {
+			index := strconv.FormatFloat(station.comfortIndex(measurement), 'f', -1, 64)
+			station.publish(station.Config.MqttConfig.FieldTopic("comfort/index"), []byte(index))
+		}
+		if station.Config.PublishWeather {
+			station.publishWeather(measurement, published, deltas)
+		}
+	}
+}
+
+// metricStddevFromSmoother updates the standard deviations of the exposed measured values over the smoothing window.
+func (station *WeatherBotAdaptors) metricStddevFromSmoother() {
+	for name, gauge := range metricStddev {
+		if stddev, ok := station.smoother.stddev(name); ok && station.Config.ExposesMetric(name) {
+			gauge.WithLabelValues(station.Config.Placement).Set(stddev)
+		}
+	}
+}
+
+// publishPlaceholders publishes a measurement without any values and exposes the measured values as NaN, so the
+// series exist before the first reading is available.
+func (station *WeatherBotAdaptors) publishPlaceholders() {
+	for name, metric := range measurementMetrics {
+		if _, ok := metricSummaries[name]; !ok && station.Config.ExposesMetric(name) {
+			metric.gauge.WithLabelValues(station.Config.Placement).Set(math.NaN())
+		}
+	}
+	if station.Config.PublishSeaLevelPressure {
+		metricPressureSeaLevel.WithLabelValues(station.Config.Placement).Set(math.NaN())
+	}
+	if station.Config.PublishSpecificHumidity {
+		metricSpecificHumidity.WithLabelValues(station.Config.Placement).Set(math.NaN())
+	}
+
+	if station.MqttAdaptor != nil && (station.Config.PayloadFormat == config.PayloadFormatBoth || !station.Config.MqttConfig.UsesFieldTopics()) {
+		placeholder := placeholderMeasurement()
+		msg, _ := placeholder.AsJson()
+		if station.Config.PayloadFormat == config.PayloadFormatJson {
+			msg, _ = readingPayloadJson(placeholder, station.Config.Placement, 0)
+		}
+		station.publish(station.Config.MqttConfig.MeasurementTopic(), msg)
+	}
+}
+
+// everySchedule invokes f in the background at each activation of the schedule.
+func everySchedule(ctx context.Context, schedule cron.Schedule, f func()) {
+	go func() {
+		var last time.Time
+		for {
+			now := time.Now()
+			last = nextActivation(schedule, now, last)
+			select {
+			case <-time.After(last.Sub(now)):
+				f()
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// longestGap returns the longest gap between consecutive activations of the schedule within scheduleGapHorizon
+// after the first activation after from.
+func longestGap(schedule cron.Schedule, from time.Time) time.Duration {
+	var longest time.Duration
+	prev := schedule.Next(from)
+	for end := prev.Add(scheduleGapHorizon); prev.Before(end); {
+		next := schedule.Next(prev)
+		if next.IsZero() {
+			break
+		}
+		longest = max(longest, next.Sub(prev))
+		prev = next
+	}
+	return longest
+}
+
+// nextActivation returns the next activation of the schedule after now. If the clock has been set back since the
+// last activation, the activation after the last one is returned, so no activation is run twice.
+func nextActivation(schedule cron.Schedule, now, last time.Time) time.Time {
+	next := schedule.Next(now)
+	if !last.IsZero() && !next.After(last) {
+		next = schedule.Next(last)
+	}
+	return next
+}
+
+// everyInterval invokes f in the given interval until ctx is done, applying interval changes received on the channel.
+// The ticker is based on the monotonic clock, so adjustments of the wall clock do not skip or double readings.
+func everyInterval(ctx context.Context, interval time.Duration, changes <-chan time.Duration, f func()) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				f()
+			case interval = <-changes:
+				ticker.Reset(interval)
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+}
+
+// untilAligned returns the duration until the next multiple of the interval since the unix epoch, so readings of
+// multiple sensors with the same interval line up.
+func untilAligned(now time.Time, interval time.Duration) time.Duration {
+	if interval <= 0 {
+		return 0
+	}
+	return now.Truncate(interval).Add(interval).Sub(now)
+}
+
+// isClockSynced checks whether the system clock is plausible if required by the config. Once plausible, the clock
+// is not checked again.
+func (station *WeatherBotAdaptors) isClockSynced() bool {
+	if !station.Config.RequireSyncedClock || station.clockSynced {
+		return true
+	}
+
+	station.clockSynced = clockPlausible(time.Now(), timesyncSynchronizedFile)
+	return station.clockSynced
+}
+
+// isMetricsUpdateDue decides whether the metrics are updated for the current reading, as metrics may be updated
+// at a lower cadence than the sensor is read.
+func (station *WeatherBotAdaptors) isMetricsUpdateDue() bool {
+	if station.Config.MetricsIntervalSecs <= station.Config.IntervalSecs {
+		return true
+	}
+
+	every := station.Config.MetricsIntervalSecs / station.Config.IntervalSecs
+	return station.readings%every == 0
+}
+
+// publishMeasurement publishes the measurement, buffering it while the broker is unavailable. Buffered measurements
+// are replayed with their original timestamps before the current measurement once the broker is reachable again.
+func (station *WeatherBotAdaptors) publishMeasurement(msg []byte) {
+	if station.Spool != nil {
+		station.publishSpooling(msg)
+		return
+	}
+
+	topic := station.Config.MqttConfig.MeasurementTopic()
+	if station.offlineBuffer.len() > 0 {
+		replayed := station.offlineBuffer.replay(func(buffered []byte) bool {
+			return station.publish(topic, buffered)
+		})
+		if replayed > 0 {
+			slog.Info("Replayed buffered measurements", "count", replayed)
+		}
+	}
+
+	published := station.offlineBuffer.len() == 0 && station.publishWithRetries(topic, msg)
+	station.recordPublish(published)
+	if !published {
+		if station.offlineBuffer.push(msg) {
+			metricsMessagesBuffered.WithLabelValues(station.Config.Placement).Inc()
 		} else {
-			metricsMessagePublishErrors.WithLabelValues(station.Config.Placement).Inc()
+			metricsOfflineBufferDropped.WithLabelValues(station.Config.Placement).Inc()
+		}
+	}
+	metricsOfflineBuffered.WithLabelValues(station.Config.Placement).Set(float64(station.offlineBuffer.len()))
+}
+
+// publishFields publishes each value of the measurement to the topic of its field, skipping the values that could
+// not be read, and returns whether all values were published. Unlike measurements published as JSON, the values are
+// not buffered while the broker is unavailable.
+func (station *WeatherBotAdaptors) publishFields(m Measurement) bool {
+	published := true
+	for _, v := range []struct {
+		name  string
+		value float32
+	}{
+		{"temperature", m.Temperature},
+		{"humidity", m.Humidity},
+		{"pressure", m.Pressure},
+	} {
+		if m.Failed(v.name) {
+			continue
 		}
+		topic := station.Config.MqttConfig.FieldTopic(station.Config.MeasurementName(v.name))
+		value := strconv.FormatFloat(float64(v.value), 'f', -1, 32)
+		if !station.publishWithRetries(topic, []byte(value)) {
+			published = false
+		}
+	}
+	return published
+}
+
+// publishSpooling publishes the measurement after draining the spool, spooling it to disk if it can not be published.
+func (station *WeatherBotAdaptors) publishSpooling(msg []byte) {
+	station.drainSpool()
+	published := station.Spool.Len() == 0 && station.publishWithRetries(station.Config.MqttConfig.MeasurementTopic(), msg)
+	station.recordPublish(published)
+	if published {
+		return
+	}
+
+	allowed, changed := station.DiskGuard.check(station.Spool.path, "spool")
+	if !allowed && changed {
+		slog.Warn("Free disk space below threshold, not spooling measurements")
+	} else if allowed && changed {
+		slog.Info("Free disk space above threshold again, spooling measurements")
+	}
+	if !allowed {
+		return
+	}
+
+	dropped, err := station.Spool.Append(msg, time.Now())
+	if err != nil {
+		slog.Error("Could not spool measurement", "file", station.Spool.path, "err", err)
+	}
+	if dropped > 0 {
+		metricsSpoolDropped.WithLabelValues(station.Config.Placement).Add(float64(dropped))
+	}
+}
+
+// DrainSpool publishes the spooled measurements in the order they were spooled, it's meant to be invoked after
+// (re-)connecting to the broker.
+func (station *WeatherBotAdaptors) DrainSpool() {
+	station.mu.Lock()
+	defer station.mu.Unlock()
+
+	if station.Spool != nil {
+		station.drainSpool()
+	}
+}
+
+func (station *WeatherBotAdaptors) drainSpool() {
+	if station.Spool.Len() == 0 {
+		return
+	}
+
+	topic := station.Config.MqttConfig.MeasurementTopic()
+	drained, err := station.Spool.Drain(func(msg []byte) bool {
+		return station.publish(topic, msg)
+	})
+	if err != nil {
+		slog.Error("Could not drain spool", "file", station.Spool.path, "err", err)
+	}
+	if drained > 0 {
+		slog.Info("Replayed spooled measurements", "placement", station.Config.Placement, "count", drained)
+	}
+}
+
+// recordPublish counts the consecutive failed publishes of measurements and invokes PublishFailuresExceeded once
+// they exceed the configured maximum.
+func (station *WeatherBotAdaptors) recordPublish(success bool) {
+	if success {
+		station.publishFailures = 0
+		return
+	}
+
+	station.publishFailures++
+	max := station.Config.MaxConsecutivePublishFailures
+	if max > 0 && station.publishFailures == max+1 && station.PublishFailuresExceeded != nil {
+		slog.Warn("Publishing failed repeatedly", "consecutive_failures", station.publishFailures)
+		station.PublishFailuresExceeded()
 	}
 }
 
+// publishWithRetries publishes the message, retrying a failed publish up to the configured amount of times.
+func (station *WeatherBotAdaptors) publishWithRetries(topic string, msg []byte) bool {
+	for attempt := 0; ; attempt++ {
+		if station.publish(topic, msg) {
+			if attempt > 0 {
+				metricsMessagesRetried.WithLabelValues(station.Config.Placement).Inc()
+			}
+			return true
+		}
+		if attempt >= station.Config.PublishRetries {
+			return false
+		}
+		time.Sleep(publishRetryDelay)
+	}
+}
+
+func (station *WeatherBotAdaptors) publish(topic string, msg []byte) bool {
+	success := station.MqttAdaptor.Publish(station.Config.MqttConfig.PrefixedTopic(topic), msg)
+	if success {
+		metricsMessagesPublished.WithLabelValues(station.Config.Placement).Inc()
+		metricsLastPublishSuccess.WithLabelValues(station.Config.Placement).Set(1)
+	} else {
+		metricsMessagePublishErrors.WithLabelValues(station.Config.Placement).Inc()
+		metricsLastPublishSuccess.WithLabelValues(station.Config.Placement).Set(0)
+	}
+	return success
+}
+
+func (station *WeatherBotAdaptors) publishComfort(m Measurement) {
+	conf := station.Config.SensorConfig
+	temperature := classifyTemperature(float64(m.Temperature), conf.ComfortTemperatureMin, conf.ComfortTemperatureMax)
+	humidity := classifyHumidity(float64(m.Humidity), conf.ComfortHumidityMin, conf.ComfortHumidityMax)
+
+	station.publish(station.Config.MqttConfig.FieldTopic("comfort/"+conf.MeasurementName("temperature")), []byte(temperature))
+	station.publish(station.Config.MqttConfig.FieldTopic("comfort/"+conf.MeasurementName("humidity")), []byte(humidity))
+}
+
+// publishWeather publishes the composite weather object aggregating the published measurement and the derived values,
+// which are based on the physical values of the measurement.
+func (station *WeatherBotAdaptors) publishWeather(m, published Measurement, deltas []delta) {
+	weather := NewWeather(station.Config, published)
+	weather.addDeltas(deltas)
+	if len(m.Errors) == 0 {
+		if station.Config.PublishComfort {
+			conf := station.Config.SensorConfig
+			weather.Comfort = map[string]string{
+				"temperature": classifyTemperature(float64(m.Temperature), conf.ComfortTemperatureMin, conf.ComfortTemperatureMax),
+				"humidity":    classifyHumidity(float64(m.Humidity), conf.ComfortHumidityMin, conf.ComfortHumidityMax),
+			}
+		}
+		if station.Config.PublishComfortIndex {
+			index := station.comfortIndex(m)
+			weather.ComfortIndex = &index
+		}
+	}
+
+	msg, err := weather.AsJson()
+	if err != nil {
+		slog.Error("Could not marshal weather", "err", err)
+		return
+	}
+	station.publish(station.Config.MqttConfig.FieldTopic(WeatherTopic), msg)
+}
+
+func (station *WeatherBotAdaptors) publishVentilation(m Measurement) {
+	outdoor, ok := station.outdoor.get(time.Now())
+	if !ok {
+		return
+	}
+
+	indoor := absoluteHumidity(float64(m.Temperature), float64(m.Humidity))
+	ventilate := shouldVentilate(indoor, outdoor, station.Config.VentilationMargin)
+	station.publish(station.Config.MqttConfig.FieldTopic("ventilate"), []byte(strconv.FormatBool(ventilate)))
+}
+
+func (station *WeatherBotAdaptors) comfortIndex(m Measurement) float64 {
+	conf := station.Config.SensorConfig
+	index := comfortIndex(float64(m.Temperature), float64(m.Humidity), conf.ComfortIdealTemperature, conf.ComfortIdealHumidity)
+	return roundTo(index, conf.DerivedDecimalPlaces)
+}
+
+func (station *WeatherBotAdaptors) notifyAlert(errs []string) {
+	if station.Alert == nil {
+		return
+	}
+
+	alert := station.Alert.record(station.Config.Placement, station.consecutiveErrors, errs, time.Now())
+	if alert == nil {
+		return
+	}
+	slog.Info("Sending alert to the webhook", "status", alert.Status)
+	if err := station.Alert.send(*alert); err != nil {
+		slog.Warn("Could not send alert", "err", err)
+		metricsAlertErrors.WithLabelValues(station.Config.Placement).Inc()
+	} else {
+		metricsAlerts.WithLabelValues(station.Config.Placement, alert.Status).Inc()
+	}
+}
+
+func (station *WeatherBotAdaptors) pushMetrics() {
+	if station.RemoteWrite == nil {
+		return
+	}
+
+	if err := station.RemoteWrite.Push(); err != nil {
+		slog.Warn("Could not push metrics via remote-write", "err", err)
+		metricsRemoteWritePushErrors.WithLabelValues(station.Config.Placement).Inc()
+	} else {
+		metricsRemoteWritePushes.WithLabelValues(station.Config.Placement).Inc()
+	}
+}
+
+// reader returns the reader of the sensor, which reads from the driver unless a Reader is set.
+func (station *WeatherBotAdaptors) reader() SensorReader {
+	if station.Reader != nil {
+		return station.Reader
+	}
+	return driverReader{station: station}
+}
+
 func (station *WeatherBotAdaptors) readMeasurement() Measurement {
+	start := time.Now()
 	measurement := NewMeasurement()
-	measurement.AddAltitude(station.Driver.Altitude())
-	measurement.AddHumidity(station.Driver.Humidity())
-	measurement.AddPressure(station.Driver.Pressure())
-	measurement.AddTemperature(station.Driver.Temperature())
+	interval := time.Duration(station.Config.IntervalSecs) * time.Second
+	measurement.Timestamp = formatTimestamp(start, station.Config.TimestampPrecision, interval)
+	measurement.Metadata = station.Metadata
+	if station.Config.IncludeCorrelationId {
+		measurement.CorrelationId = newCorrelationId()
+		measurement.logger().Debug("Reading sensor", "placement", station.Config.Placement)
+	}
+	defer func() {
+		metricReadDuration.WithLabelValues(station.Config.Placement).Observe(time.Since(start).Seconds())
+	}()
+	if station.Config.SleepBetweenReads {
+		station.wakeSensor()
+		defer station.sleepSensor()
+	}
+	reading, err := station.reader().Read()
+	valueErr := func(name string) error {
+		if err != nil {
+			return err
+		}
+		return reading.Errors[name]
+	}
+	measurement.AddAltitude(reading.Altitude, valueErr("altitude"))
+	if station.humidityUnsupported {
+		measurement.unsupported = append(measurement.unsupported, "humidity")
+	} else {
+		measurement.AddHumidity(reading.Humidity, valueErr("humidity"))
+	}
+	measurement.AddPressure(reading.Pressure, valueErr("pressure"))
+	measurement.AddTemperature(reading.Temperature, valueErr("temperature"))
+	if !station.Config.DisableHumidityClamping && !measurement.Failed("humidity") {
+		if clamped := clampHumidity(measurement.Humidity); clamped != measurement.Humidity {
+			if station.Config.LogSensor {
+				measurement.logger().Info("Clamped humidity, condensation is likely", "placement", station.Config.Placement, "humidity", measurement.Humidity, "clamped", clamped)
+			}
+			measurement.Humidity = clamped
+		}
+	}
+
+	if station.Config.LogRaw {
+		station.logRaw(measurement)
+	}
+
+	measurement.rawTemperature = measurement.Temperature
+	if station.Config.SelfHeatingCoefficient > 0 {
+		// failed readings heat up the sensor as well
+		bias := selfHeatingBias(station.readDensity.record(start), station.Config.SelfHeatingCoefficient)
+		if !measurement.Failed("temperature") {
+			measurement.Temperature -= float32(bias)
+		}
+	}
+
+	station.addDerivedValues(&measurement)
+	if station.Voltage != nil {
+		measurement.AddVoltage(station.Voltage.Voltage())
+	}
+	for _, name := range measurement.RemoveNonFinite() {
+		metricNonFiniteValues.WithLabelValues(station.Config.Placement, name).Inc()
+	}
 	return measurement
 }
+
+// addDerivedValues computes the configured derived values of a complete measurement.
+func (station *WeatherBotAdaptors) addDerivedValues(m *Measurement) {
+	if len(m.Errors) > 0 || !(station.Config.PublishSeaLevelPressure || station.Config.PublishSpecificHumidity) {
+		return
+	}
+
+	start := time.Now()
+	if station.Config.PublishSeaLevelPressure {
+		m.AddSeaLevelPressure(station.Config.StationAltitudeMeters)
+		m.PressureSeaLevel = float32(roundTo(float64(m.PressureSeaLevel), station.Config.DerivedDecimalPlaces))
+	}
+	if station.Config.PublishSpecificHumidity {
+		m.AddSpecificHumidity()
+	}
+	metricDerivedDuration.WithLabelValues(station.Config.Placement).Observe(time.Since(start).Seconds())
+}
+
+// isReconnectDue returns whether the sensor should be reconnected after the configured amount of consecutive errors.
+func (station *WeatherBotAdaptors) isReconnectDue() bool {
+	after := station.Config.ReconnectSensorAfterErrors
+	return after > 0 && station.consecutiveErrors > 0 && station.consecutiveErrors%after == 0
+}
+
+// reconnectSensor tears down and re-initializes the driver and the adaptor of the sensor, to recover from a stale
+// bus handle without restarting the process.
+func (station *WeatherBotAdaptors) reconnectSensor() {
+	slog.Warn("Reconnecting sensor", "placement", station.Config.Placement, "consecutive_errors", station.consecutiveErrors)
+	metricSensorReconnects.WithLabelValues(station.Config.Placement).Inc()
+
+	if err := station.Driver.Halt(); err != nil {
+		slog.Warn("Could not halt driver", "err", err)
+	}
+	if err := station.Adaptor.Finalize(); err != nil {
+		slog.Warn("Could not finalize adaptor", "err", err)
+	}
+	if err := station.Adaptor.Connect(); err != nil {
+		slog.Error("Could not reconnect adaptor", "err", err)
+		return
+	}
+	if err := station.Driver.Start(); err != nil {
+		slog.Error("Could not restart driver", "err", err)
+		return
+	}
+
+	if station.Config.ResetStateOnReinit {
+		station.resetState()
+	}
+}
+
+// resetState discards the state derived from previous readings, which may be stale after a gap in the readings.
+func (station *WeatherBotAdaptors) resetState() {
+	slog.Info("Resetting state derived from previous readings", "placement", station.Config.Placement)
+	station.stability.reset()
+	station.smoother.reset()
+	station.stuck.reset()
+	station.previous = nil
+	station.pressureTendency.reset()
+	station.extremes.reset()
+}
+
+// checkStuck flags the measured values that did not change for more than StuckReadsThreshold reads. The altitude is
+// not checked, as it is derived from the pressure.
+func (station *WeatherBotAdaptors) checkStuck(m Measurement) {
+	for name, value := range map[string]float32{
+		"humidity":    m.Humidity,
+		"pressure":    m.Pressure,
+		"temperature": m.Temperature,
+	} {
+		if m.Failed(name) {
+			continue
+		}
+		stuck, changed := station.stuck.record(name, value)
+		if stuck && changed {
+			m.logger().Warn("Sensor keeps returning the identical value, it may be stuck", "placement", station.Config.Placement,
+				"measurement", name, "value", value, "reads", station.Config.StuckReadsThreshold+1)
+		} else if changed {
+			m.logger().Info("Sensor returns changing values again", "placement", station.Config.Placement, "measurement", name)
+		}
+		gauge := 0.0
+		if stuck {
+			gauge = 1
+		}
+		metricStuck.WithLabelValues(station.Config.Placement, name).Set(gauge)
+	}
+}
+
+// readingLogMessage is the message the readings are logged with.
+const readingLogMessage = "Read sensor"
+
+// logReading logs the values of the measurement, the temperature in the configured unit.
+func (station *WeatherBotAdaptors) logReading(m Measurement) {
+	m = m.InTemperatureUnit(station.Config.TemperatureUnit)
+	m.logger().Info(readingLogMessage, readingAttrs(m, station.Config.Placement)...)
+}
+
+// readingAttrs returns the attributes a reading is logged with.
+func readingAttrs(m Measurement, placement string) []any {
+	return []any{"placement", placement, "temperature", m.Temperature, "humidity", m.Humidity, "pressure", m.Pressure}
+}
+
+func (station *WeatherBotAdaptors) logRaw(m Measurement) {
+	raw, err := readRawRegisters(station.Driver)
+	if err != nil {
+		slog.Warn("Could not read raw registers from sensor", "err", err)
+		return
+	}
+	slog.Info("Raw registers", "adc_T", raw.Temperature, "adc_P", raw.Pressure, "adc_H", raw.Humidity,
+		"temperature", m.Temperature, "pressure", m.Pressure, "humidity", m.Humidity)
+}
+
+func (station *WeatherBotAdaptors) updateSensorMode() {
+	ctrl, err := station.Driver.Read(strconv.Itoa(regCtrlMeas))
+	if err != nil {
+		slog.Warn("Could not read power mode from sensor", "err", err)
+		return
+	}
+	metricSensorMode.WithLabelValues(station.Config.Placement).Set(float64(sensorMode(ctrl)))
+}
+
+// sleepSensor puts the sensor into sleep mode until the next reading, so it does not heat itself up.
+func (station *WeatherBotAdaptors) sleepSensor() {
+	if err := station.BusLock.lock(); err != nil {
+		slog.Warn("Could not put sensor to sleep", "err", err)
+		return
+	}
+	defer station.BusLock.unlock()
+
+	ctrl, err := station.Driver.Read(strconv.Itoa(regCtrlMeas))
+	if err != nil {
+		slog.Warn("Could not read power mode from sensor", "err", err)
+		return
+	}
+	ctrl &^= ctrlMeasModeMask
+	if err := station.Driver.Write(strconv.Itoa(regCtrlMeas), ctrl|sensorModeSleep); err != nil {
+		slog.Warn("Could not put sensor to sleep", "err", err)
+		return
+	}
+	station.asleepCtrl = &ctrl
+	station.updateSensorMode()
+}
+
+// wakeSensor puts the sensor back into normal mode and waits for the first conversion to finish.
+func (station *WeatherBotAdaptors) wakeSensor() {
+	if station.asleepCtrl == nil {
+		return
+	}
+
+	if err := station.BusLock.lock(); err != nil {
+		slog.Warn("Could not wake sensor up", "err", err)
+		return
+	}
+	err := station.Driver.Write(strconv.Itoa(regCtrlMeas), *station.asleepCtrl|sensorModeNormal)
+	station.BusLock.unlock()
+	if err != nil {
+		slog.Warn("Could not wake sensor up", "err", err)
+		return
+	}
+	station.asleepCtrl = nil
+	station.updateSensorMode()
+	time.Sleep(wakeDelay)
+}
+
+// sensorMode extracts the power mode from the ctrl_meas register. Both 0x01 and 0x02 denote forced mode.
+func sensorMode(ctrlMeas int) int {
+	mode := ctrlMeas & ctrlMeasModeMask
+	if mode == sensorModeForced2 {
+		return sensorModeForced
+	}
+	return mode
+}
+
+// retryable returns whether further samples are read after the error according to the config.
+func (station *WeatherBotAdaptors) retryable(err error) bool {
+	return station.Config.RetryReadErrors == config.RetryReadErrorsAll || !isFatalReadError(err)
+}
+
+// retrying wraps the read of a sample, so a failed read is retried up to ReadMaxRetries times with an exponentially
+// growing delay before the sample counts as failed. Errors that are not retryable are not retried, and no further
+// attempts are started once the bot is shutting down.
+func (station *WeatherBotAdaptors) retrying(read func() (float32, error)) func() (float32, error) {
+	return func() (float32, error) {
+		delay := time.Duration(station.Config.ReadRetryDelayMs) * time.Millisecond
+		for attempt := 0; ; attempt++ {
+			val, err := read()
+			if err == nil {
+				return val, nil
+			}
+			metricReadErrors.WithLabelValues(station.Config.Placement).Inc()
+			if attempt >= station.Config.ReadMaxRetries || !station.retryable(err) {
+				return val, err
+			}
+
+			select {
+			case <-time.After(delay):
+			case <-station.done:
+				return val, err
+			}
+			delay *= 2
+		}
+	}
+}
+
+// readAveraged performs the given amount of reads and returns the mean of all successful reads. An error is only
+// returned if none of the reads succeeded. After an error that is not retryable, no further reads are performed. If
+// retryable is nil, all errors are retried.
+func readAveraged(samples int, retryable func(error) bool, read func() (float32, error)) (float32, error) {
+	var sum float64
+	var successful int
+	var err error
+	for i := 0; i < samples; i++ {
+		if i > 0 {
+			time.Sleep(sampleDelay)
+		}
+
+		val, readErr := read()
+		if readErr != nil {
+			err = readErr
+			if retryable != nil && !retryable(readErr) {
+				break
+			}
+			continue
+		}
+		sum += float64(val)
+		successful++
+	}
+
+	if successful == 0 {
+		return 0, err
+	}
+	return float32(sum / float64(successful)), nil
+}
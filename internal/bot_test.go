@@ -1,10 +1,20 @@
 package internal
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/robfig/cron/v3"
 	"github.com/soerenschneider/gobot-bme280/internal/config"
 	"gobot.io/x/gobot/v2"
 	"log"
+	"log/slog"
+	"math"
+	"path/filepath"
+	"reflect"
+	"strconv"
 	"testing"
 	"time"
 )
@@ -35,7 +45,7 @@ func TestAssembleBot(t *testing.T) {
 		t.Errorf("Failed precondition, msg isn't empty: %s", mqttAdaptor.Msg)
 	}
 
-	bot := AssembleBot(station)
+	bot := AssembleBot(context.Background(), station)
 	go func() {
 		_ = bot.Start()
 	}()
@@ -68,9 +78,1011 @@ func TestAssembleBot(t *testing.T) {
 	}
 }
 
+func TestAssembleBotMultipleSensors(t *testing.T) {
+	var stations []*WeatherBotAdaptors
+	for _, placement := range []string{"attic", "cellar"} {
+		conf := config.DefaultConfig()
+		conf.Placement = placement
+		station, _ := newTestStation(conf)
+		stations = append(stations, station)
+	}
+	stations[1].MqttAdaptor = stations[0].MqttAdaptor
+
+	bot := AssembleBot(context.Background(), stations...)
+	if got := bot.Devices().Len(); got != 2 {
+		t.Errorf("expected a device per sensor, got %d", got)
+	}
+	if got := bot.Connections().Len(); got != 2 {
+		t.Errorf("expected the connections of the first sensor only, got %d", got)
+	}
+	if stations[0].extremes == stations[1].extremes {
+		t.Errorf("expected the extremes to be tracked per sensor")
+	}
+}
+
+func TestAssembleBotMqttAdaptorPerSensor(t *testing.T) {
+	var stations []*WeatherBotAdaptors
+	for _, placement := range []string{"attic", "cellar"} {
+		conf := config.DefaultConfig()
+		conf.Placement = placement
+		station, _ := newTestStation(conf)
+		stations = append(stations, station)
+	}
+
+	bot := AssembleBot(context.Background(), stations...)
+	if got := bot.Connections().Len(); got != 3 {
+		t.Errorf("expected the MQTT adaptor of each sensor to be connected, got %d connections", got)
+	}
+}
+
+type fakeReader struct {
+	reading Reading
+	err     error
+}
+
+func (r *fakeReader) Read() (Reading, error) {
+	return r.reading, r.err
+}
+
+type recordingSink struct {
+	written []Measurement
+}
+
+func (s *recordingSink) Write(m Measurement, _ string) error {
+	s.written = append(s.written, m)
+	return nil
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_reader(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Placement = "reader"
+	conf.Topic = "sensors/reader"
+	mqttAdaptor := &FakeMqttAdapter{}
+	reader := &fakeReader{reading: Reading{Temperature: 21.5, Humidity: 40, Pressure: 101325, Altitude: 12}}
+	sink := &recordingSink{}
+	station := &WeatherBotAdaptors{
+		Reader:      reader,
+		MqttAdaptor: mqttAdaptor,
+		Influx:      sink,
+		Config:      conf,
+	}
+	station.setup()
+
+	station.readAndPublishMeasurement()
+	if len(sink.written) != 1 || sink.written[0].Temperature != 21.5 || sink.written[0].Pressure != 101325 {
+		t.Fatalf("expected the reading to be written to the sink, got %v", sink.written)
+	}
+	if len(mqttAdaptor.Published) != 1 || mqttAdaptor.Published[0].Topic != "sensors/reader" {
+		t.Fatalf("expected the reading to be published, got %v", mqttAdaptor.Published)
+	}
+
+	reader.reading.Errors = map[string]error{"humidity": errors.New("humidity not available")}
+	station.readAndPublishMeasurement()
+	if !sink.written[1].Failed("humidity") || sink.written[1].Failed("temperature") {
+		t.Errorf("expected only the humidity to fail, got %v", sink.written[1])
+	}
+
+	reader.err = errors.New("sensor gone")
+	station.readAndPublishMeasurement()
+	if station.consecutiveErrors != 2 || !sink.written[2].Failed("temperature") {
+		t.Errorf("expected all values to fail if the reader fails, got %d consecutive errors", station.consecutiveErrors)
+	}
+}
+
+func TestAssembleBotReader(t *testing.T) {
+	conf := config.DefaultConfig()
+	station := &WeatherBotAdaptors{
+		Reader:      &fakeReader{},
+		MqttAdaptor: &FakeMqttAdapter{},
+		Config:      conf,
+	}
+
+	bot := AssembleBot(context.Background(), station)
+	if got := bot.Devices().Len(); got != 0 {
+		t.Errorf("expected no devices without a driver, got %d", got)
+	}
+	if got := bot.Connections().Len(); got != 1 {
+		t.Errorf("expected the MQTT adaptor to be connected, got %d connections", got)
+	}
+}
+
+func newTestStation(conf config.Config) (*WeatherBotAdaptors, *FakeMqttAdapter) {
+	mqttAdaptor := &FakeMqttAdapter{}
+	station := &WeatherBotAdaptors{
+		Driver:      &FakeBme280{Conn: mqttAdaptor},
+		Adaptor:     mqttAdaptor,
+		MqttAdaptor: mqttAdaptor,
+		Config:      conf,
+	}
+	station.setup()
+	return station, mqttAdaptor
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_delta(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	conf.PublishDelta = true
+	station, mqttAdaptor := newTestStation(conf)
+
+	station.readAndPublishMeasurement()
+	if len(mqttAdaptor.Published) != 1 {
+		t.Fatalf("expected only the measurement for the first interval, got %v", mqttAdaptor.Published)
+	}
+
+	station.readAndPublishMeasurement()
+	want := []string{"sensors/test", "sensors/test/temperature/delta", "sensors/test/humidity/delta", "sensors/test/pressure/delta"}
+	var got []string
+	for _, msg := range mqttAdaptor.Published[1:] {
+		got = append(got, msg.Topic)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("published topics = %v, want %v", got, want)
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_offlineBuffer(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	conf.OfflineBufferSize = 5
+	station, mqttAdaptor := newTestStation(conf)
+
+	mqttAdaptor.Unavailable = true
+	station.readAndPublishMeasurement()
+	station.readAndPublishMeasurement()
+	if station.offlineBuffer.len() != 2 {
+		t.Fatalf("expected 2 buffered measurements, got %d", station.offlineBuffer.len())
+	}
+
+	mqttAdaptor.Unavailable = false
+	station.readAndPublishMeasurement()
+	if len(mqttAdaptor.Published) != 3 || station.offlineBuffer.len() != 0 {
+		t.Errorf("expected buffered measurements to be replayed, published %d, buffered %d", len(mqttAdaptor.Published), station.offlineBuffer.len())
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_spool(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	station, mqttAdaptor := newTestStation(conf)
+	spool, err := NewSpool(filepath.Join(t.TempDir(), "test.spool"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	station.Spool = spool
+
+	mqttAdaptor.Unavailable = true
+	station.readAndPublishMeasurement()
+	station.readAndPublishMeasurement()
+	if spool.Len() != 2 {
+		t.Fatalf("expected 2 spooled measurements, got %d", spool.Len())
+	}
+
+	mqttAdaptor.Unavailable = false
+	station.DrainSpool()
+	if len(mqttAdaptor.Published) != 2 || spool.Len() != 0 {
+		t.Errorf("expected spooled measurements to be drained, published %d, spooled %d", len(mqttAdaptor.Published), spool.Len())
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_spoolLowDiskSpace(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	station, mqttAdaptor := newTestStation(conf)
+	spool, err := NewSpool(filepath.Join(t.TempDir(), "test.spool"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	station.Spool = spool
+	station.DiskGuard = NewDiskGuard(conf.Placement, 1)
+	station.DiskGuard.free = func(string) (uint64, error) { return 1024, nil }
+
+	mqttAdaptor.Unavailable = true
+	station.readAndPublishMeasurement()
+	if spool.Len() != 0 {
+		t.Errorf("expected nothing to be spooled without free disk space, got %d", spool.Len())
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_publishRetries(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	conf.PublishRetries = 2
+	conf.OfflineBufferSize = 5
+	station, mqttAdaptor := newTestStation(conf)
+
+	mqttAdaptor.FailPublishes = 2
+	station.readAndPublishMeasurement()
+	if len(mqttAdaptor.Published) != 1 || station.offlineBuffer.len() != 0 {
+		t.Fatalf("expected the measurement to be published after retrying, published %d, buffered %d", len(mqttAdaptor.Published), station.offlineBuffer.len())
+	}
+
+	mqttAdaptor.FailPublishes = 3
+	station.readAndPublishMeasurement()
+	if len(mqttAdaptor.Published) != 1 || station.offlineBuffer.len() != 1 {
+		t.Errorf("expected the measurement to be buffered once all retries failed, published %d, buffered %d", len(mqttAdaptor.Published), station.offlineBuffer.len())
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_maxPublishFailures(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	conf.MaxConsecutivePublishFailures = 2
+	station, mqttAdaptor := newTestStation(conf)
+	exceeded := 0
+	station.PublishFailuresExceeded = func() {
+		exceeded++
+	}
+
+	mqttAdaptor.Unavailable = true
+	station.readAndPublishMeasurement()
+	station.readAndPublishMeasurement()
+	if exceeded != 0 {
+		t.Fatal("expected the failures not to exceed the maximum yet")
+	}
+	station.readAndPublishMeasurement()
+	station.readAndPublishMeasurement()
+	if exceeded != 1 {
+		t.Errorf("expected exceeding the maximum to be reported once, got %d", exceeded)
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_age(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	conf.PublishAge = true
+	station, mqttAdaptor := newTestStation(conf)
+	station.Driver = &failingBme280{}
+	station.lastSuccess = time.Now().Add(-90 * time.Second)
+
+	station.readAndPublishMeasurement()
+	if len(mqttAdaptor.Published) == 0 {
+		t.Fatal("expected the age to be published")
+	}
+	got := mqttAdaptor.Published[0]
+	if got.Topic != "sensors/test/age_seconds" || string(got.Msg) != "90" {
+		t.Errorf("published %s %q, want sensors/test/age_seconds 90", got.Topic, got.Msg)
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_humidityDerived(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	conf.Placement = "humidity_derived"
+	conf.PublishDewPoint = true
+	conf.PublishAbsoluteHumidity = true
+	station, mqttAdaptor := newTestStation(conf)
+
+	station.readAndPublishMeasurement()
+	published := map[string]string{}
+	for _, msg := range mqttAdaptor.Published {
+		published[msg.Topic] = string(msg.Msg)
+	}
+	if published["sensors/test/dew_point"] != "-7.49" {
+		t.Errorf("dew point = %q, want -7.49", published["sensors/test/dew_point"])
+	}
+	if published["sensors/test/absolute_humidity"] != "2.56" {
+		t.Errorf("absolute humidity = %q, want 2.56", published["sensors/test/absolute_humidity"])
+	}
+
+	metric := &dto.Metric{}
+	if err := metricDewPoint.WithLabelValues(conf.Placement).Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	if val := metric.GetGauge().GetValue(); val != -7.49 {
+		t.Errorf("dew point gauge = %f, want -7.49", val)
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_lastReadAndPublish(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	conf.Placement = "last_read"
+	station, mqttAdaptor := newTestStation(conf)
+
+	before := time.Now().Unix()
+	station.readAndPublishMeasurement()
+	metric := &dto.Metric{}
+	if err := metricLastRead.WithLabelValues(conf.Placement).Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	if val := metric.GetGauge().GetValue(); val < float64(before) {
+		t.Errorf("last read timestamp = %f, want at least %d", val, before)
+	}
+	if err := metricsLastPublishSuccess.WithLabelValues(conf.Placement).Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	if val := metric.GetGauge().GetValue(); val != 1 {
+		t.Errorf("last publish success = %f, want 1", val)
+	}
+
+	mqttAdaptor.Unavailable = true
+	station.readAndPublishMeasurement()
+	if err := metricsLastPublishSuccess.WithLabelValues(conf.Placement).Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	if val := metric.GetGauge().GetValue(); val != 0 {
+		t.Errorf("last publish success with unavailable broker = %f, want 0", val)
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_fahrenheit(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	conf.Placement = "fahrenheit"
+	conf.TemperatureUnit = config.TemperatureUnitFahrenheit
+	conf.PublishDewPoint = true
+	station, mqttAdaptor := newTestStation(conf)
+
+	station.readAndPublishMeasurement()
+	published := map[string]string{}
+	for _, msg := range mqttAdaptor.Published {
+		published[msg.Topic] = string(msg.Msg)
+	}
+
+	m := &Measurement{}
+	if err := json.Unmarshal([]byte(published["sensors/test"]), m); err != nil {
+		t.Fatal(err)
+	}
+	if math.Abs(float64(m.Temperature)-72.05) > 0.001 {
+		t.Errorf("temperature = %f, want 72.05", m.Temperature)
+	}
+	if published["sensors/test/dew_point"] != "18.52" {
+		t.Errorf("dew point = %q, want 18.52", published["sensors/test/dew_point"])
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_logSensor(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(NewLogHandler(&buf, config.LogLevelInfo, config.LogFormatJson)))
+
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	conf.Placement = "logged"
+	conf.LogSensor = true
+	station, _ := newTestStation(conf)
+
+	station.readAndPublishMeasurement()
+	var record map[string]interface{}
+	for _, line := range bytes.Split(buf.Bytes(), []byte("\n")) {
+		entry := map[string]interface{}{}
+		if err := json.Unmarshal(line, &entry); err == nil && entry["msg"] == "Read sensor" {
+			record = entry
+		}
+	}
+	if record == nil {
+		t.Fatalf("expected a record of the reading, got %s", buf.String())
+	}
+	if record["placement"] != "logged" {
+		t.Errorf("placement = %v, want logged", record["placement"])
+	}
+	want := map[string]float64{
+		"temperature": MeasureDefaultsTemperature,
+		"humidity":    MeasureDefaultsHumidity,
+		"pressure":    MeasureDefaultsPressure,
+	}
+	for key, val := range want {
+		if got, ok := record[key].(float64); !ok || math.Abs(got-val) > 0.001 {
+			t.Errorf("%s = %v, want %v", key, record[key], val)
+		}
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_fieldTopics(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Placement = "attic"
+	conf.Topic = "home/{{.Placement}}/bme280/{{.Field}}"
+	conf.PublishDewPoint = true
+	conf.FormatTopic()
+	station, mqttAdaptor := newTestStation(conf)
+
+	station.readAndPublishMeasurement()
+	published := map[string]string{}
+	for _, msg := range mqttAdaptor.Published {
+		published[msg.Topic] = string(msg.Msg)
+	}
+	want := map[string]string{
+		"home/attic/bme280/temperature": "22.25",
+		"home/attic/bme280/humidity":    "13",
+		"home/attic/bme280/pressure":    "13.37",
+	}
+	for topic, value := range want {
+		if published[topic] != value {
+			t.Errorf("%s = %q, want %q", topic, published[topic], value)
+		}
+	}
+	if _, ok := published["home/attic/bme280/dew_point"]; !ok {
+		t.Errorf("expected the dew point to be published to its field topic, got %v", published)
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_bothPayloads(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Placement = "attic"
+	conf.Topic = "home/{{.Placement}}/bme280/{{.Field}}"
+	conf.CombinedTopic = "home/{{.Placement}}/bme280"
+	conf.PayloadFormat = config.PayloadFormatBoth
+	conf.FormatTopic()
+	station, mqttAdaptor := newTestStation(conf)
+
+	station.readAndPublishMeasurement()
+	published := map[string][]byte{}
+	for _, msg := range mqttAdaptor.Published {
+		published[msg.Topic] = msg.Msg
+	}
+	if got := string(published["home/attic/bme280/temperature"]); got != "22.25" {
+		t.Errorf("home/attic/bme280/temperature = %q, want %q", got, "22.25")
+	}
+	m := &Measurement{}
+	if err := json.Unmarshal(published["home/attic/bme280"], m); err != nil {
+		t.Fatalf("expected the measurement to be published to the combined topic: %v", err)
+	}
+	if m.Temperature != MeasureDefaultsTemperature {
+		t.Errorf("Expected %f, got %f", MeasureDefaultsTemperature, m.Temperature)
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_jsonPayload(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Placement = "attic"
+	conf.Topic = "sensors/test"
+	conf.PayloadFormat = config.PayloadFormatJson
+	conf.TemperatureUnit = config.TemperatureUnitFahrenheit
+	station, mqttAdaptor := newTestStation(conf)
+
+	station.readAndPublishMeasurement()
+	if len(mqttAdaptor.Published) != 1 || mqttAdaptor.Published[0].Topic != "sensors/test" {
+		t.Fatalf("expected a single message to the topic, got %v", mqttAdaptor.Published)
+	}
+	payload := map[string]any{}
+	if err := json.Unmarshal(mqttAdaptor.Published[0].Msg, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload["placement"] != "attic" || payload["temperature_c"] != MeasureDefaultsTemperature || payload["humidity_pct"] != MeasureDefaultsHumidity {
+		t.Errorf("unexpected payload %v", payload)
+	}
+	if pressure := payload["pressure_hpa"].(float64); math.Abs(pressure-MeasureDefaultsPressure/100) > 0.0001 {
+		t.Errorf("pressure_hpa = %f, want %f", pressure, MeasureDefaultsPressure/100)
+	}
+}
+
+func TestWeatherBotAdaptors_publishPlaceholders(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	conf.Placement = "placeholders"
+	station, mqttAdaptor := newTestStation(conf)
+
+	station.publishPlaceholders()
+	if len(mqttAdaptor.Published) != 1 || mqttAdaptor.Topic != "sensors/test" {
+		t.Fatalf("expected a placeholder measurement to be published, got %v", mqttAdaptor.Published)
+	}
+	var got Measurement
+	if err := json.Unmarshal(mqttAdaptor.Msg, &got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got.Errors, []string{errNoReadingYet}) {
+		t.Errorf("errors = %v, want %v", got.Errors, []string{errNoReadingYet})
+	}
+	metric := &dto.Metric{}
+	if err := metricTemperature.WithLabelValues(conf.Placement).Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	if val := metric.GetGauge().GetValue(); !math.IsNaN(val) {
+		t.Errorf("expected the temperature to be exposed as NaN, got %f", val)
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_weather(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	conf.Placement = "livingroom"
+	conf.PublishWeather = true
+	conf.PublishComfortIndex = true
+	station, mqttAdaptor := newTestStation(conf)
+
+	station.readAndPublishMeasurement()
+	if mqttAdaptor.Topic != "sensors/test/weather" {
+		t.Fatalf("expected the weather to be published last, got %v", mqttAdaptor.Published)
+	}
+	var got Weather
+	if err := json.Unmarshal(mqttAdaptor.Msg, &got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Placement != "livingroom" || got.Measurement.Temperature != MeasureDefaultsTemperature {
+		t.Errorf("unexpected weather %+v", got)
+	}
+	if got.Units["temp"] != "°C" || got.ComfortIndex == nil {
+		t.Errorf("expected units and the comfort index, got %+v", got)
+	}
+}
+
+func TestWeatherBotAdaptors_readMeasurement_correlationId(t *testing.T) {
+	conf := config.DefaultConfig()
+	station, _ := newTestStation(conf)
+	if id := station.readMeasurement().CorrelationId; id != "" {
+		t.Errorf("expected no correlation id by default, got %q", id)
+	}
+
+	station.Config.IncludeCorrelationId = true
+	first, second := station.readMeasurement(), station.readMeasurement()
+	if len(first.CorrelationId) != 8 || first.CorrelationId == second.CorrelationId {
+		t.Errorf("expected short unique correlation ids, got %q and %q", first.CorrelationId, second.CorrelationId)
+	}
+}
+
+func TestWeatherBotAdaptors_readMeasurement_selfHeating(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.SelfHeatingCoefficient = 1
+	station, _ := newTestStation(conf)
+
+	// a single reading within the window of 10 minutes is a density of 0.1 readings per minute
+	got := station.readMeasurement().Temperature
+	if want := float32(MeasureDefaultsTemperature - 0.1); got != want {
+		t.Errorf("temperature = %f, want %f", got, want)
+	}
+}
+
+func TestWeatherBotAdaptors_readMeasurement_sleepBetweenReads(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.SleepBetweenReads = true
+	station, _ := newTestStation(conf)
+	driver := station.Driver.(*FakeBme280)
+
+	sensorModeGauge := func() float64 {
+		metric := &dto.Metric{}
+		if err := metricSensorMode.WithLabelValues(conf.Placement).Write(metric); err != nil {
+			t.Fatal(err)
+		}
+		return metric.GetGauge().GetValue()
+	}
+
+	station.readMeasurement()
+	if got := sensorModeGauge(); got != sensorModeSleep {
+		t.Errorf("sensor mode = %v after reading, want %v", got, sensorModeSleep)
+	}
+	station.wakeSensor()
+	if got := sensorModeGauge(); got != sensorModeNormal {
+		t.Errorf("sensor mode = %v after waking up, want %v", got, sensorModeNormal)
+	}
+	station.readMeasurement()
+	// ctrl_meas of the fake is 0x27, i.e. normal mode
+	want := []int{0x24, 0x27, 0x24}
+	if !reflect.DeepEqual(driver.Writes, want) {
+		t.Errorf("written %#v, want %#v", driver.Writes, want)
+	}
+}
+
+// jitteringBme280 returns the given temperatures in turn.
+type jitteringBme280 struct {
+	FakeBme280
+	temperatures []float32
+}
+
+func (driver *jitteringBme280) Temperature() (temp float32, err error) {
+	temp = driver.temperatures[0]
+	driver.temperatures = driver.temperatures[1:]
+	return temp, nil
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_deadbandRounded(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	conf.DecimalPlaces = 1
+	conf.Deadbands = map[string]float64{"temperature": 0.05}
+	station, mqttAdaptor := newTestStation(conf)
+	// both readings are rounded to 21.2, the raw change exceeds the deadband
+	station.Driver = &jitteringBme280{FakeBme280: FakeBme280{Conn: mqttAdaptor}, temperatures: []float32{21.24, 21.16}}
+
+	station.readAndPublishMeasurement()
+	station.readAndPublishMeasurement()
+	if len(mqttAdaptor.Published) != 1 {
+		t.Errorf("expected the jitter below the rounding step to be suppressed, published %d", len(mqttAdaptor.Published))
+	}
+}
+
+type panickingBme280 struct {
+	FakeBme280
+}
+
+func (driver *panickingBme280) Pressure() (pressure float32, err error) {
+	panic("pressure not implemented")
+}
+
+func TestWeatherBotAdaptors_readAndPublishRecovering(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	station, mqttAdaptor := newTestStation(conf)
+	station.Driver = &panickingBme280{FakeBme280{Conn: mqttAdaptor}}
+
+	station.readAndPublishRecovering()
+	if len(mqttAdaptor.Published) != 0 {
+		t.Fatalf("expected nothing to be published, got %v", mqttAdaptor.Published)
+	}
+
+	// the lock must have been released, so the next interval is read
+	station.Driver = &FakeBme280{Conn: mqttAdaptor}
+	station.readAndPublishRecovering()
+	if len(mqttAdaptor.Published) != 1 {
+		t.Errorf("expected the next measurement to be published, got %v", mqttAdaptor.Published)
+	}
+}
+
+type failingHumidityBme280 struct {
+	FakeBme280
+}
+
+func (driver *failingHumidityBme280) Humidity() (humidity float32, err error) {
+	return 0, errors.New("humidity not available")
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_partial(t *testing.T) {
+	tests := []struct {
+		name        string
+		failPartial bool
+		want        int
+	}{
+		{
+			name:        "lenient",
+			failPartial: false,
+			want:        1,
+		},
+		{
+			name:        "strict",
+			failPartial: true,
+			want:        0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := config.DefaultConfig()
+			conf.FailPartial = tt.failPartial
+			station, mqttAdaptor := newTestStation(conf)
+			station.Driver = &failingHumidityBme280{}
+
+			station.readAndPublishMeasurement()
+			if len(mqttAdaptor.Published) != tt.want {
+				t.Fatalf("expected %d published messages, got %d", tt.want, len(mqttAdaptor.Published))
+			}
+			if tt.want == 0 {
+				return
+			}
+
+			m := &Measurement{}
+			if err := json.Unmarshal(mqttAdaptor.Msg, m); err != nil {
+				t.Fatal(err)
+			}
+			if m.Temperature != MeasureDefaultsTemperature || m.Humidity != -1 {
+				t.Errorf("expected temperature %f and no humidity, got %+v", MeasureDefaultsTemperature, m)
+			}
+		})
+	}
+}
+
+type failingBme280 struct {
+	FakeBme280
+	starts int
+}
+
+func (driver *failingBme280) Start() error {
+	driver.starts++
+	return nil
+}
+
+func (driver *failingBme280) Temperature() (temp float32, err error) {
+	return 0, errors.New("bus error")
+}
+
+func TestWeatherBotAdaptors_reconnectSensor(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.ReconnectSensorAfterErrors = 2
+	station, _ := newTestStation(conf)
+	driver := &failingBme280{}
+	station.Driver = driver
+
+	for i := 0; i < 5; i++ {
+		station.readAndPublishMeasurement()
+	}
+	if driver.starts != 2 {
+		t.Errorf("expected 2 reconnects after 5 consecutive errors, got %d", driver.starts)
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_availability(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.AvailabilityGraceIntervals = 2
+	station, mqttAdaptor := newTestStation(conf)
+	var changes []bool
+	station.AvailabilityChanged = func(available bool) {
+		changes = append(changes, available)
+	}
+
+	station.Driver = &failingBme280{}
+	station.readAndPublishMeasurement()
+	if len(changes) != 0 {
+		t.Fatalf("expected a single failed reading to be within the grace period, got %v", changes)
+	}
+	station.readAndPublishMeasurement()
+	station.readAndPublishMeasurement()
+	station.Driver = &FakeBme280{Conn: mqttAdaptor}
+	station.readAndPublishMeasurement()
+	if want := []bool{false, true}; !reflect.DeepEqual(changes, want) {
+		t.Errorf("availability changes = %v, want %v", changes, want)
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_withholdMetrics(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Placement = "withhold-metrics"
+	conf.StabilitySamples = 3
+	conf.StabilityThreshold = 0.1
+	conf.WithholdMetricsUntilStable = true
+	station, _ := newTestStation(conf)
+
+	station.readAndPublishMeasurement()
+	station.readAndPublishMeasurement()
+	if metricTemperature.DeleteLabelValues(conf.Placement) {
+		t.Errorf("expected temperature metric to be withheld until readings are stable")
+	}
+
+	station.readAndPublishMeasurement()
+	if !metricTemperature.DeleteLabelValues(conf.Placement) {
+		t.Errorf("expected temperature metric once readings are stable")
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_smoothing(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Placement = "smoothing"
+	conf.SmoothingWindow = 3
+	station, _ := newTestStation(conf)
+
+	station.readAndPublishMeasurement()
+	metric := &dto.Metric{}
+	if err := metricInstant.WithLabelValues(conf.Placement, "temperature").Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	if val := metric.GetGauge().GetValue(); math.Abs(val-MeasureDefaultsTemperature) > 0.001 {
+		t.Errorf("instant temperature gauge = %f, want %f", val, MeasureDefaultsTemperature)
+	}
+	if !metricTemperature.DeleteLabelValues(conf.Placement) {
+		t.Errorf("expected the smoothed temperature metric")
+	}
+
+	if err := metricStddev["temperature"].WithLabelValues(conf.Placement).Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	if val := metric.GetGauge().GetValue(); val != 0 {
+		t.Errorf("temperature stddev gauge = %f, want 0 for constant readings", val)
+	}
+
+	conf.Placement = "no-smoothing"
+	conf.SmoothingWindow = 1
+	conf.SamplesPerReading = 3
+	station, _ = newTestStation(conf)
+	station.readAndPublishMeasurement()
+	if metricInstant.DeleteLabelValues(conf.Placement, "temperature") {
+		t.Errorf("expected no instant metric without smoothing")
+	}
+	if metricStddev["temperature"].DeleteLabelValues(conf.Placement) {
+		t.Errorf("expected no stddev metric without smoothing")
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_rawTemperature(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Placement = "raw-temperature"
+	conf.MeasurementOffsets = map[string]float64{"temperature": -1.5}
+	station, _ := newTestStation(conf)
+
+	station.readAndPublishMeasurement()
+	metric := &dto.Metric{}
+	if err := metricTemperatureRaw.WithLabelValues(conf.Placement).Write(metric); err != nil {
+		t.Fatal(err)
+	}
+	if val := metric.GetGauge().GetValue(); math.Abs(val-MeasureDefaultsTemperature) > 0.001 {
+		t.Errorf("raw temperature gauge = %f, want %f", val, MeasureDefaultsTemperature)
+	}
+
+	conf.Placement = "no-raw-temperature"
+	conf.MeasurementOffsets = nil
+	station, _ = newTestStation(conf)
+	station.readAndPublishMeasurement()
+	if metricTemperatureRaw.DeleteLabelValues(conf.Placement) {
+		t.Errorf("expected no raw temperature metric without corrections")
+	}
+}
+
+func TestWeatherBotAdaptors_readAndPublishMeasurement_stuck(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Placement = "stuck"
+	conf.StuckReadsThreshold = 2
+	station, _ := newTestStation(conf)
+
+	for i, want := range []float64{0, 0, 1} {
+		station.readAndPublishMeasurement()
+		metric := &dto.Metric{}
+		if err := metricStuck.WithLabelValues(conf.Placement, "temperature").Write(metric); err != nil {
+			t.Fatal(err)
+		}
+		if val := metric.GetGauge().GetValue(); val != want {
+			t.Errorf("read %d: stuck gauge = %f, want %f", i, val, want)
+		}
+	}
+}
+
+func Test_sensorMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		ctrlMeas int
+		want     int
+	}{
+		{
+			name:     "sleep",
+			ctrlMeas: 0x24,
+			want:     0,
+		},
+		{
+			name:     "forced",
+			ctrlMeas: 0x25,
+			want:     1,
+		},
+		{
+			name:     "forced alternative",
+			ctrlMeas: 0x26,
+			want:     1,
+		},
+		{
+			name:     "normal",
+			ctrlMeas: 0x27,
+			want:     3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sensorMode(tt.ctrlMeas); got != tt.want {
+				t.Errorf("sensorMode() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readAveraged(t *testing.T) {
+	tests := []struct {
+		name    string
+		values  []float32
+		errs    []error
+		want    float32
+		wantErr bool
+	}{
+		{
+			name:   "single sample",
+			values: []float32{21},
+			errs:   []error{nil},
+			want:   21,
+		},
+		{
+			name:   "mean",
+			values: []float32{20, 21, 22, 23},
+			errs:   []error{nil, nil, nil, nil},
+			want:   21.5,
+		},
+		{
+			name:   "failed samples are skipped",
+			values: []float32{20, 0, 22},
+			errs:   []error{nil, errors.New("error"), nil},
+			want:   21,
+		},
+		{
+			name:    "all samples failed",
+			values:  []float32{0, 0},
+			errs:    []error{errors.New("error"), errors.New("error")},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			i := 0
+			read := func() (float32, error) {
+				defer func() { i++ }()
+				return tt.values[i], tt.errs[i]
+			}
+			got, err := readAveraged(len(tt.values), nil, read)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("readAveraged() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("readAveraged() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_readAveraged_notRetryable(t *testing.T) {
+	reads := 0
+	read := func() (float32, error) {
+		reads++
+		return 0, errors.New("sensor gone")
+	}
+
+	_, err := readAveraged(4, func(error) bool { return false }, read)
+	if err == nil || reads != 1 {
+		t.Errorf("expected to stop after the first error, got %d reads, error %v", reads, err)
+	}
+}
+
+func TestWeatherBotAdaptors_retrying(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.ReadRetryDelayMs = 1
+	station, _ := newTestStation(conf)
+
+	reads := 0
+	read := station.retrying(func() (float32, error) {
+		reads++
+		if reads < 3 {
+			return 0, errors.New("bus contention")
+		}
+		return 21, nil
+	})
+	if val, err := read(); err != nil || val != 21 || reads != 3 {
+		t.Errorf("retrying() = %f, %v after %d reads, want 21 after 3 reads", val, err, reads)
+	}
+
+	reads = 0
+	read = station.retrying(func() (float32, error) {
+		reads++
+		return 0, errors.New("bus contention")
+	})
+	if _, err := read(); err == nil || reads != conf.ReadMaxRetries+1 {
+		t.Errorf("expected an error after %d reads, got %d reads, error %v", conf.ReadMaxRetries+1, reads, err)
+	}
+}
+
+func TestWeatherBotAdaptors_retryingShutdown(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.ReadRetryDelayMs = 60000
+	station, _ := newTestStation(conf)
+	ctx, cancel := context.WithCancel(context.Background())
+	station.done = ctx.Done()
+	cancel()
+
+	reads := 0
+	read := station.retrying(func() (float32, error) {
+		reads++
+		return 0, errors.New("bus contention")
+	})
+	if _, err := read(); err == nil || reads != 1 {
+		t.Errorf("expected to give up retrying on shutdown, got %d reads, error %v", reads, err)
+	}
+}
+
+func TestBenchmark(t *testing.T) {
+	result := Benchmark(&FakeBme280{}, "loc", 50*time.Millisecond)
+	if result.Reads == 0 {
+		t.Fatal("expected reads")
+	}
+	if result.Errors != 0 {
+		t.Errorf("expected no errors, got %d", result.Errors)
+	}
+	if result.MinLatency > result.AvgLatency || result.AvgLatency > result.MaxLatency {
+		t.Errorf("inconsistent latencies: %v", result)
+	}
+}
+
 type FakeMqttAdapter struct {
 	Msg   []byte
 	Topic string
+
+	// Published contains the messages of all successful publishes in order
+	Published []FakeMessage
+	// Unavailable simulates an unreachable broker
+	Unavailable bool
+	// FailPublishes is the amount of publishes that fail before the broker becomes available
+	FailPublishes int
+}
+
+type FakeMessage struct {
+	Topic string
+	Msg   []byte
 }
 
 func (m *FakeMqttAdapter) Name() string {
@@ -87,6 +1099,14 @@ func (m *FakeMqttAdapter) Finalize() error {
 }
 
 func (m *FakeMqttAdapter) Publish(topic string, msg []byte) bool {
+	if m.Unavailable {
+		return false
+	}
+	if m.FailPublishes > 0 {
+		m.FailPublishes--
+		return false
+	}
+	m.Published = append(m.Published, FakeMessage{Topic: topic, Msg: msg})
 	m.Topic = topic
 	m.Msg = msg
 	log.Printf("%s -> %v", topic, string(msg))
@@ -97,6 +1117,11 @@ func (m *FakeMqttAdapter) Publish(topic string, msg []byte) bool {
 
 type FakeBme280 struct {
 	Conn gobot.Connection
+
+	// Writes contains the values written to the registers in order
+	Writes []int
+	// ChipId is returned when reading the chip id register
+	ChipId int
 }
 
 func (driver *FakeBme280) Name() string {
@@ -129,3 +1154,133 @@ func (driver *FakeBme280) Temperature() (temp float32, err error) {
 func (driver *FakeBme280) Humidity() (humidity float32, err error) {
 	return MeasureDefaultsHumidity, nil
 }
+
+func (driver *FakeBme280) Read(register string) (val int, err error) {
+	if register == strconv.Itoa(regChipId) {
+		return driver.ChipId, nil
+	}
+	if len(driver.Writes) > 0 {
+		return driver.Writes[len(driver.Writes)-1], nil
+	}
+	return 0x27, nil
+}
+
+func (driver *FakeBme280) Write(register string, val int) error {
+	driver.Writes = append(driver.Writes, val)
+	return nil
+}
+
+func Test_untilAligned(t *testing.T) {
+	tests := []struct {
+		name     string
+		now      time.Time
+		interval time.Duration
+		want     time.Duration
+	}{
+		{
+			name:     "mid interval",
+			now:      time.Date(2023, 10, 1, 12, 0, 20, 0, time.UTC),
+			interval: time.Minute,
+			want:     40 * time.Second,
+		},
+		{
+			name:     "on boundary",
+			now:      time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC),
+			interval: 30 * time.Second,
+			want:     30 * time.Second,
+		},
+		{
+			name:     "no interval",
+			now:      time.Date(2023, 10, 1, 12, 0, 20, 0, time.UTC),
+			interval: 0,
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := untilAligned(tt.now, tt.interval); got != tt.want {
+				t.Errorf("untilAligned() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_longestGap(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+		want     time.Duration
+	}{
+		{
+			name:     "regular",
+			schedule: "*/5 * * * *",
+			want:     5 * time.Minute,
+		},
+		{
+			name:     "twice a day",
+			schedule: "0 8,20 * * *",
+			want:     12 * time.Hour,
+		},
+		{
+			name:     "weekdays",
+			schedule: "0 8 * * 1-5",
+			want:     72 * time.Hour,
+		},
+		{
+			name:     "monthly",
+			schedule: "0 0 1 * *",
+			want:     30 * 24 * time.Hour,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := cron.ParseStandard(tt.schedule)
+			if err != nil {
+				t.Fatal(err)
+			}
+			from := time.Date(2023, 10, 1, 12, 1, 0, 0, time.UTC)
+			if got := longestGap(schedule, from); got != tt.want {
+				t.Errorf("longestGap() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_nextActivation(t *testing.T) {
+	schedule, err := cron.ParseStandard("*/5 * * * *")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		now  time.Time
+		last time.Time
+		want time.Time
+	}{
+		{
+			name: "first activation",
+			now:  time.Date(2023, 10, 1, 12, 1, 0, 0, time.UTC),
+			want: time.Date(2023, 10, 1, 12, 5, 0, 0, time.UTC),
+		},
+		{
+			name: "regular activation",
+			now:  time.Date(2023, 10, 1, 12, 5, 0, 0, time.UTC),
+			last: time.Date(2023, 10, 1, 12, 5, 0, 0, time.UTC),
+			want: time.Date(2023, 10, 1, 12, 10, 0, 0, time.UTC),
+		},
+		{
+			name: "clock set back",
+			now:  time.Date(2023, 10, 1, 12, 3, 0, 0, time.UTC),
+			last: time.Date(2023, 10, 1, 12, 5, 0, 0, time.UTC),
+			want: time.Date(2023, 10, 1, 12, 10, 0, 0, time.UTC),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextActivation(schedule, tt.now, tt.last); !got.Equal(tt.want) {
+				t.Errorf("nextActivation() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,45 @@
+package internal
+
+// offlineBuffer is a bounded FIFO of messages that could not be published. Once full, the oldest messages are
+// dropped in favor of newer ones.
+type offlineBuffer struct {
+	size     int
+	messages [][]byte
+}
+
+func newOfflineBuffer(size int) *offlineBuffer {
+	return &offlineBuffer{size: size}
+}
+
+// push adds a message to the buffer and returns false if an older message had to be dropped to make room.
+func (b *offlineBuffer) push(msg []byte) bool {
+	if b.size <= 0 {
+		return false
+	}
+
+	dropped := false
+	if len(b.messages) >= b.size {
+		b.messages = b.messages[1:]
+		dropped = true
+	}
+	b.messages = append(b.messages, msg)
+	return !dropped
+}
+
+// replay hands the buffered messages to publish in the order they were added, stopping at the first message
+// that fails to publish. Messages that were published successfully are removed from the buffer.
+func (b *offlineBuffer) replay(publish func(msg []byte) bool) int {
+	replayed := 0
+	for len(b.messages) > 0 {
+		if !publish(b.messages[0]) {
+			break
+		}
+		b.messages = b.messages[1:]
+		replayed++
+	}
+	return replayed
+}
+
+func (b *offlineBuffer) len() int {
+	return len(b.messages)
+}
@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOfflineBuffer(t *testing.T) {
+	buffer := newOfflineBuffer(2)
+	buffer.push([]byte("a"))
+	buffer.push([]byte("b"))
+	if buffer.push([]byte("c")) {
+		t.Errorf("push() = true, expected oldest message to be dropped")
+	}
+
+	var published []string
+	failAfter := 1
+	replayed := buffer.replay(func(msg []byte) bool {
+		if len(published) == failAfter {
+			return false
+		}
+		published = append(published, string(msg))
+		return true
+	})
+	if replayed != 1 || buffer.len() != 1 {
+		t.Errorf("replay() = %d, len() = %d, want 1, 1", replayed, buffer.len())
+	}
+
+	failAfter = 10
+	buffer.replay(func(msg []byte) bool {
+		published = append(published, string(msg))
+		return true
+	})
+	if want := []string{"b", "c"}; !reflect.DeepEqual(published, want) {
+		t.Errorf("replayed %v, want %v", published, want)
+	}
+	if buffer.len() != 0 {
+		t.Errorf("len() = %d, want 0", buffer.len())
+	}
+}
+
+func TestOfflineBuffer_Disabled(t *testing.T) {
+	buffer := newOfflineBuffer(0)
+	buffer.push([]byte("a"))
+	if buffer.len() != 0 {
+		t.Errorf("len() = %d, want 0", buffer.len())
+	}
+}
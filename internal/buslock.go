@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"time"
+)
+
+const (
+	busLockTimeout      = 2 * time.Second
+	busLockPollInterval = 10 * time.Millisecond
+)
+
+// BusLock serializes the access to the i2c bus with cooperating processes using an advisory lock on a file.
+type BusLock struct {
+	path string
+	file *os.File
+}
+
+func NewBusLock(path string) *BusLock {
+	return &BusLock{path: path}
+}
+
+// Lock acquires the lock, giving up after a timeout so a process holding the lock forever does not stall readings.
+func (l *BusLock) Lock() error {
+	if l.file == nil {
+		file, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return fmt.Errorf("could not open bus lock file: %w", err)
+		}
+		l.file = file
+	}
+
+	deadline := time.Now().Add(busLockTimeout)
+	for {
+		err := syscall.Flock(int(l.file.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, syscall.EWOULDBLOCK) {
+			return fmt.Errorf("could not acquire bus lock: %w", err)
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("could not acquire bus lock within %v", busLockTimeout)
+		}
+		time.Sleep(busLockPollInterval)
+	}
+}
+
+func (l *BusLock) Unlock() {
+	if l.file != nil {
+		_ = syscall.Flock(int(l.file.Fd()), syscall.LOCK_UN)
+	}
+}
+
+// lock acquires the lock if a lock is configured.
+func (l *BusLock) lock() error {
+	if l == nil {
+		return nil
+	}
+	return l.Lock()
+}
+
+// unlock releases the lock if a lock is configured.
+func (l *BusLock) unlock() {
+	if l != nil {
+		l.Unlock()
+	}
+}
+
+// locked wraps the read so it's performed while holding the lock, if a lock is configured.
+func (l *BusLock) locked(read func() (float32, error)) func() (float32, error) {
+	if l == nil {
+		return read
+	}
+	return func() (float32, error) {
+		if err := l.Lock(); err != nil {
+			return 0, err
+		}
+		defer l.Unlock()
+		return read()
+	}
+}
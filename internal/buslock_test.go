@@ -0,0 +1,54 @@
+package internal
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBusLock(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "i2c.lock")
+	first, second := NewBusLock(path), NewBusLock(path)
+
+	if err := first.Lock(); err != nil {
+		t.Fatal(err)
+	}
+
+	acquired := make(chan error, 1)
+	go func() {
+		acquired <- second.Lock()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("expected the lock to be held by the first process")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	first.Unlock()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the lock to be acquired after it was released")
+	}
+	second.Unlock()
+}
+
+func TestBusLock_locked(t *testing.T) {
+	read := func() (float32, error) {
+		return 21, nil
+	}
+
+	var disabled *BusLock
+	if val, err := disabled.locked(read)(); err != nil || val != 21 {
+		t.Errorf("expected the read to be passed through without a lock, got %f, %v", val, err)
+	}
+
+	lock := NewBusLock(filepath.Join(t.TempDir(), "i2c.lock"))
+	if val, err := lock.locked(read)(); err != nil || val != 21 {
+		t.Errorf("expected the read to be performed while holding the lock, got %f, %v", val, err)
+	}
+}
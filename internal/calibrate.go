@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+// calibrationReads is the amount of reads that are averaged to calibrate a measured value, so the noise of a single
+// read does not end up in the offset.
+const calibrationReads = 5
+
+type CalibrationResult struct {
+	Measurement string
+	Read        float64
+	Reference   float64
+}
+
+// Offset returns the offset to configure in MeasurementOffsets, so the published value matches the reference.
+func (r CalibrationResult) Offset() float64 {
+	return r.Reference - r.Read
+}
+
+func (r CalibrationResult) String() string {
+	return fmt.Sprintf("%s read=%.2f reference=%.2f offset=%s:%s", r.Measurement, r.Read, r.Reference, r.Measurement,
+		strconv.FormatFloat(r.Offset(), 'f', 2, 64))
+}
+
+// Calibrate reads the measured value from the sensor and compares it to the reference, which is given in the unit of
+// the published value, i.e. after converting the temperature to the configured unit and applying the configured scale.
+// Configured offsets are ignored, so the offset of the result replaces them.
+func Calibrate(sensor WeatherBotSensor, measurement string, reference float64, conf config.SensorConfig, lock *BusLock) (CalibrationResult, error) {
+	read := map[string]func() (float32, error){
+		"temperature": sensor.Temperature,
+		"humidity":    sensor.Humidity,
+		"pressure":    sensor.Pressure,
+	}[measurement]
+	if read == nil {
+		return CalibrationResult{}, fmt.Errorf("can not calibrate unknown measurement %q", measurement)
+	}
+
+	if err := lock.lock(); err != nil {
+		return CalibrationResult{}, err
+	}
+	defer lock.unlock()
+
+	var sum float64
+	for i := 0; i < calibrationReads; i++ {
+		value, err := read()
+		if err != nil {
+			return CalibrationResult{}, fmt.Errorf("could not read %s: %w", measurement, err)
+		}
+		sum += float64(value)
+	}
+
+	value := sum / calibrationReads
+	if measurement == "temperature" {
+		value = toTemperatureUnit(value, conf.TemperatureUnit)
+	}
+	if scale, ok := conf.MeasurementScales[measurement]; ok {
+		value *= scale
+	}
+	return CalibrationResult{Measurement: measurement, Read: value, Reference: reference}, nil
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func TestCalibrate(t *testing.T) {
+	sensor := &selfTestSensor{temperature: 22.5, humidity: 40, pressure: 101325}
+	tests := []struct {
+		name        string
+		measurement string
+		reference   float64
+		conf        config.SensorConfig
+		wantOffset  float64
+		wantErr     bool
+	}{
+		{
+			name:        "temperature",
+			measurement: "temperature",
+			reference:   21.75,
+			wantOffset:  -0.75,
+		},
+		{
+			name:        "temperature in fahrenheit",
+			measurement: "temperature",
+			reference:   73,
+			conf:        config.SensorConfig{TemperatureUnit: config.TemperatureUnitFahrenheit},
+			wantOffset:  0.5,
+		},
+		{
+			name:        "scaled pressure",
+			measurement: "pressure",
+			reference:   1015,
+			conf:        config.SensorConfig{MeasurementScales: map[string]float64{"pressure": 0.01}},
+			wantOffset:  1.75,
+		},
+		{
+			name:        "configured offset is replaced",
+			measurement: "humidity",
+			reference:   42,
+			conf:        config.SensorConfig{MeasurementOffsets: map[string]float64{"humidity": 5}},
+			wantOffset:  2,
+		},
+		{
+			name:        "unknown measurement",
+			measurement: "altitude",
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Calibrate(sensor, tt.measurement, tt.reference, tt.conf, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Calibrate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && math.Abs(got.Offset()-tt.wantOffset) > 0.001 {
+				t.Errorf("Offset() = %f, want %f", got.Offset(), tt.wantOffset)
+			}
+		})
+	}
+}
+
+func TestCalibrate_readError(t *testing.T) {
+	sensor := &selfTestSensor{err: errors.New("bus error")}
+	if _, err := Calibrate(sensor, "temperature", 20, config.SensorConfig{}, nil); err == nil {
+		t.Errorf("Calibrate() expected error if the sensor can not be read")
+	}
+}
@@ -0,0 +1,21 @@
+package internal
+
+import (
+	"os"
+	"time"
+)
+
+const timesyncSynchronizedFile = "/run/systemd/timesync/synchronized"
+
+// minPlausibleTime is the earliest time considered a valid system clock, clocks before it are assumed to not have
+// been synced yet.
+var minPlausibleTime = time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// clockPlausible returns whether the system clock looks synced, either because systemd-timesyncd flagged it as
+// synchronized or because it is past minPlausibleTime.
+func clockPlausible(now time.Time, syncFlagFile string) bool {
+	if _, err := os.Stat(syncFlagFile); err == nil {
+		return true
+	}
+	return !now.Before(minPlausibleTime)
+}
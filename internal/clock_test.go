@@ -0,0 +1,27 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_clockPlausible(t *testing.T) {
+	syncFlag := filepath.Join(t.TempDir(), "synchronized")
+	bootTime := time.Date(1970, 1, 1, 0, 3, 0, 0, time.UTC)
+
+	if clockPlausible(bootTime, syncFlag) {
+		t.Errorf("clockPlausible() = true for an unsynced clock")
+	}
+	if !clockPlausible(time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC), syncFlag) {
+		t.Errorf("clockPlausible() = false for a plausible clock")
+	}
+
+	if err := os.WriteFile(syncFlag, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if !clockPlausible(bootTime, syncFlag) {
+		t.Errorf("clockPlausible() = false despite sync flag")
+	}
+}
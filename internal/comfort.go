@@ -0,0 +1,47 @@
+package internal
+
+import "math"
+
+const (
+	comfortTemperatureCold = "cold"
+	comfortTemperatureOk   = "comfortable"
+	comfortTemperatureHot  = "hot"
+
+	comfortHumidityDry   = "dry"
+	comfortHumidityOk    = "ok"
+	comfortHumidityHumid = "humid"
+
+	// comfortIndexTemperatureWeight is the amount of index points lost per degree celsius of deviation
+	comfortIndexTemperatureWeight = 10
+	// comfortIndexHumidityWeight is the amount of index points lost per percent of relative humidity deviation
+	comfortIndexHumidityWeight = 1
+)
+
+func classifyTemperature(temp, min, max float64) string {
+	switch {
+	case temp < min:
+		return comfortTemperatureCold
+	case temp > max:
+		return comfortTemperatureHot
+	default:
+		return comfortTemperatureOk
+	}
+}
+
+func classifyHumidity(humidity, min, max float64) string {
+	switch {
+	case humidity < min:
+		return comfortHumidityDry
+	case humidity > max:
+		return comfortHumidityHumid
+	default:
+		return comfortHumidityOk
+	}
+}
+
+// comfortIndex scores the comfort in [0, 100] by the deviation from the ideal temperature and humidity:
+// 100 - 10 * |T - T_ideal| - 1 * |H - H_ideal|, clamped to 0.
+func comfortIndex(temp, humidity, idealTemp, idealHumidity float64) float64 {
+	index := 100 - comfortIndexTemperatureWeight*math.Abs(temp-idealTemp) - comfortIndexHumidityWeight*math.Abs(humidity-idealHumidity)
+	return math.Max(0, index)
+}
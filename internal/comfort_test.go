@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_comfortIndex(t *testing.T) {
+	tests := []struct {
+		name     string
+		temp     float64
+		humidity float64
+		want     float64
+	}{
+		{
+			name:     "ideal",
+			temp:     22,
+			humidity: 50,
+			want:     100,
+		},
+		{
+			name:     "deviation",
+			temp:     20.5,
+			humidity: 60,
+			want:     75,
+		},
+		{
+			name:     "clamped",
+			temp:     5,
+			humidity: 90,
+			want:     0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := comfortIndex(tt.temp, tt.humidity, 22, 50); math.Abs(got-tt.want) > 0.001 {
+				t.Errorf("comfortIndex() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+const (
+	CommandRead     = "read"
+	CommandInterval = "interval"
+	commandResponse = "response"
+
+	responseAck  = "ack"
+	responseNack = "nack"
+)
+
+// HandleReadCommand performs an out-of-cycle reading upon receiving a command.
+func (station *WeatherBotAdaptors) HandleReadCommand(_ []byte) {
+	slog.Info("Received read command", "placement", station.Config.Placement)
+	station.readAndPublishRecovering()
+}
+
+// HandleIntervalCommand changes the interval of the readings to the amount of seconds in the payload and publishes
+// whether the change has been applied to the response topic.
+func (station *WeatherBotAdaptors) HandleIntervalCommand(payload []byte) {
+	response := responseAck
+	if err := station.setInterval(strings.TrimSpace(string(payload))); err != nil {
+		slog.Warn("Rejected interval command", "err", err)
+		response = fmt.Sprintf("%s: %v", responseNack, err)
+	}
+	station.publish(station.CommandTopic+"/"+commandResponse, []byte(response))
+}
+
+func (station *WeatherBotAdaptors) setInterval(value string) error {
+	secs, err := strconv.Atoi(value)
+	if err != nil {
+		return fmt.Errorf("invalid interval %q", value)
+	}
+	if len(station.Config.Schedule) > 0 {
+		return errors.New("readings follow a schedule")
+	}
+
+	station.mu.Lock()
+	defer station.mu.Unlock()
+
+	conf := station.Config
+	conf.IntervalSecs = secs
+	if err := config.Validate(&conf); err != nil {
+		return fmt.Errorf("interval of %ds is not valid", secs)
+	}
+
+	slog.Info("Changing interval", "placement", station.Config.Placement, "from_s", station.Config.IntervalSecs, "to_s", secs)
+	station.Config.IntervalSecs = secs
+	interval := time.Duration(secs) * time.Second
+	station.health.setInterval(interval)
+	station.watchdog.setMaxAge(2 * interval)
+	// only the latest change is of interest if the previous one has not been applied yet
+	select {
+	case <-station.intervalChanges:
+	default:
+	}
+	station.intervalChanges <- interval
+	return nil
+}
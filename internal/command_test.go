@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func TestWeatherBotAdaptors_HandleReadCommand(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	station, mqttAdaptor := newTestStation(conf)
+
+	station.HandleReadCommand([]byte("now"))
+	if len(mqttAdaptor.Published) != 1 || mqttAdaptor.Published[0].Topic != "sensors/test" {
+		t.Errorf("expected a measurement to be published, got %v", mqttAdaptor.Published)
+	}
+}
+
+func TestWeatherBotAdaptors_HandleIntervalCommand(t *testing.T) {
+	tests := []struct {
+		name         string
+		payload      string
+		wantInterval int
+		wantResponse string
+	}{
+		{
+			name:         "valid interval",
+			payload:      "60\n",
+			wantInterval: 60,
+			wantResponse: "ack",
+		},
+		{
+			name:         "out of range",
+			payload:      "1",
+			wantInterval: 30,
+			wantResponse: "nack: interval of 1s is not valid",
+		},
+		{
+			name:         "not a number",
+			payload:      "fast",
+			wantInterval: 30,
+			wantResponse: `nack: invalid interval "fast"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := config.DefaultConfig()
+			conf.Placement = "test"
+			conf.Host = "tcp://localhost:1883"
+			conf.Topic = "sensors/test"
+			conf.TopicPrefix = "home"
+			conf.CommandTopic = "sensors/cmd"
+			station, mqttAdaptor := newTestStation(conf)
+			station.CommandTopic = "sensors/cmd/test"
+
+			station.HandleIntervalCommand([]byte(tt.payload))
+			if station.Config.IntervalSecs != tt.wantInterval {
+				t.Errorf("interval = %d, want %d", station.Config.IntervalSecs, tt.wantInterval)
+			}
+			if len(mqttAdaptor.Published) != 1 {
+				t.Fatalf("expected a single response, got %v", mqttAdaptor.Published)
+			}
+			got := mqttAdaptor.Published[0]
+			if got.Topic != "home/sensors/cmd/test/response" || string(got.Msg) != tt.wantResponse {
+				t.Errorf("response = %s %q, want %q", got.Topic, got.Msg, tt.wantResponse)
+			}
+		})
+	}
+}
+
+func TestWeatherBotAdaptors_setInterval(t *testing.T) {
+	t.Setenv("NOTIFY_SOCKET", filepath.Join(t.TempDir(), "notify"))
+	t.Setenv("WATCHDOG_USEC", "10000000")
+	conf := config.DefaultConfig()
+	conf.Placement = "test"
+	conf.Host = "tcp://localhost:1883"
+	conf.Topic = "sensors/test"
+	station, _ := newTestStation(conf)
+
+	if err := station.setInterval("60"); err != nil {
+		t.Fatal(err)
+	}
+	if station.health.interval != time.Minute {
+		t.Errorf("health interval = %v, want %v", station.health.interval, time.Minute)
+	}
+	if got := time.Duration(station.watchdog.maxAge); got != 2*time.Minute {
+		t.Errorf("watchdog max age = %v, want %v", got, 2*time.Minute)
+	}
+}
+
+func Test_everyInterval(t *testing.T) {
+	changes := make(chan time.Duration, 1)
+	calls := make(chan struct{}, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	everyInterval(ctx, time.Hour, changes, func() {
+		calls <- struct{}{}
+	})
+
+	changes <- 10 * time.Millisecond
+	select {
+	case <-calls:
+	case <-time.After(time.Second):
+		t.Fatal("expected the changed interval to be applied")
+	}
+}
+
+func Test_everyIntervalCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	calls := make(chan struct{}, 10)
+	everyInterval(ctx, 10*time.Millisecond, nil, func() {
+		calls <- struct{}{}
+	})
+	<-calls
+	cancel()
+
+	// allow a tick that raced with the cancellation
+	time.Sleep(30 * time.Millisecond)
+	for len(calls) > 0 {
+		<-calls
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(calls) > 0 {
+		t.Fatal("expected no calls after the context is done")
+	}
+}
@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"sort"
+	"strconv"
+)
+
+// condensationTopic is the subtopic of the configured topic the condensation alarms of the surfaces are published to.
+const condensationTopic = "alarm/condensation"
+
+// condensationRisk considers condensation likely on a surface that is colder than the air by the given margin in °C
+// if the surface temperature is at or below the dew point.
+func condensationRisk(tempCelsius, dewPointCelsius, margin float64) bool {
+	return tempCelsius-margin <= dewPointCelsius
+}
+
+// publishCondensation publishes the condensation alarm of each configured surface in alphabetical order.
+func (station *WeatherBotAdaptors) publishCondensation(m Measurement) {
+	surfaces := make([]string, 0, len(station.Config.CondensationSurfaces))
+	for surface := range station.Config.CondensationSurfaces {
+		surfaces = append(surfaces, surface)
+	}
+	sort.Strings(surfaces)
+
+	temperature := float64(m.Temperature)
+	dew := dewPoint(temperature, float64(m.Humidity))
+	for _, surface := range surfaces {
+		alarm := condensationRisk(temperature, dew, station.Config.CondensationSurfaces[surface])
+		station.publish(station.Config.MqttConfig.FieldTopic(condensationTopic+"/"+surface), []byte(strconv.FormatBool(alarm)))
+	}
+}
@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func Test_condensationRisk(t *testing.T) {
+	if !condensationRisk(20, 12, 10) {
+		t.Errorf("expected condensation on a surface 10 °C colder than the air")
+	}
+	if condensationRisk(20, 12, 3) {
+		t.Errorf("expected no condensation on a surface 3 °C colder than the air")
+	}
+}
+
+func TestWeatherBotAdaptors_publishCondensation(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	conf.CondensationSurfaces = map[string]float64{"window": 15, "wall": 3}
+	station, mqttAdaptor := newTestStation(conf)
+
+	m := NewMeasurement()
+	m.Temperature = 20
+	m.Humidity = 50
+	station.publishCondensation(m)
+
+	want := []FakeMessage{
+		{Topic: "sensors/test/alarm/condensation/wall", Msg: []byte("false")},
+		{Topic: "sensors/test/alarm/condensation/window", Msg: []byte("true")},
+	}
+	if !reflect.DeepEqual(mqttAdaptor.Published, want) {
+		t.Errorf("published %v, want %v", mqttAdaptor.Published, want)
+	}
+}
@@ -1,61 +1,181 @@
 package config
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"log"
 	"os"
 	"reflect"
+	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/caarlos0/env/v9"
 	"github.com/go-playground/validator/v10"
+	"github.com/robfig/cron/v3"
+	"gopkg.in/yaml.v3"
 )
 
 const (
 	BotName                = "gobot_bme280"
 	defaultLogSensor       = false
 	defaultIntervalSeconds = 30
+
+	defaultMinIntervalSeconds = 30
+	defaultMaxIntervalSeconds = 300
+	// absolute bounds for the interval that can not be overridden, except for allowing fast intervals
+	absoluteMinIntervalSeconds = 5
+	absoluteMaxIntervalSeconds = 86400
+	// lower bound for the interval if fast intervals are explicitly allowed
+	fastMinIntervalSeconds = 1
 	defaultMetricConfig    = "0.0.0.0:9192"
+	// amount of failed reads in a row after which the bot is reported as not ready
+	defaultReadinessMaxFailedReads = 3
+
+	MetricsBindFallbackFail     = "fail"
+	MetricsBindFallbackNextPort = "next-port"
+	MetricsBindFallbackDisable  = "disable"
+
+	PlatformRaspi       = "raspi"
+	PlatformTinkerboard = "tinkerboard"
+	PlatformJetson      = "jetson"
+	defaultPlatform     = PlatformRaspi
+
+	LogLevelDebug   = "debug"
+	LogLevelInfo    = "info"
+	LogLevelWarn    = "warn"
+	LogLevelError   = "error"
+	defaultLogLevel = LogLevelInfo
+
+	LogFormatText    = "text"
+	LogFormatJson    = "json"
+	defaultLogFormat = LogFormatText
+
+	defaultLogFileMaxSizeMb  = 10
+	defaultLogFileMaxBackups = 3
+	defaultMinFreeDiskMb     = 100
+
+	TimestampPrecisionSecond      = "second"
+	TimestampPrecisionMillisecond = "millisecond"
+	TimestampPrecisionInterval    = "interval"
+	defaultTimestampPrecision     = TimestampPrecisionSecond
 )
 
 var (
+	defaultMetricQuantiles = []float64{0.5, 0.95}
+
 	once     sync.Once
 	validate *validator.Validate
 )
 
 type Config struct {
-	Placement     string `json:"placement,omitempty" env:"PLACEMENT" validate:"required"`
-	MetricConfig  string `json:"metrics_addr,omitempty" env:"METRICS_LISTEN_ADDR" validate:"omitempty,tcp_addr"`
-	IntervalSecs  int    `json:"interval_s,omitempty" env:"INTERVAL_S" validate:"min=30,max=300"`
-	StatIntervals []int  `json:"stat_intervals,omitempty" env:"STAT_INTERVALS" validate:"dive,min=10,max=3600"`
-	LogSensor     bool   `json:"log_sensor,omitempty" env:"LOG_SENSOR_READINGS"`
+	Placement           string    `json:"placement,omitempty" env:"PLACEMENT" validate:"required"`
+	PlacementFromHost   bool      `json:"placement_from_hostname,omitempty" env:"PLACEMENT_FROM_HOSTNAME"`
+	Platform            string    `json:"platform,omitempty" env:"PLATFORM" validate:"omitempty,oneof=raspi tinkerboard jetson"`
+	MetricConfig        string    `json:"metrics_addr,omitempty" env:"METRICS_LISTEN_ADDR" validate:"omitempty,tcp_addr"`
+	MetricsBindFallback string    `json:"metrics_bind_fallback,omitempty" env:"METRICS_BIND_FALLBACK" validate:"omitempty,oneof=fail next-port disable"`
+	IntervalSecs        int       `json:"interval_s,omitempty" env:"INTERVAL_S"`
+	AllowFastInterval   bool      `json:"allow_fast_interval,omitempty" env:"ALLOW_FAST_INTERVAL"`
+	AlignToClock        bool      `json:"align_to_clock,omitempty" env:"ALIGN_TO_CLOCK"`
+	PhaseOffsetMs       int       `json:"phase_offset_ms,omitempty" env:"PHASE_OFFSET_MS" validate:"gte=0"`
+	Schedule            []string  `json:"schedule,omitempty" env:"SCHEDULE" envSeparator:";" validate:"dive,cron_schedule"`
+	MinIntervalSecs     int       `json:"min_interval_s,omitempty" env:"MIN_INTERVAL_S" validate:"omitempty,min=5,max=86400"`
+	MaxIntervalSecs     int       `json:"max_interval_s,omitempty" env:"MAX_INTERVAL_S" validate:"omitempty,min=5,max=86400"`
+	MetricsIntervalSecs int       `json:"metrics_interval_s,omitempty" env:"METRICS_INTERVAL_S" validate:"gte=0"`
+	StatIntervals       []int     `json:"stat_intervals,omitempty" env:"STAT_INTERVALS" validate:"dive,min=10,max=3600"`
+	MetricMeasurements  []string  `json:"metric_measurements,omitempty" env:"METRIC_MEASUREMENTS" validate:"dive,oneof=temperature humidity pressure altitude"`
+	MetricSummaries     []string  `json:"metric_summaries,omitempty" env:"METRIC_SUMMARIES" validate:"dive,oneof=temperature humidity pressure altitude"`
+	MetricQuantiles     []float64 `json:"metric_quantiles,omitempty" env:"METRIC_QUANTILES" validate:"dive,gt=0,lt=1"`
+	MetricPrefix        string    `json:"metric_prefix,omitempty" env:"METRIC_PREFIX" validate:"omitempty,metric_prefix"`
+	LogSensor           bool      `json:"log_sensor,omitempty" env:"LOG_SENSOR_READINGS"`
+	StartupRetryMax     int       `json:"startup_retry_max,omitempty" env:"STARTUP_RETRY_MAX" validate:"min=0,max=100"`
+	StartTimeoutSecs    int       `json:"start_timeout_s,omitempty" env:"START_TIMEOUT_S" validate:"gte=0"`
+	StartupDelaySecs    int       `json:"startup_delay_s,omitempty" env:"STARTUP_DELAY_S" validate:"gte=0"`
+
+	LogLevel          string `json:"log_level,omitempty" env:"LOG_LEVEL" validate:"omitempty,oneof=debug info warn error"`
+	LogFormat         string `json:"log_format,omitempty" env:"LOG_FORMAT" validate:"omitempty,oneof=text json"`
+	LogFile           string `json:"log_file,omitempty" env:"LOG_FILE"`
+	LogFileMaxSizeMb  int    `json:"log_file_max_size_mb,omitempty" env:"LOG_FILE_MAX_SIZE_MB" validate:"gte=0"`
+	LogFileMaxBackups int    `json:"log_file_max_backups,omitempty" env:"LOG_FILE_MAX_BACKUPS" validate:"gte=0"`
+	CsvFile           string `json:"csv_file,omitempty" env:"CSV_FILE"`
+	CsvFileMaxSizeMb  int    `json:"csv_file_max_size_mb,omitempty" env:"CSV_FILE_MAX_SIZE_MB" validate:"gte=0"`
+	FlushEveryN       int    `json:"flush_every_n,omitempty" env:"FLUSH_EVERY_N" validate:"gte=0,lte=1000"`
+	MinFreeDiskMb     int    `json:"min_free_disk_mb,omitempty" env:"MIN_FREE_DISK_MB" validate:"gte=0"`
+	Syslog            bool   `json:"syslog,omitempty" env:"SYSLOG"`
+	SyslogNetwork     string `json:"syslog_network,omitempty" env:"SYSLOG_NETWORK" validate:"omitempty,oneof=udp tcp unix unixgram"`
+	SyslogAddress     string `json:"syslog_address,omitempty" env:"SYSLOG_ADDRESS" validate:"required_with=SyslogNetwork"`
+	SyslogFacility    string `json:"syslog_facility,omitempty" env:"SYSLOG_FACILITY" validate:"omitempty,oneof=user daemon local0 local1 local2 local3 local4 local5 local6 local7"`
+
+	CsvColumns []string `json:"csv_columns,omitempty" env:"CSV_COLUMNS" envSeparator:";" validate:"dive,csv_column"`
+
+	TimestampPrecision   string `json:"timestamp_precision,omitempty" env:"TIMESTAMP_PRECISION" validate:"omitempty,oneof=second millisecond interval"`
+	ExtremesReset        string `json:"extremes_reset,omitempty" env:"EXTREMES_RESET" validate:"omitempty,datetime=15:04"`
+	RequireSyncedClock   bool   `json:"require_synced_clock,omitempty" env:"REQUIRE_SYNCED_CLOCK"`
+	IncludeCorrelationId bool   `json:"include_correlation_id,omitempty" env:"INCLUDE_CORRELATION_ID"`
+
+	ReadinessMaxFailedReads int `json:"readiness_max_failed_reads,omitempty" env:"READINESS_MAX_FAILED_READS" validate:"gte=0"`
+
+	Sensors []SensorEntry `json:"sensors,omitempty" validate:"dive"`
+
+	ReadingsSocketPath string `json:"readings_socket_path,omitempty" env:"READINGS_SOCKET_PATH"`
 	MqttConfig
 	SensorConfig
+	RemoteWriteConfig
+	KafkaConfig
+	InfluxConfig
+	AlertConfig
 }
 
 func DefaultConfig() Config {
 	return Config{
+		Platform:     defaultPlatform,
 		LogSensor:    defaultLogSensor,
 		IntervalSecs: defaultIntervalSeconds,
 		MetricConfig: defaultMetricConfig,
+
+		MinIntervalSecs: defaultMinIntervalSeconds,
+		MaxIntervalSecs: defaultMaxIntervalSeconds,
+
+		LogLevel:          defaultLogLevel,
+		LogFormat:         defaultLogFormat,
+		LogFileMaxSizeMb:  defaultLogFileMaxSizeMb,
+		LogFileMaxBackups: defaultLogFileMaxBackups,
+		MinFreeDiskMb:     defaultMinFreeDiskMb,
+
+		TimestampPrecision: defaultTimestampPrecision,
+
+		ReadinessMaxFailedReads: defaultReadinessMaxFailedReads,
+
+		MqttConfig:   defaultMqttConfig(),
 		SensorConfig: defaultSensorConfig(),
+		InfluxConfig: defaultInfluxConfig(),
+		AlertConfig:  defaultAlertConfig(),
 	}
 }
 
-func Read(filePath string) (*Config, error) {
+// Read builds the config by applying the given JSON or YAML files in order on top of the default values, later files overriding
+// the values of earlier ones. References to environment variables such as ${VAR} within the files are expanded
+// before parsing. Environment variables that are set are applied last, overriding the files even if set to a zero value.
+func Read(filePaths ...string) (*Config, error) {
 	ret := DefaultConfig()
 
-	if len(filePath) > 0 {
+	for _, filePath := range filePaths {
+		if len(filePath) == 0 {
+			continue
+		}
+
 		fileContent, err := os.ReadFile(filePath)
 		if err != nil {
 			return nil, fmt.Errorf("could not read config from file: %v", err)
 		}
 
-		err = json.Unmarshal(fileContent, &ret)
+		err = unmarshal([]byte(os.ExpandEnv(string(fileContent))), &ret)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("could not parse config file %q: %v", filePath, err)
 		}
 	}
 
@@ -66,15 +186,50 @@ func Read(filePath string) (*Config, error) {
 	return &ret, err
 }
 
+// unmarshal sniffs the format of the content, treating it as JSON if it starts with '{' and as YAML otherwise.
+// YAML is converted to JSON first, so the field names of both formats are identical.
+func unmarshal(content []byte, conf *Config) error {
+	if bytes.HasPrefix(bytes.TrimSpace(content), []byte("{")) {
+		return json.Unmarshal(content, conf)
+	}
+
+	var values map[string]interface{}
+	if err := yaml.Unmarshal(content, &values); err != nil {
+		return fmt.Errorf("content is neither valid JSON nor YAML: %v", err)
+	}
+
+	converted, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("could not convert YAML: %v", err)
+	}
+	return json.Unmarshal(converted, conf)
+}
+
 func Validate(s interface{}) error {
 	once.Do(func() {
 		validate = validator.New()
 		if err := validate.RegisterValidation("mqtt_topic", validateTopic); err != nil {
 			log.Fatal("could not build custom validation 'mqtt_topic'")
 		}
+		if err := validate.RegisterValidation("mqtt_topic_template", validateTopicTemplate); err != nil {
+			log.Fatal("could not build custom validation 'mqtt_topic_template'")
+		}
 		if err := validate.RegisterValidation("mqtt_broker", validateBroker); err != nil {
 			log.Fatal("could not build custom validation 'validateBroker'")
 		}
+		if err := validate.RegisterValidation("cron_schedule", validateSchedule); err != nil {
+			log.Fatal("could not build custom validation 'cron_schedule'")
+		}
+		if err := validate.RegisterValidation("csv_column", validateCsvColumn); err != nil {
+			log.Fatal("could not build custom validation 'csv_column'")
+		}
+		if err := validate.RegisterValidation("influx_tag", validateInfluxTag); err != nil {
+			log.Fatal("could not build custom validation 'influx_tag'")
+		}
+		if err := validate.RegisterValidation("metric_prefix", validateMetricPrefix); err != nil {
+			log.Fatal("could not build custom validation 'metric_prefix'")
+		}
+		validate.RegisterStructValidation(validateConfig, Config{})
 	})
 	return validate.Struct(s)
 }
@@ -94,6 +249,21 @@ func validateTopic(fl validator.FieldLevel) bool {
 	return true
 }
 
+// validateTopicTemplate accepts plain topics and templated topics that only reference the placement and the field.
+func validateTopicTemplate(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() != reflect.String {
+		return false
+	}
+
+	topic := field.String()
+	if !isTopicTemplate(topic) {
+		return matchTopic(topic)
+	}
+	executed, err := executeTopicTemplate(topic, topicTemplateData{Placement: "placement", Field: "temperature"})
+	return err == nil && matchTopic(executed)
+}
+
 func validateBroker(fl validator.FieldLevel) bool {
 	// Get the field value and check if it's a slice
 	field := fl.Field()
@@ -109,3 +279,184 @@ func validateBroker(fl validator.FieldLevel) bool {
 
 	return true
 }
+
+func validateSchedule(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() != reflect.String {
+		return false
+	}
+
+	_, err := cron.ParseStandard(field.String())
+	return err == nil
+}
+
+// CronSchedule returns the schedule built from all configured cron expressions, or nil if no schedule is
+// configured and readings happen at the fixed interval.
+func (conf *Config) CronSchedule() (cron.Schedule, error) {
+	if len(conf.Schedule) == 0 {
+		return nil, nil
+	}
+
+	var schedules multiSchedule
+	for _, expr := range conf.Schedule {
+		schedule, err := cron.ParseStandard(expr)
+		if err != nil {
+			return nil, fmt.Errorf("could not parse schedule %q: %v", expr, err)
+		}
+		schedules = append(schedules, schedule)
+	}
+	return schedules, nil
+}
+
+// multiSchedule activates at the earliest activation time of any of its schedules.
+type multiSchedule []cron.Schedule
+
+func (m multiSchedule) Next(t time.Time) time.Time {
+	var next time.Time
+	for _, schedule := range m {
+		candidate := schedule.Next(t)
+		if next.IsZero() || candidate.Before(next) {
+			next = candidate
+		}
+	}
+	return next
+}
+
+// Hash returns a short hash of the effective config, allowing to detect hosts that share an identical config.
+func (conf *Config) Hash() string {
+	data, err := json.Marshal(conf)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// ResolvePlacement falls back to the hostname of the machine if no placement is set and PlacementFromHost is enabled.
+// An explicitly set placement is never overridden.
+func (conf *Config) ResolvePlacement() error {
+	if len(conf.Placement) > 0 || !conf.PlacementFromHost {
+		return nil
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		return fmt.Errorf("could not determine hostname: %w", err)
+	}
+	conf.Placement = hostname
+	return nil
+}
+
+// IntervalBounds returns the effective bounds for the interval, falling back to the defaults for unset bounds. If fast
+// intervals are allowed, the lower bound is relaxed to a second.
+func (conf *Config) IntervalBounds() (int, int) {
+	min, max := conf.MinIntervalSecs, conf.MaxIntervalSecs
+	if conf.AllowFastInterval {
+		min = fastMinIntervalSeconds
+	} else if min == 0 {
+		min = defaultMinIntervalSeconds
+	}
+	if max == 0 {
+		max = defaultMaxIntervalSeconds
+	}
+	return min, max
+}
+
+// UsesFastInterval returns whether the interval is below the default lower bound, which is only meant for bench tests.
+func (conf *Config) UsesFastInterval() bool {
+	return conf.AllowFastInterval && conf.IntervalSecs < defaultMinIntervalSeconds
+}
+
+// ExposesMetric returns whether the given measured value is exposed as metric, which is the case for all values if
+// MetricMeasurements is not set.
+func (conf *Config) ExposesMetric(name string) bool {
+	if len(conf.MetricMeasurements) == 0 {
+		return true
+	}
+	for _, exposed := range conf.MetricMeasurements {
+		if exposed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// SummaryQuantiles returns the quantiles of the measured values that are exported as summaries, falling back to the
+// median and the 95th percentile if no quantiles are configured.
+func (conf *Config) SummaryQuantiles() []float64 {
+	if len(conf.MetricQuantiles) == 0 {
+		return defaultMetricQuantiles
+	}
+	return conf.MetricQuantiles
+}
+
+// ExtremesResetTime returns the time of day the recorded extremes are reset at, or nil if they are never reset.
+func (conf *Config) ExtremesResetTime() *time.Time {
+	if len(conf.ExtremesReset) == 0 {
+		return nil
+	}
+
+	resetAt, err := time.Parse("15:04", conf.ExtremesReset)
+	if err != nil {
+		return nil
+	}
+	return &resetAt
+}
+
+// validateConfig performs validations that span multiple fields of the config.
+func validateConfig(sl validator.StructLevel) {
+	conf := sl.Current().Interface().(Config)
+
+	minInterval, maxInterval := conf.IntervalBounds()
+	if minInterval > maxInterval {
+		sl.ReportError(conf.MinIntervalSecs, "MinIntervalSecs", "MinIntervalSecs", "ltefield", "MaxIntervalSecs")
+	}
+	if conf.IntervalSecs < minInterval || (!conf.AllowFastInterval && conf.IntervalSecs < absoluteMinIntervalSeconds) {
+		sl.ReportError(conf.IntervalSecs, "IntervalSecs", "IntervalSecs", "min", strconv.Itoa(minInterval))
+	}
+	if conf.IntervalSecs > maxInterval || conf.IntervalSecs > absoluteMaxIntervalSeconds {
+		sl.ReportError(conf.IntervalSecs, "IntervalSecs", "IntervalSecs", "max", strconv.Itoa(maxInterval))
+	}
+
+	if !conf.MqttConfig.Disabled && conf.PublishTimeoutMs >= conf.IntervalSecs*1000 {
+		sl.ReportError(conf.PublishTimeoutMs, "PublishTimeoutMs", "PublishTimeoutMs", "ltinterval", "")
+	}
+	if !conf.MqttConfig.Disabled && conf.PublishRetries > 0 && (conf.PublishRetries+1)*conf.PublishTimeoutMs >= conf.IntervalSecs*1000 {
+		sl.ReportError(conf.PublishRetries, "PublishRetries", "PublishRetries", "ltinterval", "")
+	}
+
+	if conf.PayloadFormat == PayloadFormatJson && conf.UsesFieldTopics() {
+		sl.ReportError(conf.PayloadFormat, "PayloadFormat", "PayloadFormat", "singletopic", "")
+	}
+	if conf.PayloadFormat == PayloadFormatBoth && !conf.MqttConfig.Disabled && !conf.UsesFieldTopics() {
+		sl.ReportError(conf.Topic, "Topic", "Topic", "fieldtopic", "")
+	}
+	if conf.PayloadFormat == PayloadFormatBoth && strings.Contains(conf.CombinedTopic, topicFieldPlaceholder) {
+		sl.ReportError(conf.CombinedTopic, "CombinedTopic", "CombinedTopic", "singletopic", "")
+	}
+
+	if conf.PhaseOffsetMs > 0 && conf.PhaseOffsetMs >= conf.IntervalSecs*1000 {
+		sl.ReportError(conf.PhaseOffsetMs, "PhaseOffsetMs", "PhaseOffsetMs", "ltinterval", "")
+	}
+
+	if conf.MetricsIntervalSecs > 0 && conf.IntervalSecs > 0 && (conf.MetricsIntervalSecs < conf.IntervalSecs || conf.MetricsIntervalSecs%conf.IntervalSecs != 0) {
+		sl.ReportError(conf.MetricsIntervalSecs, "MetricsIntervalSecs", "MetricsIntervalSecs", "multipleofinterval", "")
+	}
+
+	if field, ok := validateSensors(conf.Sensors, conf.GpioBus); !ok {
+		sl.ReportError(conf.Sensors, "Sensors", "Sensors", "unique", field)
+	}
+
+	if _, err := conf.AlertTemplate(); err != nil {
+		sl.ReportError(conf.AlertWebhookTemplate, "AlertWebhookTemplate", "AlertWebhookTemplate", "template", "")
+	}
+
+	if conf.PublishComfort {
+		if conf.ComfortTemperatureMin >= conf.ComfortTemperatureMax {
+			sl.ReportError(conf.ComfortTemperatureMin, "ComfortTemperatureMin", "ComfortTemperatureMin", "ltfield", "ComfortTemperatureMax")
+		}
+		if conf.ComfortHumidityMin >= conf.ComfortHumidityMax {
+			sl.ReportError(conf.ComfortHumidityMin, "ComfortHumidityMin", "ComfortHumidityMin", "ltfield", "ComfortHumidityMax")
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import "text/template"
+
+const (
+	defaultAlertThreshold    = 3
+	defaultAlertDebounceSecs = 300
+)
+
+func defaultAlertConfig() AlertConfig {
+	return AlertConfig{
+		AlertThreshold:    defaultAlertThreshold,
+		AlertDebounceSecs: defaultAlertDebounceSecs,
+	}
+}
+
+type AlertConfig struct {
+	AlertWebhookUrl      string `json:"alert_webhook_url,omitempty" env:"ALERT_WEBHOOK_URL" validate:"omitempty,url"`
+	AlertWebhookTemplate string `json:"alert_webhook_template,omitempty" env:"ALERT_WEBHOOK_TEMPLATE"`
+	AlertThreshold       int    `json:"alert_threshold,omitempty" env:"ALERT_THRESHOLD" validate:"gte=0"`
+	AlertDebounceSecs    int    `json:"alert_debounce_s,omitempty" env:"ALERT_DEBOUNCE_S" validate:"gte=0"`
+}
+
+func (conf AlertConfig) Enabled() bool {
+	return len(conf.AlertWebhookUrl) > 0
+}
+
+// AlertTemplate parses the template of the webhook body, it returns nil if no template is configured.
+func (conf AlertConfig) AlertTemplate() (*template.Template, error) {
+	if len(conf.AlertWebhookTemplate) == 0 {
+		return nil, nil
+	}
+	return template.New("alert").Parse(conf.AlertWebhookTemplate)
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// csvFields are the fields of a measurement that can be written as columns of the CSV file.
+var csvFields = []string{"timestamp", "placement", "temperature", "humidity", "pressure", "altitude"}
+
+// CsvColumn is a column of the CSV file, consisting of the field of the measurement and the header of the column.
+type CsvColumn struct {
+	Field  string
+	Header string
+}
+
+// parseCsvColumn parses a column in the form "field" or "field:header".
+func parseCsvColumn(spec string) (CsvColumn, bool) {
+	field, header := spec, spec
+	if idx := strings.Index(spec, ":"); idx >= 0 {
+		field, header = spec[:idx], spec[idx+1:]
+	}
+	if len(header) == 0 || !sliceContains(csvFields, field) {
+		return CsvColumn{}, false
+	}
+	return CsvColumn{Field: strings.ToLower(field), Header: header}, true
+}
+
+// CsvColumnList returns the configured columns of the CSV file, or nil if the default columns should be written.
+func (conf *Config) CsvColumnList() []CsvColumn {
+	if len(conf.CsvColumns) == 0 {
+		return nil
+	}
+
+	columns := make([]CsvColumn, 0, len(conf.CsvColumns))
+	for _, spec := range conf.CsvColumns {
+		if column, ok := parseCsvColumn(spec); ok {
+			columns = append(columns, column)
+		}
+	}
+	return columns
+}
+
+func validateCsvColumn(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() != reflect.String {
+		return false
+	}
+
+	_, ok := parseCsvColumn(field.String())
+	return ok
+}
@@ -0,0 +1,78 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// derivedDependency describes a derived value and the measured values it is computed from.
+type derivedDependency struct {
+	name    string
+	enabled func(conf *Config) bool
+	inputs  []string
+}
+
+var derivedDependencies = []derivedDependency{
+	{
+		name:    "sea-level pressure",
+		enabled: func(conf *Config) bool { return conf.PublishSeaLevelPressure },
+		inputs:  []string{"pressure", "temperature"},
+	},
+	{
+		name:    "specific humidity",
+		enabled: func(conf *Config) bool { return conf.PublishSpecificHumidity },
+		inputs:  []string{"temperature", "humidity", "pressure"},
+	},
+	{
+		name:    "dew point",
+		enabled: func(conf *Config) bool { return conf.PublishDewPoint },
+		inputs:  []string{"temperature", "humidity"},
+	},
+	{
+		name:    "absolute humidity",
+		enabled: func(conf *Config) bool { return conf.PublishAbsoluteHumidity },
+		inputs:  []string{"temperature", "humidity"},
+	},
+	{
+		name:    "pressure tendency",
+		enabled: func(conf *Config) bool { return conf.PublishPressureTendency },
+		inputs:  []string{"pressure"},
+	},
+	{
+		name:    "comfort",
+		enabled: func(conf *Config) bool { return conf.PublishComfort || conf.PublishComfortIndex },
+		inputs:  []string{"temperature", "humidity"},
+	},
+	{
+		name:    "condensation risk",
+		enabled: func(conf *Config) bool { return len(conf.CondensationSurfaces) > 0 },
+		inputs:  []string{"temperature", "humidity"},
+	},
+}
+
+// DependencyWarnings returns a warning for each enabled derived value whose inputs are not exported as metrics.
+// Derived values are always computed from the values read from the sensor, so this is not an error, but the derived
+// value can not be reproduced from the exported metrics.
+func (conf *Config) DependencyWarnings() []string {
+	var warnings []string
+	for _, dep := range derivedDependencies {
+		if !dep.enabled(conf) {
+			continue
+		}
+
+		var missing []string
+		for _, input := range dep.inputs {
+			if !conf.ExposesMetric(input) {
+				missing = append(missing, input)
+			}
+		}
+		switch len(missing) {
+		case 0:
+		case 1:
+			warnings = append(warnings, fmt.Sprintf("%s is enabled, but %s is not part of metric_measurements", dep.name, missing[0]))
+		default:
+			warnings = append(warnings, fmt.Sprintf("%s is enabled, but %s are not part of metric_measurements", dep.name, strings.Join(missing, ", ")))
+		}
+	}
+	return warnings
+}
@@ -0,0 +1,68 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/go-playground/validator/v10"
+)
+
+const (
+	defaultInfluxMeasurement       = "bme280"
+	defaultInfluxBatchSize         = 10
+	defaultInfluxFlushIntervalSecs = 60
+)
+
+func defaultInfluxConfig() InfluxConfig {
+	return InfluxConfig{
+		InfluxMeasurement:       defaultInfluxMeasurement,
+		InfluxBatchSize:         defaultInfluxBatchSize,
+		InfluxFlushIntervalSecs: defaultInfluxFlushIntervalSecs,
+	}
+}
+
+type InfluxConfig struct {
+	InfluxUrl               string `json:"influx_url,omitempty" env:"INFLUX_URL" validate:"omitempty,url"`
+	InfluxOrg               string `json:"influx_org,omitempty" env:"INFLUX_ORG" validate:"required_with=InfluxUrl"`
+	InfluxBucket            string `json:"influx_bucket,omitempty" env:"INFLUX_BUCKET" validate:"required_with=InfluxUrl"`
+	InfluxToken             string `json:"influx_token,omitempty" env:"INFLUX_TOKEN" validate:"required_with=InfluxUrl"`
+	InfluxMeasurement       string `json:"influx_measurement,omitempty" env:"INFLUX_MEASUREMENT" validate:"required_with=InfluxUrl"`
+	InfluxBatchSize         int    `json:"influx_batch_size,omitempty" env:"INFLUX_BATCH_SIZE" validate:"gte=0,lte=10000"`
+	InfluxFlushIntervalSecs int    `json:"influx_flush_interval_s,omitempty" env:"INFLUX_FLUSH_INTERVAL_S" validate:"gte=0"`
+	// InfluxTags are static tags added to each point next to the placement
+	InfluxTags map[string]string `json:"influx_tags,omitempty" env:"INFLUX_TAGS" validate:"dive,keys,influx_tag,startsnotwith=_,ne=placement,endkeys,influx_tag"`
+	// InfluxFieldPrecision is the amount of decimal places per field, fields with a precision of 0 are written as
+	// integers
+	InfluxFieldPrecision map[string]int `json:"influx_field_precision,omitempty" env:"INFLUX_FIELD_PRECISION" validate:"dive,keys,oneof=temperature humidity pressure,endkeys,gte=0,lte=6"`
+}
+
+func (conf InfluxConfig) Enabled() bool {
+	return len(conf.InfluxUrl) > 0
+}
+
+func (conf InfluxConfig) String() string {
+	token := ""
+	if len(conf.InfluxToken) > 0 {
+		token = "*** (redacted)"
+	}
+	return fmt.Sprintf("{%s %s %s %s %s %d %d %v %v}", conf.InfluxUrl, conf.InfluxOrg, conf.InfluxBucket, token,
+		conf.InfluxMeasurement, conf.InfluxBatchSize, conf.InfluxFlushIntervalSecs, conf.InfluxTags, conf.InfluxFieldPrecision)
+}
+
+// validateInfluxTag accepts tag keys and values that can be written as line protocol. Commas, equal signs and spaces
+// are escaped, control characters and a trailing backslash, which would escape the following separator, can not be
+// written.
+func validateInfluxTag(fl validator.FieldLevel) bool {
+	field := fl.Field()
+	if field.Kind() != reflect.String {
+		return false
+	}
+
+	tag := field.String()
+	if len(tag) == 0 || strings.HasSuffix(tag, `\`) {
+		return false
+	}
+	return strings.IndexFunc(tag, unicode.IsControl) < 0
+}
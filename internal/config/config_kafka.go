@@ -0,0 +1,27 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+type KafkaConfig struct {
+	KafkaBrokers       []string `json:"kafka_brokers,omitempty" env:"KAFKA_BROKERS" envSeparator:"," validate:"dive,hostname_port"`
+	KafkaTopic         string   `json:"kafka_topic,omitempty" env:"KAFKA_TOPIC" validate:"required_with=KafkaBrokers"`
+	KafkaSaslMechanism string   `json:"kafka_sasl_mechanism,omitempty" env:"KAFKA_SASL_MECHANISM" validate:"omitempty,oneof=plain scram-sha-256 scram-sha-512"`
+	KafkaUsername      string   `json:"kafka_username,omitempty" env:"KAFKA_USERNAME" validate:"required_with=KafkaSaslMechanism"`
+	KafkaPassword      string   `json:"kafka_password,omitempty" env:"KAFKA_PASSWORD" validate:"required_with=KafkaSaslMechanism"`
+	KafkaTls           bool     `json:"kafka_tls,omitempty" env:"KAFKA_TLS"`
+}
+
+func (conf KafkaConfig) Enabled() bool {
+	return len(conf.KafkaBrokers) > 0
+}
+
+func (conf KafkaConfig) String() string {
+	password := ""
+	if len(conf.KafkaPassword) > 0 {
+		password = "*** (redacted)"
+	}
+	return fmt.Sprintf("{%s %s %s %s %s %t}", strings.Join(conf.KafkaBrokers, ","), conf.KafkaTopic, conf.KafkaSaslMechanism, conf.KafkaUsername, password, conf.KafkaTls)
+}
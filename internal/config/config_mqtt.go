@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"regexp"
 	"strings"
+	"text/template"
 )
 
 var (
@@ -14,19 +15,95 @@ var (
 	mqttTopicRegex = regexp.MustCompile(`^([\w%]+)(/[\w%]+)*$`)
 )
 
+const (
+	defaultPublishTimeoutMs = 2000
+	defaultQos              = 1
+	defaultSpoolMaxBytes    = 10 * 1024 * 1024
+
+	PayloadFormatScalar  = "scalar"
+	PayloadFormatJson    = "json"
+	PayloadFormatBoth    = "both"
+	defaultPayloadFormat = PayloadFormatScalar
+
+	defaultStatusPayloadOnline  = "online"
+	defaultStatusPayloadOffline = "offline (clean)"
+	defaultStatusPayloadLost    = "offline (lost)"
+
+	defaultAvailabilityGraceIntervals = 2
+)
+
+func defaultMqttConfig() MqttConfig {
+	return MqttConfig{
+		PublishTimeoutMs:     defaultPublishTimeoutMs,
+		Qos:                  defaultQos,
+		PayloadFormat:        defaultPayloadFormat,
+		StatusPayloadOnline:  defaultStatusPayloadOnline,
+		StatusPayloadOffline: defaultStatusPayloadOffline,
+		StatusPayloadLost:    defaultStatusPayloadLost,
+		SpoolMaxBytes:        defaultSpoolMaxBytes,
+
+		AvailabilityGraceIntervals: defaultAvailabilityGraceIntervals,
+	}
+}
+
 type MqttConfig struct {
-	Disabled       bool   `json:"disable_mqtt" env:"MQTT_DISABLED"`
-	Host           string `json:"mqtt_host,omitempty" env:"MQTT_BROKER" validate:"required_if=Disabled false,mqtt_broker"`
-	Topic          string `json:"mqtt_topic,omitempty" env:"MQTT_TOPIC" validate:"required_if=Disabled false,mqtt_topic"`
-	ClientKeyFile  string `json:"mqtt_ssl_key_file,omitempty" env:"MQTT_TLS_CLIENT_KEY_FILE" validate:"required_unless=ClientCertFile '',omitempty,file"`
-	ClientCertFile string `json:"mqtt_ssl_cert_file,omitempty" env:"MQTT_TLS_CLIENT_CRT_FILE" validate:"required_unless=ClientKeyFile '',omitempty,file"`
-	ServerCaFile   string `json:"mqtt_ssl_ca_file,omitempty" env:"MQTT_TLS_SERVER_CA_FILE" validate:"omitempty,file"`
+	Disabled             bool   `json:"disable_mqtt" env:"MQTT_DISABLED"`
+	Host                 string `json:"mqtt_host,omitempty" env:"MQTT_BROKER" validate:"required_if=Disabled false,omitempty,mqtt_broker"`
+	Topic                string `json:"mqtt_topic,omitempty" env:"MQTT_TOPIC" validate:"required_if=Disabled false,omitempty,mqtt_topic_template"`
+	ClientKeyFile        string `json:"mqtt_ssl_key_file,omitempty" env:"MQTT_TLS_CLIENT_KEY_FILE" validate:"required_unless=ClientCertFile '',omitempty,file"`
+	ClientCertFile       string `json:"mqtt_ssl_cert_file,omitempty" env:"MQTT_TLS_CLIENT_CRT_FILE" validate:"required_unless=ClientKeyFile '',omitempty,file"`
+	ServerCaFile         string `json:"mqtt_ssl_ca_file,omitempty" env:"MQTT_TLS_SERVER_CA_FILE" validate:"omitempty,file|dir"`
+	TopicPrefix          string `json:"mqtt_topic_prefix,omitempty" env:"MQTT_TOPIC_PREFIX" validate:"omitempty,mqtt_topic"`
+	PublishTimeoutMs     int    `json:"mqtt_publish_timeout_ms,omitempty" env:"MQTT_PUBLISH_TIMEOUT_MS" validate:"gte=0"`
+	Qos                  int    `json:"mqtt_qos,omitempty" env:"MQTT_QOS" validate:"gte=0,lte=2"`
+	PayloadFormat        string `json:"mqtt_payload_format,omitempty" env:"MQTT_PAYLOAD_FORMAT" validate:"omitempty,oneof=scalar json both"`
+	CombinedTopic        string `json:"mqtt_combined_topic,omitempty" env:"MQTT_COMBINED_TOPIC" validate:"required_if=PayloadFormat both,omitempty,mqtt_topic_template"`
+	PublishRetries       int    `json:"mqtt_publish_retries,omitempty" env:"MQTT_PUBLISH_RETRIES" validate:"gte=0,lte=10"`
+	OfflineBufferSize    int    `json:"mqtt_offline_buffer_size,omitempty" env:"MQTT_OFFLINE_BUFFER_SIZE" validate:"gte=0,lte=100000"`
+	BirthTopic           string `json:"mqtt_birth_topic,omitempty" env:"MQTT_BIRTH_TOPIC" validate:"required_with=BirthPayload,omitempty,mqtt_topic"`
+	BirthPayload         string `json:"mqtt_birth_payload,omitempty" env:"MQTT_BIRTH_PAYLOAD" validate:"required_with=BirthTopic"`
+	StatusTopic          string `json:"mqtt_status_topic,omitempty" env:"MQTT_STATUS_TOPIC" validate:"omitempty,mqtt_topic"`
+	StatusPayloadOnline  string `json:"mqtt_status_payload_online,omitempty" env:"MQTT_STATUS_PAYLOAD_ONLINE" validate:"required_with=StatusTopic"`
+	StatusPayloadOffline string `json:"mqtt_status_payload_offline,omitempty" env:"MQTT_STATUS_PAYLOAD_OFFLINE" validate:"required_with=StatusTopic"`
+	StatusPayloadLost    string `json:"mqtt_status_payload_lost,omitempty" env:"MQTT_STATUS_PAYLOAD_LOST" validate:"required_with=StatusTopic"`
+	// AvailabilityGraceIntervals is the amount of failed or missed readings in a row after which the status topic
+	// reports the bot as offline, 0 only reports the connection to the broker
+	AvailabilityGraceIntervals int     `json:"mqtt_availability_grace_intervals,omitempty" env:"MQTT_AVAILABILITY_GRACE_INTERVALS" validate:"gte=0"`
+	VentilationReferenceTopic  string  `json:"mqtt_ventilation_reference_topic,omitempty" env:"MQTT_VENTILATION_REFERENCE_TOPIC" validate:"omitempty,mqtt_topic"`
+	VentilationMargin          float64 `json:"ventilation_margin,omitempty" env:"VENTILATION_MARGIN" validate:"gte=0"`
+	CommandTopic               string  `json:"mqtt_command_topic,omitempty" env:"MQTT_COMMAND_TOPIC" validate:"omitempty,mqtt_topic"`
+	PublishAge                 bool    `json:"mqtt_publish_age,omitempty" env:"MQTT_PUBLISH_AGE"`
+	PublishWeather             bool    `json:"mqtt_publish_weather,omitempty" env:"MQTT_PUBLISH_WEATHER"`
+	PublishSchema              bool    `json:"mqtt_publish_schema,omitempty" env:"MQTT_PUBLISH_SCHEMA"`
+	PublishStartupTest         bool    `json:"mqtt_publish_startup_test,omitempty" env:"MQTT_PUBLISH_STARTUP_TEST"`
+	PublishConfigSnapshot      bool    `json:"mqtt_publish_config_snapshot,omitempty" env:"MQTT_PUBLISH_CONFIG_SNAPSHOT"`
+
+	MaxConsecutivePublishFailures int `json:"mqtt_max_consecutive_publish_failures,omitempty" env:"MQTT_MAX_CONSECUTIVE_PUBLISH_FAILURES" validate:"gte=0"`
+	// ClientPerSensor connects each of multiple sensors with a client of its own, so the publish failures of a sensor
+	// do not delay the others
+	ClientPerSensor bool `json:"mqtt_client_per_sensor,omitempty" env:"MQTT_CLIENT_PER_SENSOR"`
+
+	SpoolDir      string `json:"mqtt_spool_dir,omitempty" env:"MQTT_SPOOL_DIR"`
+	SpoolMaxBytes int64  `json:"mqtt_spool_max_bytes,omitempty" env:"MQTT_SPOOL_MAX_BYTES" validate:"gte=0"`
 }
 
 func (conf *MqttConfig) UsesSslCerts() bool {
 	return len(conf.ClientCertFile) > 0 && len(conf.ClientKeyFile) > 0
 }
 
+// UsesBirthMessage returns whether a birth message should be published after connecting to the broker.
+func (conf *MqttConfig) UsesBirthMessage() bool {
+	return len(conf.BirthTopic) > 0 && len(conf.BirthPayload) > 0
+}
+
+// PrefixedTopic prepends the configured prefix to the given topic.
+func (conf *MqttConfig) PrefixedTopic(topic string) string {
+	if len(conf.TopicPrefix) == 0 {
+		return topic
+	}
+	return conf.TopicPrefix + "/" + topic
+}
+
 func matchTopic(topic string) bool {
 	return mqttTopicRegex.MatchString(topic)
 }
@@ -35,8 +112,81 @@ func matchHost(host string) bool {
 	return mqttHostRegex.Match([]byte(host))
 }
 
+// topicPlacementReplacer replaces the characters of the placement that break the structure of topics or have a
+// wildcard meaning.
+var topicPlacementReplacer = strings.NewReplacer("/", "_", "+", "_", "#", "_")
+
+// TopicPlacement returns the placement with the characters that are not allowed in a topic level replaced.
+func (conf *Config) TopicPlacement() string {
+	return topicPlacementReplacer.Replace(conf.Placement)
+}
+
+// topicFieldPlaceholder is the placeholder of the field that is left in a templated topic by FormatTopic.
+const topicFieldPlaceholder = "{{.Field}}"
+
+// topicTemplateData are the values that can be referenced by a templated topic.
+type topicTemplateData struct {
+	Placement string
+	Field     string
+}
+
+func isTopicTemplate(topic string) bool {
+	return strings.Contains(topic, "{{")
+}
+
+func executeTopicTemplate(topic string, data topicTemplateData) (string, error) {
+	tmpl, err := template.New("topic").Parse(topic)
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// FormatTopic replaces the %s placeholder and the {{.Placement}} placeholder of templated topics with the placement,
+// sanitized to be used in a topic. The {{.Field}} placeholder is kept, it's replaced when publishing a field.
 func (conf *Config) FormatTopic() {
-	if strings.Contains(conf.Topic, "%s") {
-		conf.Topic = fmt.Sprintf(conf.Topic, conf.Placement)
+	conf.Topic = conf.formatTopic(conf.Topic)
+	conf.CombinedTopic = conf.formatTopic(conf.CombinedTopic)
+}
+
+func (conf *Config) formatTopic(topic string) string {
+	if isTopicTemplate(topic) {
+		data := topicTemplateData{Placement: conf.TopicPlacement(), Field: topicFieldPlaceholder}
+		if executed, err := executeTopicTemplate(topic, data); err == nil {
+			topic = executed
+		}
+	}
+	if strings.Contains(topic, "%s") {
+		topic = fmt.Sprintf(topic, conf.TopicPlacement())
+	}
+	return topic
+}
+
+// UsesFieldTopics returns whether the topic contains the {{.Field}} placeholder, so each field is published to its
+// own topic instead of publishing the measurement as JSON.
+func (conf *MqttConfig) UsesFieldTopics() bool {
+	return strings.Contains(conf.Topic, topicFieldPlaceholder)
+}
+
+// MeasurementTopic returns the topic to publish the measurement object to, which is the combined topic if the
+// measurement is published to both the topics of its fields and a single topic.
+func (conf *MqttConfig) MeasurementTopic() string {
+	if conf.PayloadFormat == PayloadFormatBoth {
+		return conf.CombinedTopic
+	}
+	return conf.Topic
+}
+
+// FieldTopic returns the topic to publish the field to. The {{.Field}} placeholder of a formatted topic is replaced
+// by the field, other topics get the field appended as a sub-topic.
+func (conf *MqttConfig) FieldTopic(field string) string {
+	if conf.UsesFieldTopics() {
+		return strings.ReplaceAll(conf.Topic, topicFieldPlaceholder, field)
 	}
+	return conf.Topic + "/" + field
 }
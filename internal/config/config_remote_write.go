@@ -0,0 +1,21 @@
+package config
+
+import "fmt"
+
+type RemoteWriteConfig struct {
+	RemoteWriteUrl      string `json:"remote_write_url,omitempty" env:"REMOTE_WRITE_URL" validate:"omitempty,url"`
+	RemoteWriteUsername string `json:"remote_write_username,omitempty" env:"REMOTE_WRITE_USERNAME" validate:"required_with=RemoteWritePassword"`
+	RemoteWritePassword string `json:"remote_write_password,omitempty" env:"REMOTE_WRITE_PASSWORD" validate:"required_with=RemoteWriteUsername"`
+}
+
+func (conf RemoteWriteConfig) Enabled() bool {
+	return len(conf.RemoteWriteUrl) > 0
+}
+
+func (conf RemoteWriteConfig) String() string {
+	password := ""
+	if len(conf.RemoteWritePassword) > 0 {
+		password = "*** (redacted)"
+	}
+	return fmt.Sprintf("{%s %s %s}", conf.RemoteWriteUrl, conf.RemoteWriteUsername, password)
+}
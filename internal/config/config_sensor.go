@@ -1,18 +1,161 @@
 package config
 
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+)
+
 const (
-	defaultGpioBus     = 1
-	defaultGpioAddress = 0x76
+	defaultGpioBus           = 1
+	defaultGpioAddress       = 0x76
+	defaultSamplesPerReading = 1
+
+	defaultComfortTemperatureMin = 20
+	defaultComfortTemperatureMax = 24
+	defaultComfortHumidityMin    = 40
+	defaultComfortHumidityMax    = 60
+
+	defaultComfortIdealTemperature = 22
+	defaultComfortIdealHumidity    = 50
+
+	defaultResetStateOnReinit = true
+
+	defaultDerivedDecimalPlaces = 2
+
+	defaultSmoothingWindow = 1
+
+	RetryReadErrorsTransient = "transient"
+	RetryReadErrorsAll       = "all"
+	defaultRetryReadErrors   = RetryReadErrorsTransient
+	defaultReadMaxRetries    = 3
+	defaultReadRetryDelayMs  = 50
+
+	SensorTypeAuto   = "auto"
+	SensorTypeBme280 = "bme280"
+	SensorTypeBmp280 = "bmp280"
+
+	TemperatureUnitCelsius    = "celsius"
+	TemperatureUnitFahrenheit = "fahrenheit"
+	defaultTemperatureUnit    = TemperatureUnitCelsius
 )
 
 func defaultSensorConfig() SensorConfig {
 	return SensorConfig{
-		GpioBus:     defaultGpioBus,
-		GpioAddress: defaultGpioAddress,
+		GpioBus:           defaultGpioBus,
+		GpioAddress:       defaultGpioAddress,
+		SamplesPerReading: defaultSamplesPerReading,
+
+		ComfortTemperatureMin: defaultComfortTemperatureMin,
+		ComfortTemperatureMax: defaultComfortTemperatureMax,
+		ComfortHumidityMin:    defaultComfortHumidityMin,
+		ComfortHumidityMax:    defaultComfortHumidityMax,
+
+		ComfortIdealTemperature: defaultComfortIdealTemperature,
+		ComfortIdealHumidity:    defaultComfortIdealHumidity,
+
+		ResetStateOnReinit: defaultResetStateOnReinit,
+		RetryReadErrors:    defaultRetryReadErrors,
+		ReadMaxRetries:     defaultReadMaxRetries,
+		ReadRetryDelayMs:   defaultReadRetryDelayMs,
+
+		DerivedDecimalPlaces: defaultDerivedDecimalPlaces,
+		TemperatureUnit:      defaultTemperatureUnit,
+		SmoothingWindow:      defaultSmoothingWindow,
 	}
 }
 
 type SensorConfig struct {
-	GpioBus     int `json:"gpio_bus,omitempty" env:"GPIO_BUS" validate:"gte=0"`
-	GpioAddress int `json:"gpio_address,omitempty" env:"GPIO_ADDRESS" validate:"gte=1,lte=200"`
+	GpioBus                 int     `json:"gpio_bus,omitempty" env:"GPIO_BUS" validate:"gte=0"`
+	GpioAddress             int     `json:"gpio_address,omitempty" env:"GPIO_ADDRESS" validate:"gte=1,lte=200"`
+	BusLockFile             string  `json:"bus_lock_file,omitempty" env:"BUS_LOCK_FILE"`
+	I2cDevicePath           string  `json:"i2c_device_path,omitempty" env:"I2C_DEVICE_PATH" validate:"omitempty,file"`
+	SensorId                string  `json:"sensor_id,omitempty" env:"SENSOR_ID"`
+	SensorType              string  `json:"sensor_type,omitempty" env:"SENSOR_TYPE" validate:"omitempty,oneof=auto bme280 bmp280"`
+	MetadataFile            string  `json:"metadata_file,omitempty" env:"METADATA_FILE" validate:"omitempty,file"`
+	PublishSeaLevelPressure bool    `json:"publish_pressure_sealevel,omitempty" env:"PUBLISH_PRESSURE_SEALEVEL"`
+	StationAltitudeMeters   float64 `json:"station_altitude_m,omitempty" env:"STATION_ALTITUDE_M" validate:"gte=-500,lte=9000"`
+	DecimalPlaces           int     `json:"decimal_places,omitempty" env:"DECIMAL_PLACES" validate:"gte=0,lte=6"`
+	DerivedDecimalPlaces    int     `json:"derived_decimal_places,omitempty" env:"DERIVED_DECIMAL_PLACES" validate:"gte=0,lte=6"`
+	TemperatureUnit         string  `json:"temperature_unit,omitempty" env:"TEMPERATURE_UNIT" validate:"omitempty,oneof=celsius fahrenheit"`
+	PublishSpecificHumidity bool    `json:"publish_specific_humidity,omitempty" env:"PUBLISH_SPECIFIC_HUMIDITY"`
+	PublishDewPoint         bool    `json:"publish_dew_point,omitempty" env:"PUBLISH_DEW_POINT"`
+	PublishAbsoluteHumidity bool    `json:"publish_absolute_humidity,omitempty" env:"PUBLISH_ABSOLUTE_HUMIDITY"`
+	SamplesPerReading       int     `json:"samples_per_reading,omitempty" env:"SAMPLES_PER_READING" validate:"omitempty,min=1,max=16"`
+	LogRaw                  bool    `json:"log_raw,omitempty" env:"LOG_RAW"`
+	FailPartial             bool    `json:"fail_partial,omitempty" env:"FAIL_PARTIAL"`
+	DisableHumidityClamping bool    `json:"disable_humidity_clamping,omitempty" env:"DISABLE_HUMIDITY_CLAMPING"`
+	SleepBetweenReads       bool    `json:"sleep_between_reads,omitempty" env:"SLEEP_BETWEEN_READS"`
+	SelfHeatingCoefficient  float64 `json:"self_heating_coefficient,omitempty" env:"SELF_HEATING_COEFFICIENT" validate:"gte=0"`
+
+	TemperatureOversampling int `json:"temperature_oversampling,omitempty" env:"TEMPERATURE_OVERSAMPLING" validate:"omitempty,oneof=1 2 4 8 16"`
+	PressureOversampling    int `json:"pressure_oversampling,omitempty" env:"PRESSURE_OVERSAMPLING" validate:"omitempty,oneof=1 2 4 8 16"`
+	HumidityOversampling    int `json:"humidity_oversampling,omitempty" env:"HUMIDITY_OVERSAMPLING" validate:"omitempty,oneof=1 2 4 8 16"`
+	IirFilter               int `json:"iir_filter,omitempty" env:"IIR_FILTER" validate:"omitempty,oneof=2 4 8 16"`
+
+	ReconnectSensorAfterErrors int    `json:"reconnect_sensor_after_errors,omitempty" env:"RECONNECT_SENSOR_AFTER_ERRORS" validate:"gte=0"`
+	ResetStateOnReinit         bool   `json:"reset_state_on_reinit,omitempty" env:"RESET_STATE_ON_REINIT"`
+	RetryReadErrors            string `json:"retry_read_errors,omitempty" env:"RETRY_READ_ERRORS" validate:"omitempty,oneof=transient all"`
+	ReadMaxRetries             int    `json:"read_max_retries,omitempty" env:"READ_MAX_RETRIES" validate:"gte=0,lte=10"`
+	ReadRetryDelayMs           int    `json:"read_retry_delay_ms,omitempty" env:"READ_RETRY_DELAY_MS" validate:"gte=0,lte=5000"`
+
+	VoltageFile  string  `json:"voltage_file,omitempty" env:"VOLTAGE_FILE" validate:"omitempty,file"`
+	VoltageScale float64 `json:"voltage_scale,omitempty" env:"VOLTAGE_SCALE"`
+
+	StabilitySamples   int     `json:"stability_samples,omitempty" env:"STABILITY_SAMPLES" validate:"gte=0,lte=100"`
+	StabilityThreshold float64 `json:"stability_threshold,omitempty" env:"STABILITY_THRESHOLD" validate:"gte=0"`
+	SmoothingWindow    int     `json:"smoothing_window,omitempty" env:"SMOOTHING_WINDOW" validate:"omitempty,min=1,max=100"`
+
+	StuckReadsThreshold int `json:"stuck_reads_threshold,omitempty" env:"STUCK_READS_THRESHOLD" validate:"gte=0"`
+
+	WithholdMetricsUntilStable bool `json:"withhold_metrics_until_stable,omitempty" env:"WITHHOLD_METRICS_UNTIL_STABLE"`
+	PublishPlaceholders        bool `json:"publish_placeholders,omitempty" env:"PUBLISH_PLACEHOLDERS"`
+
+	PublishComfort        bool    `json:"publish_comfort,omitempty" env:"PUBLISH_COMFORT"`
+	ComfortTemperatureMin float64 `json:"comfort_temperature_min,omitempty" env:"COMFORT_TEMPERATURE_MIN"`
+	ComfortTemperatureMax float64 `json:"comfort_temperature_max,omitempty" env:"COMFORT_TEMPERATURE_MAX"`
+	ComfortHumidityMin    float64 `json:"comfort_humidity_min,omitempty" env:"COMFORT_HUMIDITY_MIN" validate:"gte=0,lte=100"`
+	ComfortHumidityMax    float64 `json:"comfort_humidity_max,omitempty" env:"COMFORT_HUMIDITY_MAX" validate:"gte=0,lte=100"`
+
+	PublishComfortIndex     bool    `json:"publish_comfort_index,omitempty" env:"PUBLISH_COMFORT_INDEX"`
+	ComfortIdealTemperature float64 `json:"comfort_ideal_temperature,omitempty" env:"COMFORT_IDEAL_TEMPERATURE"`
+	ComfortIdealHumidity    float64 `json:"comfort_ideal_humidity,omitempty" env:"COMFORT_IDEAL_HUMIDITY" validate:"gte=0,lte=100"`
+
+	PublishDelta            bool `json:"publish_delta,omitempty" env:"PUBLISH_DELTA"`
+	PublishPressureTendency bool `json:"publish_pressure_tendency,omitempty" env:"PUBLISH_PRESSURE_TENDENCY"`
+
+	CondensationSurfaces map[string]float64 `json:"condensation_surfaces,omitempty" env:"CONDENSATION_SURFACES" validate:"dive,keys,mqtt_topic,endkeys,gte=0"`
+
+	Deadbands          map[string]float64 `json:"deadbands,omitempty" env:"DEADBANDS" validate:"dive,keys,oneof=temperature humidity pressure,endkeys,gte=0"`
+	HeartbeatIntervals int                `json:"heartbeat_intervals,omitempty" env:"HEARTBEAT_INTERVALS" validate:"gte=0"`
+
+	MeasurementNames map[string]string `json:"measurement_names,omitempty" env:"MEASUREMENT_NAMES" validate:"dive,keys,oneof=temperature humidity pressure,endkeys,mqtt_topic"`
+
+	MeasurementScales  map[string]float64 `json:"measurement_scales,omitempty" env:"MEASUREMENT_SCALES" validate:"dive,keys,oneof=temperature humidity pressure altitude,endkeys"`
+	MeasurementOffsets map[string]float64 `json:"measurement_offsets,omitempty" env:"MEASUREMENT_OFFSETS" validate:"dive,keys,oneof=temperature humidity pressure altitude,endkeys"`
+}
+
+// EffectiveSensorId returns the configured sensor id or, if unset, an id derived from the bus and the address.
+func (conf *SensorConfig) EffectiveSensorId() string {
+	if len(conf.SensorId) > 0 {
+		return conf.SensorId
+	}
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%d:%d", conf.GpioBus, conf.GpioAddress)))
+	return hex.EncodeToString(sum[:])[:8]
+}
+
+// CorrectsTemperature returns whether the published temperature differs from the temperature read from the sensor,
+// because it is smoothed, compensated for self-heating, scaled or offset.
+func (conf *SensorConfig) CorrectsTemperature() bool {
+	_, scaled := conf.MeasurementScales["temperature"]
+	_, offset := conf.MeasurementOffsets["temperature"]
+	return scaled || offset || conf.SmoothingWindow > 1 || conf.SelfHeatingCoefficient > 0
+}
+
+// MeasurementName returns the identifier used in topics for the given measurement, which defaults to its name.
+func (conf *SensorConfig) MeasurementName(name string) string {
+	if mapped, ok := conf.MeasurementNames[name]; ok {
+		return mapped
+	}
+	return name
 }
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// metricPrefixRegex matches the names of metrics without colons, which are reserved for recording rules.
+var metricPrefixRegex = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// SensorEntry configures one of multiple sensors read by a single process. If the bus or the metric prefix is not set,
+// the one of the config is used.
+type SensorEntry struct {
+	Placement    string `json:"placement,omitempty" validate:"required"`
+	GpioBus      *int   `json:"gpio_bus,omitempty" validate:"omitempty,gte=0"`
+	GpioAddress  int    `json:"gpio_address,omitempty" validate:"gte=1,lte=200"`
+	SensorId     string `json:"sensor_id,omitempty"`
+	MetricPrefix string `json:"metric_prefix,omitempty" validate:"omitempty,metric_prefix"`
+}
+
+// SensorConfigs returns a config for each configured sensor, which is a copy of this config with the placement, the
+// bus, the address, the id and the metric prefix of the sensor. If the topics do not contain the placement, it is
+// appended to the topics, so the readings of the sensors can be told apart. If no sensors are configured, this config
+// is returned.
+func (conf *Config) SensorConfigs() []Config {
+	if len(conf.Sensors) == 0 {
+		return []Config{*conf}
+	}
+
+	configs := make([]Config, 0, len(conf.Sensors))
+	for _, sensor := range conf.Sensors {
+		sensorConf := *conf
+		sensorConf.Sensors = nil
+		sensorConf.Placement = sensor.Placement
+		if sensor.GpioBus != nil {
+			sensorConf.GpioBus = *sensor.GpioBus
+		}
+		sensorConf.GpioAddress = sensor.GpioAddress
+		sensorConf.SensorId = sensor.SensorId
+		if len(sensor.MetricPrefix) > 0 {
+			sensorConf.MetricPrefix = sensor.MetricPrefix
+		}
+		sensorConf.Topic = placementScopedTopic(sensorConf.Topic)
+		if len(sensorConf.CombinedTopic) > 0 {
+			sensorConf.CombinedTopic = placementScopedTopic(sensorConf.CombinedTopic)
+		}
+		configs = append(configs, sensorConf)
+	}
+	return configs
+}
+
+// placementScopedTopic appends the placement to the topic, unless the topic already contains it.
+func placementScopedTopic(topic string) string {
+	if strings.Contains(topic, "%s") || strings.Contains(topic, ".Placement") {
+		return topic
+	}
+	return topic + "/%s"
+}
+
+// validateSensors reports sensors sharing a placement or an address on the bus of the config.
+func validateSensors(sensors []SensorEntry, gpioBus int) (string, bool) {
+	placements := map[string]bool{}
+	addresses := map[string]bool{}
+	for _, sensor := range sensors {
+		if placements[sensor.Placement] {
+			return "Placement", false
+		}
+		placements[sensor.Placement] = true
+
+		bus := gpioBus
+		if sensor.GpioBus != nil {
+			bus = *sensor.GpioBus
+		}
+		address := fmt.Sprintf("%d:%d", bus, sensor.GpioAddress)
+		if addresses[address] {
+			return "GpioAddress", false
+		}
+		addresses[address] = true
+	}
+	return "", true
+}
+
+// validateMetricPrefix accepts valid metric names besides the namespace of the metrics, which would be ambiguous.
+func validateMetricPrefix(fl validator.FieldLevel) bool {
+	prefix := fl.Field().String()
+	return metricPrefixRegex.MatchString(prefix) && prefix != BotName
+}
@@ -1,8 +1,12 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func Test_matchHost(t *testing.T) {
@@ -61,11 +65,25 @@ func TestConfig_Validate(t *testing.T) {
 		IntervalSecs int
 		LogValues    bool
 		MqttConfig   MqttConfig
+
+		AllowFastInterval bool
+
+		MetricsIntervalSecs     int
+		PhaseOffsetMs           int
+		MinIntervalSecs         int
+		MaxIntervalSecs         int
+		PublishSeaLevelPressure bool
+		SamplesPerReading       int
+		sensorConfig            SensorConfig
+		InfluxConfig            InfluxConfig
+		AlertConfig             AlertConfig
 	}
 	tests := []struct {
 		name    string
 		fields  fields
 		wantErr bool
+		// wantErrField is the field the error is expected to name, if set
+		wantErrField string
 	}{
 		{
 			name: "all okay",
@@ -85,36 +103,764 @@ func TestConfig_Validate(t *testing.T) {
 			wantErr: false,
 		},
 		{
-			name: "missing loc",
+			name: "missing loc",
+			fields: fields{
+				MetricConfig: "0.0.0.0:9100",
+				FirmAtaPort:  "/dev/ttyUSB0",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				LogValues:    false,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid gpiobus",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      -5,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				LogValues:    false,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "sea level pressure at sea level",
+			fields: fields{
+				placement:               "loc",
+				MetricConfig:            "0.0.0.0:9100",
+				GpioBus:                 1,
+				GpioAddress:             75,
+				IntervalSecs:            30,
+				PublishSeaLevelPressure: true,
+				sensorConfig:            SensorConfig{StationAltitudeMeters: 0},
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "station altitude out of range",
+			fields: fields{
+				placement:               "loc",
+				MetricConfig:            "0.0.0.0:9100",
+				GpioBus:                 1,
+				GpioAddress:             75,
+				IntervalSecs:            30,
+				PublishSeaLevelPressure: true,
+				sensorConfig:            SensorConfig{StationAltitudeMeters: 12000},
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "publish timeout exceeds interval",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:             "tcp://host:80",
+					Topic:            "topic/bla",
+					PublishTimeoutMs: 30000,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "publish retries exceed interval",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:             "tcp://host:80",
+					Topic:            "topic/bla",
+					PublishTimeoutMs: 10000,
+					PublishRetries:   2,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "influx without bucket",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Disabled: true,
+				},
+				InfluxConfig: InfluxConfig{
+					InfluxUrl:         "http://influx:8086",
+					InfluxOrg:         "home",
+					InfluxToken:       "secret",
+					InfluxMeasurement: "bme280",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "influx tags",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Disabled: true,
+				},
+				InfluxConfig: InfluxConfig{
+					InfluxUrl:         "http://influx:8086",
+					InfluxOrg:         "home",
+					InfluxBucket:      "sensors",
+					InfluxToken:       "secret",
+					InfluxMeasurement: "bme280",
+					InfluxTags:        map[string]string{"site": "home", "floor": "first floor"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "influx precision of unknown field",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Disabled: true,
+				},
+				InfluxConfig: InfluxConfig{
+					InfluxUrl:            "http://influx:8086",
+					InfluxOrg:            "home",
+					InfluxBucket:         "sensors",
+					InfluxToken:          "secret",
+					InfluxMeasurement:    "bme280",
+					InfluxFieldPrecision: map[string]int{"altitude": 1},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "influx tag overriding placement",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Disabled: true,
+				},
+				InfluxConfig: InfluxConfig{
+					InfluxUrl:         "http://influx:8086",
+					InfluxOrg:         "home",
+					InfluxBucket:      "sensors",
+					InfluxToken:       "secret",
+					InfluxMeasurement: "bme280",
+					InfluxTags:        map[string]string{"placement": "attic"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "influx tag with newline",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Disabled: true,
+				},
+				InfluxConfig: InfluxConfig{
+					InfluxUrl:         "http://influx:8086",
+					InfluxOrg:         "home",
+					InfluxBucket:      "sensors",
+					InfluxToken:       "secret",
+					InfluxMeasurement: "bme280",
+					InfluxTags:        map[string]string{"site": "home\nfake,placement=x"},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "influx only",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Disabled: true,
+				},
+				InfluxConfig: InfluxConfig{
+					InfluxUrl:         "http://influx:8086",
+					InfluxOrg:         "home",
+					InfluxBucket:      "sensors",
+					InfluxToken:       "secret",
+					InfluxMeasurement: "bme280",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "templated topic",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "home/{{.Placement}}/bme280/{{.Field}}",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "templated topic with unknown field",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "home/{{.Room}}/bme280",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "malformed templated topic",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "home/{{.Placement/bme280",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fast interval not allowed",
+			fields: fields{
+				placement:         "loc",
+				MetricConfig:      "0.0.0.0:9100",
+				GpioBus:           1,
+				GpioAddress:       75,
+				IntervalSecs:      10,
+				AllowFastInterval: false,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fast interval allowed",
+			fields: fields{
+				placement:         "loc",
+				MetricConfig:      "0.0.0.0:9100",
+				GpioBus:           1,
+				GpioAddress:       75,
+				IntervalSecs:      1,
+				AllowFastInterval: true,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "fast interval below a second",
+			fields: fields{
+				placement:         "loc",
+				MetricConfig:      "0.0.0.0:9100",
+				GpioBus:           1,
+				GpioAddress:       75,
+				IntervalSecs:      0,
+				AllowFastInterval: true,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "fast interval keeps upper bound",
+			fields: fields{
+				placement:         "loc",
+				MetricConfig:      "0.0.0.0:9100",
+				GpioBus:           1,
+				GpioAddress:       75,
+				IntervalSecs:      301,
+				AllowFastInterval: true,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid qos",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+					Qos:   3,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "phase offset exceeds interval",
+			fields: fields{
+				placement:     "loc",
+				MetricConfig:  "0.0.0.0:9100",
+				GpioBus:       1,
+				GpioAddress:   75,
+				IntervalSecs:  30,
+				PhaseOffsetMs: 30000,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid payload format",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:          "tcp://host:80",
+					Topic:         "topic/bla",
+					PayloadFormat: "xml",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "json payload with field topics",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:          "tcp://host:80",
+					Topic:         "sensors/{{.Placement}}/{{.Field}}",
+					PayloadFormat: PayloadFormatJson,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "both payloads",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:          "tcp://host:80",
+					Topic:         "sensors/{{.Placement}}/{{.Field}}",
+					CombinedTopic: "sensors/{{.Placement}}",
+					PayloadFormat: PayloadFormatBoth,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "both payloads without combined topic",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:          "tcp://host:80",
+					Topic:         "sensors/{{.Placement}}/{{.Field}}",
+					PayloadFormat: PayloadFormatBoth,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "both payloads without field topics",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:          "tcp://host:80",
+					Topic:         "sensors/{{.Placement}}",
+					CombinedTopic: "sensors/{{.Placement}}/all",
+					PayloadFormat: PayloadFormatBoth,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "both payloads with field in combined topic",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:          "tcp://host:80",
+					Topic:         "sensors/{{.Placement}}/{{.Field}}",
+					CombinedTopic: "sensors/{{.Field}}",
+					PayloadFormat: PayloadFormatBoth,
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid alert template",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+				AlertConfig: AlertConfig{
+					AlertWebhookUrl:      "https://example.com/hook",
+					AlertWebhookTemplate: "{{ .Placement",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "birth topic without payload",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:       "tcp://host:80",
+					Topic:      "topic/bla",
+					BirthTopic: "sensors/online",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "too many samples per reading",
+			fields: fields{
+				placement:         "loc",
+				MetricConfig:      "0.0.0.0:9100",
+				GpioBus:           1,
+				GpioAddress:       75,
+				IntervalSecs:      30,
+				SamplesPerReading: 17,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "too large smoothing window",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				sensorConfig: SensorConfig{SmoothingWindow: 101},
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative stuck reads threshold",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				sensorConfig: SensorConfig{StuckReadsThreshold: -1},
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "inverted comfort thresholds",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				sensorConfig: SensorConfig{
+					PublishComfort:        true,
+					ComfortTemperatureMin: 24,
+					ComfortTemperatureMax: 20,
+					ComfortHumidityMin:    40,
+					ComfortHumidityMax:    60,
+				},
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "status topic without payloads",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				MqttConfig: MqttConfig{
+					Host:        "tcp://host:80",
+					Topic:       "topic/bla",
+					StatusTopic: "topic/bla/status",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid oversampling",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				sensorConfig: SensorConfig{
+					PressureOversampling: 3,
+				},
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "unknown temperature unit",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				sensorConfig: SensorConfig{
+					TemperatureUnit: "kelvin",
+				},
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "measurement names",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				sensorConfig: SensorConfig{
+					MeasurementNames: map[string]string{"temperature": "temp", "humidity": "hum"},
+				},
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "unknown measurement name",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				sensorConfig: SensorConfig{
+					MeasurementNames: map[string]string{"altitude": "alt"},
+				},
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "negative condensation margin",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 30,
+				sensorConfig: SensorConfig{
+					CondensationSurfaces: map[string]float64{"window": 10, "wall": -1},
+				},
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "metrics interval not a multiple of interval",
+			fields: fields{
+				placement:           "loc",
+				MetricConfig:        "0.0.0.0:9100",
+				GpioBus:             1,
+				GpioAddress:         75,
+				IntervalSecs:        30,
+				MetricsIntervalSecs: 45,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "interval below default bounds",
+			fields: fields{
+				placement:    "loc",
+				MetricConfig: "0.0.0.0:9100",
+				GpioBus:      1,
+				GpioAddress:  75,
+				IntervalSecs: 10,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "interval within custom bounds",
+			fields: fields{
+				placement:       "loc",
+				MetricConfig:    "0.0.0.0:9100",
+				GpioBus:         1,
+				GpioAddress:     75,
+				IntervalSecs:    3600,
+				MaxIntervalSecs: 3600,
+				MqttConfig: MqttConfig{
+					Host:  "tcp://host:80",
+					Topic: "topic/bla",
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "interval below absolute bounds",
 			fields: fields{
-				MetricConfig: "0.0.0.0:9100",
-				FirmAtaPort:  "/dev/ttyUSB0",
-				GpioBus:      1,
-				GpioAddress:  75,
-				IntervalSecs: 30,
-				LogValues:    false,
+				placement:       "loc",
+				MetricConfig:    "0.0.0.0:9100",
+				GpioBus:         1,
+				GpioAddress:     75,
+				IntervalSecs:    absoluteMinIntervalSeconds - 1,
+				MinIntervalSecs: defaultMinIntervalSeconds,
 				MqttConfig: MqttConfig{
 					Host:  "tcp://host:80",
 					Topic: "topic/bla",
 				},
 			},
-			wantErr: true,
+			wantErr:      true,
+			wantErrField: "IntervalSecs",
 		},
 		{
-			name: "invalid gpiobus",
+			name: "interval below absolute bounds with fast interval allowed",
 			fields: fields{
-				placement:    "loc",
-				MetricConfig: "0.0.0.0:9100",
-				GpioBus:      -5,
-				GpioAddress:  75,
-				IntervalSecs: 30,
-				LogValues:    false,
+				placement:         "loc",
+				MetricConfig:      "0.0.0.0:9100",
+				GpioBus:           1,
+				GpioAddress:       75,
+				IntervalSecs:      absoluteMinIntervalSeconds - 1,
+				MinIntervalSecs:   defaultMinIntervalSeconds,
+				AllowFastInterval: true,
 				MqttConfig: MqttConfig{
 					Host:  "tcp://host:80",
 					Topic: "topic/bla",
 				},
 			},
-			wantErr: true,
+			wantErr: false,
 		},
 		{
 			name: "missing host",
@@ -135,20 +881,34 @@ func TestConfig_Validate(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			sensorConfig := tt.fields.sensorConfig
+			sensorConfig.GpioBus = tt.fields.GpioBus
+			sensorConfig.GpioAddress = tt.fields.GpioAddress
+			sensorConfig.PublishSeaLevelPressure = tt.fields.PublishSeaLevelPressure
+			sensorConfig.SamplesPerReading = tt.fields.SamplesPerReading
 			c := &Config{
 				Placement:    tt.fields.placement,
 				MetricConfig: tt.fields.MetricConfig,
-				SensorConfig: SensorConfig{
-					GpioBus:     tt.fields.GpioBus,
-					GpioAddress: tt.fields.GpioAddress,
-				},
+				SensorConfig: sensorConfig,
 				IntervalSecs: tt.fields.IntervalSecs,
 				LogSensor:    tt.fields.LogValues,
-				MqttConfig:   tt.fields.MqttConfig,
+
+				AllowFastInterval:   tt.fields.AllowFastInterval,
+				MetricsIntervalSecs: tt.fields.MetricsIntervalSecs,
+				MinIntervalSecs:     tt.fields.MinIntervalSecs,
+				MaxIntervalSecs:     tt.fields.MaxIntervalSecs,
+				MqttConfig:          tt.fields.MqttConfig,
+				PhaseOffsetMs:       tt.fields.PhaseOffsetMs,
+				InfluxConfig:        tt.fields.InfluxConfig,
+				AlertConfig:         tt.fields.AlertConfig,
 			}
-			if err := Validate(c); (err != nil) != tt.wantErr {
+			err := Validate(c)
+			if (err != nil) != tt.wantErr {
 				t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
 			}
+			if err != nil && tt.wantErrField != "" && !strings.Contains(err.Error(), tt.wantErrField) {
+				t.Errorf("Validate() error = %v, want error for field %s", err, tt.wantErrField)
+			}
 		})
 	}
 }
@@ -172,16 +932,37 @@ func TestReadJsonConfig(t *testing.T) {
 			want: &Config{
 				Placement:    "location",
 				MetricConfig: ":1234",
-				SensorConfig: SensorConfig{
-					GpioBus:     defaultGpioBus,
-					GpioAddress: defaultGpioAddress,
-				},
+				SensorConfig: defaultSensorConfig(),
 				IntervalSecs: defaultIntervalSeconds,
 				LogSensor:    defaultLogSensor,
+
+				MinIntervalSecs:   defaultMinIntervalSeconds,
+				MaxIntervalSecs:   defaultMaxIntervalSeconds,
+				Platform:          defaultPlatform,
+				LogLevel:          defaultLogLevel,
+				LogFormat:         defaultLogFormat,
+				LogFileMaxSizeMb:  defaultLogFileMaxSizeMb,
+				LogFileMaxBackups: defaultLogFileMaxBackups,
+				MinFreeDiskMb:     defaultMinFreeDiskMb,
+
+				TimestampPrecision: defaultTimestampPrecision,
+
+				ReadinessMaxFailedReads: defaultReadinessMaxFailedReads,
 				MqttConfig: MqttConfig{
-					Host:  "tcp://broker:1883",
-					Topic: "mytopic/foo",
+					Host:                 "tcp://broker:1883",
+					Topic:                "mytopic/foo",
+					PublishTimeoutMs:     defaultPublishTimeoutMs,
+					Qos:                  defaultQos,
+					PayloadFormat:        defaultPayloadFormat,
+					StatusPayloadOnline:  defaultStatusPayloadOnline,
+					StatusPayloadOffline: defaultStatusPayloadOffline,
+					StatusPayloadLost:    defaultStatusPayloadLost,
+					SpoolMaxBytes:        defaultSpoolMaxBytes,
+
+					AvailabilityGraceIntervals: defaultAvailabilityGraceIntervals,
 				},
+				InfluxConfig: defaultInfluxConfig(),
+				AlertConfig:  defaultAlertConfig(),
 			},
 			wantErr: false,
 		},
@@ -200,6 +981,196 @@ func TestReadJsonConfig(t *testing.T) {
 	}
 }
 
+func TestReadOverlayConfig(t *testing.T) {
+	overlay := filepath.Join(t.TempDir(), "overlay.json")
+	if err := os.WriteFile(overlay, []byte(`{"placement": "overlay", "gpio_bus": 3}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Read("../../contrib/example-config.json", overlay)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+
+	want := &Config{
+		Placement:    "overlay",
+		MetricConfig: ":1234",
+		SensorConfig: SensorConfig{
+			GpioBus:           3,
+			GpioAddress:       defaultGpioAddress,
+			SamplesPerReading: defaultSamplesPerReading,
+
+			ComfortTemperatureMin: defaultComfortTemperatureMin,
+			ComfortTemperatureMax: defaultComfortTemperatureMax,
+			ComfortHumidityMin:    defaultComfortHumidityMin,
+			ComfortHumidityMax:    defaultComfortHumidityMax,
+
+			ComfortIdealTemperature: defaultComfortIdealTemperature,
+			ComfortIdealHumidity:    defaultComfortIdealHumidity,
+
+			ResetStateOnReinit: defaultResetStateOnReinit,
+			RetryReadErrors:    defaultRetryReadErrors,
+			ReadMaxRetries:     defaultReadMaxRetries,
+			ReadRetryDelayMs:   defaultReadRetryDelayMs,
+
+			DerivedDecimalPlaces: defaultDerivedDecimalPlaces,
+			TemperatureUnit:      defaultTemperatureUnit,
+			SmoothingWindow:      defaultSmoothingWindow,
+		},
+		IntervalSecs: defaultIntervalSeconds,
+		LogSensor:    defaultLogSensor,
+
+		MinIntervalSecs:   defaultMinIntervalSeconds,
+		MaxIntervalSecs:   defaultMaxIntervalSeconds,
+		Platform:          defaultPlatform,
+		LogLevel:          defaultLogLevel,
+		LogFormat:         defaultLogFormat,
+		LogFileMaxSizeMb:  defaultLogFileMaxSizeMb,
+		LogFileMaxBackups: defaultLogFileMaxBackups,
+		MinFreeDiskMb:     defaultMinFreeDiskMb,
+
+		TimestampPrecision: defaultTimestampPrecision,
+
+		ReadinessMaxFailedReads: defaultReadinessMaxFailedReads,
+		MqttConfig: MqttConfig{
+			Host:                 "tcp://broker:1883",
+			Topic:                "mytopic/foo",
+			PublishTimeoutMs:     defaultPublishTimeoutMs,
+			Qos:                  defaultQos,
+			PayloadFormat:        defaultPayloadFormat,
+			StatusPayloadOnline:  defaultStatusPayloadOnline,
+			StatusPayloadOffline: defaultStatusPayloadOffline,
+			StatusPayloadLost:    defaultStatusPayloadLost,
+			SpoolMaxBytes:        defaultSpoolMaxBytes,
+
+			AvailabilityGraceIntervals: defaultAvailabilityGraceIntervals,
+		},
+		InfluxConfig: defaultInfluxConfig(),
+		AlertConfig:  defaultAlertConfig(),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read() got = %v, want %v", got, want)
+	}
+}
+
+func TestReadYamlConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config")
+	content := "placement: yaml\ninterval_s: 60\nmqtt_host: tcp://broker:1883\nmqtt_topic: mytopic/foo\n"
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Read(file)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got.Placement != "yaml" || got.IntervalSecs != 60 || got.Host != "tcp://broker:1883" || got.Topic != "mytopic/foo" {
+		t.Errorf("Read() got = %v", got)
+	}
+}
+
+func TestReadInvalidConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(file, []byte("placement: [unterminated"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Read(file); err == nil {
+		t.Errorf("Read() expected error for invalid content")
+	}
+}
+
+func TestReadConfigExpandsEnv(t *testing.T) {
+	if err := os.Setenv("GOBOT_TEST_PLACEMENT", "expanded"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("GOBOT_TEST_PLACEMENT")
+
+	file := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(file, []byte(`{"placement": "${GOBOT_TEST_PLACEMENT}", "mqtt_topic": "${GOBOT_TEST_UNSET}"}`), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Read(file)
+	if err != nil {
+		t.Fatalf("Read() error = %v", err)
+	}
+	if got.Placement != "expanded" {
+		t.Errorf("expected placement to be expanded, got %q", got.Placement)
+	}
+	if got.Topic != "" {
+		t.Errorf("expected unset variable to expand to empty string, got %q", got.Topic)
+	}
+}
+
+func TestReadConfigPrecedence(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.json")
+	content := `{"placement": "file", "mqtt_host": "tcp://file:1883", "interval_s": 60, "log_sensor": true}`
+	if err := os.WriteFile(file, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		env   map[string]string
+		files []string
+		want  func(conf *Config) (got, want interface{})
+	}{
+		{
+			name: "default",
+			want: func(conf *Config) (interface{}, interface{}) { return conf.IntervalSecs, defaultIntervalSeconds },
+		},
+		{
+			name:  "file over default",
+			files: []string{file},
+			want:  func(conf *Config) (interface{}, interface{}) { return conf.IntervalSecs, 60 },
+		},
+		{
+			name:  "env over file placement",
+			env:   map[string]string{"GOBOT_BME280_PLACEMENT": "env"},
+			files: []string{file},
+			want:  func(conf *Config) (interface{}, interface{}) { return conf.Placement, "env" },
+		},
+		{
+			name:  "env over file mqtt host",
+			env:   map[string]string{"GOBOT_BME280_MQTT_BROKER": "tcp://env:1883"},
+			files: []string{file},
+			want:  func(conf *Config) (interface{}, interface{}) { return conf.Host, "tcp://env:1883" },
+		},
+		{
+			name:  "unset env keeps file",
+			env:   map[string]string{"GOBOT_BME280_PLACEMENT": "env"},
+			files: []string{file},
+			want:  func(conf *Config) (interface{}, interface{}) { return conf.Host, "tcp://file:1883" },
+		},
+		{
+			name:  "env zero value over file",
+			env:   map[string]string{"GOBOT_BME280_LOG_SENSOR_READINGS": "false"},
+			files: []string{file},
+			want:  func(conf *Config) (interface{}, interface{}) { return conf.LogSensor, false },
+		},
+		{
+			name: "env over default",
+			env:  map[string]string{"GOBOT_BME280_INTERVAL_S": "120"},
+			want: func(conf *Config) (interface{}, interface{}) { return conf.IntervalSecs, 120 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, val := range tt.env {
+				t.Setenv(key, val)
+			}
+			conf, err := Read(tt.files...)
+			if err != nil {
+				t.Fatalf("Read() error = %v", err)
+			}
+			if got, want := tt.want(conf); !reflect.DeepEqual(got, want) {
+				t.Errorf("got %v, want %v", got, want)
+			}
+		})
+	}
+}
+
 func Test_matchTopic(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -265,6 +1236,51 @@ func TestConfig_TemplateTopic(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "template with invalid characters",
+			fields: fields{
+				placement: "kids/room #2",
+				MqttConfig: MqttConfig{
+					Topic: "prefix/%s",
+				},
+			},
+			want: &Config{
+				Placement: "kids/room #2",
+				MqttConfig: MqttConfig{
+					Topic: "prefix/kids_room _2",
+				},
+			},
+		},
+		{
+			name: "go template placement",
+			fields: fields{
+				placement: "kids/room",
+				MqttConfig: MqttConfig{
+					Topic: "home/{{.Placement}}/bme280",
+				},
+			},
+			want: &Config{
+				Placement: "kids/room",
+				MqttConfig: MqttConfig{
+					Topic: "home/kids_room/bme280",
+				},
+			},
+		},
+		{
+			name: "go template placement and field",
+			fields: fields{
+				placement: "loc",
+				MqttConfig: MqttConfig{
+					Topic: "home/{{ .Placement }}/bme280/{{ .Field }}",
+				},
+			},
+			want: &Config{
+				Placement: "loc",
+				MqttConfig: MqttConfig{
+					Topic: "home/loc/bme280/{{.Field}}",
+				},
+			},
+		},
 		{
 			name: "no templating",
 			fields: fields{
@@ -294,3 +1310,339 @@ func TestConfig_TemplateTopic(t *testing.T) {
 		})
 	}
 }
+
+func TestMqttConfig_PrefixedTopic(t *testing.T) {
+	tests := []struct {
+		name   string
+		prefix string
+		topic  string
+		want   string
+	}{
+		{
+			name:  "no prefix",
+			topic: "sensors/bme280",
+			want:  "sensors/bme280",
+		},
+		{
+			name:   "prefix",
+			prefix: "sites/hq",
+			topic:  "sensors/bme280",
+			want:   "sites/hq/sensors/bme280",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &MqttConfig{TopicPrefix: tt.prefix}
+			if got := conf.PrefixedTopic(tt.topic); got != tt.want {
+				t.Errorf("PrefixedTopic() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMqttConfig_FieldTopic(t *testing.T) {
+	tests := []struct {
+		name  string
+		topic string
+		field string
+		want  string
+	}{
+		{
+			name:  "plain topic",
+			topic: "sensors/loc",
+			field: "dew_point",
+			want:  "sensors/loc/dew_point",
+		},
+		{
+			name:  "templated topic",
+			topic: "home/loc/bme280/{{.Field}}",
+			field: "temperature",
+			want:  "home/loc/bme280/temperature",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := &MqttConfig{Topic: tt.topic}
+			if got := conf.FieldTopic(tt.field); got != tt.want {
+				t.Errorf("FieldTopic() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSensorConfig_MeasurementName(t *testing.T) {
+	conf := SensorConfig{MeasurementNames: map[string]string{"temperature": "temp"}}
+	if got := conf.MeasurementName("temperature"); got != "temp" {
+		t.Errorf("MeasurementName() = %q, want %q", got, "temp")
+	}
+	if got := conf.MeasurementName("humidity"); got != "humidity" {
+		t.Errorf("MeasurementName() = %q, want %q", got, "humidity")
+	}
+}
+
+func TestConfig_CronSchedule(t *testing.T) {
+	conf := DefaultConfig()
+	schedule, err := conf.CronSchedule()
+	if err != nil || schedule != nil {
+		t.Fatalf("CronSchedule() = %v, %v, want no schedule", schedule, err)
+	}
+
+	conf.Schedule = []string{"*/5 6-21 * * *", "0 22-23,0-5 * * *"}
+	schedule, err = conf.CronSchedule()
+	if err != nil {
+		t.Fatalf("CronSchedule() error = %v", err)
+	}
+
+	tests := []struct {
+		now  time.Time
+		want time.Time
+	}{
+		{
+			now:  time.Date(2023, 10, 1, 12, 1, 0, 0, time.Local),
+			want: time.Date(2023, 10, 1, 12, 5, 0, 0, time.Local),
+		},
+		{
+			now:  time.Date(2023, 10, 1, 23, 1, 0, 0, time.Local),
+			want: time.Date(2023, 10, 2, 0, 0, 0, 0, time.Local),
+		},
+	}
+	for _, tt := range tests {
+		if got := schedule.Next(tt.now); !got.Equal(tt.want) {
+			t.Errorf("Next(%v) = %v, want %v", tt.now, got, tt.want)
+		}
+	}
+}
+
+func TestConfig_ValidateSchedule(t *testing.T) {
+	conf := DefaultConfig()
+	conf.Placement = "loc"
+	conf.Host = "tcp://host:80"
+	conf.Topic = "topic/bla"
+
+	conf.Schedule = []string{"*/5 * * * *"}
+	if err := Validate(&conf); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+
+	conf.Schedule = []string{"every five minutes"}
+	if err := Validate(&conf); err == nil {
+		t.Errorf("Validate() expected error for invalid schedule")
+	}
+}
+
+func TestConfig_ValidatePlatform(t *testing.T) {
+	conf := DefaultConfig()
+	conf.Placement = "loc"
+	conf.Host = "tcp://host:80"
+	conf.Topic = "topic/bla"
+
+	for _, platform := range []string{PlatformRaspi, PlatformTinkerboard, PlatformJetson} {
+		conf.Platform = platform
+		if err := Validate(&conf); err != nil {
+			t.Errorf("Validate() error = %v for platform %q", err, platform)
+		}
+	}
+
+	conf.Platform = "beaglebone"
+	if err := Validate(&conf); err == nil {
+		t.Errorf("Validate() expected error for unknown platform")
+	}
+}
+
+func TestConfig_CsvColumnList(t *testing.T) {
+	conf := DefaultConfig()
+	conf.Placement = "loc"
+	conf.Host = "tcp://host:80"
+	conf.Topic = "topic/bla"
+
+	conf.CsvColumns = []string{"timestamp:Time", "placement:Room", "temperature:°C", "Humidity"}
+	if err := Validate(&conf); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	want := []CsvColumn{
+		{Field: "timestamp", Header: "Time"},
+		{Field: "placement", Header: "Room"},
+		{Field: "temperature", Header: "°C"},
+		{Field: "humidity", Header: "Humidity"},
+	}
+	if got := conf.CsvColumnList(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CsvColumnList() = %v, want %v", got, want)
+	}
+
+	conf.CsvColumns = []string{"dewpoint:Dew point"}
+	if err := Validate(&conf); err == nil {
+		t.Errorf("Validate() expected error for unknown field")
+	}
+	conf.CsvColumns = []string{"pressure:"}
+	if err := Validate(&conf); err == nil {
+		t.Errorf("Validate() expected error for empty header")
+	}
+}
+
+func TestConfig_SensorConfigs(t *testing.T) {
+	conf := DefaultConfig()
+	conf.Placement = "loc"
+	conf.Host = "tcp://host:80"
+	conf.Topic = "sensors"
+
+	if got := conf.SensorConfigs(); len(got) != 1 || got[0].Placement != "loc" {
+		t.Errorf("SensorConfigs() without sensors = %v, want the config itself", got)
+	}
+
+	bus := 0
+	conf.Sensors = []SensorEntry{
+		{Placement: "attic", GpioAddress: 0x76},
+		{Placement: "cellar", GpioBus: &bus, GpioAddress: 0x77, SensorId: "outdoor"},
+	}
+	if err := Validate(&conf); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	got := conf.SensorConfigs()
+	if len(got) != 2 {
+		t.Fatalf("SensorConfigs() returned %d configs, want 2", len(got))
+	}
+	if got[0].Placement != "attic" || got[0].GpioBus != defaultGpioBus || got[0].GpioAddress != 0x76 {
+		t.Errorf("SensorConfigs()[0] = %s %d:%d, want attic %d:118", got[0].Placement, got[0].GpioBus, got[0].GpioAddress, defaultGpioBus)
+	}
+	if got[1].Placement != "cellar" || got[1].GpioBus != 0 || got[1].GpioAddress != 0x77 || got[1].SensorId != "outdoor" {
+		t.Errorf("SensorConfigs()[1] = %s %d:%d %s, want cellar 0:119 outdoor", got[1].Placement, got[1].GpioBus, got[1].GpioAddress, got[1].SensorId)
+	}
+	got[1].FormatTopic()
+	if got[1].Topic != "sensors/cellar" {
+		t.Errorf("FormatTopic() = %s, want sensors/cellar", got[1].Topic)
+	}
+
+	conf.Sensors[1].Placement = "attic"
+	if err := Validate(&conf); err == nil {
+		t.Errorf("Validate() expected error for duplicate placement")
+	}
+	conf.Sensors[1].Placement = "cellar"
+	conf.Sensors[1].GpioBus = nil
+	conf.Sensors[1].GpioAddress = 0x76
+	if err := Validate(&conf); err == nil {
+		t.Errorf("Validate() expected error for duplicate address")
+	}
+}
+
+func TestConfig_SensorConfigsMetricPrefix(t *testing.T) {
+	conf := DefaultConfig()
+	conf.Placement = "loc"
+	conf.Host = "tcp://host:80"
+	conf.Topic = "sensors"
+	conf.Sensors = []SensorEntry{
+		{Placement: "attic", GpioAddress: 0x76, MetricPrefix: "attic"},
+		{Placement: "cellar", GpioAddress: 0x77},
+	}
+	if err := Validate(&conf); err != nil {
+		t.Errorf("Validate() error = %v", err)
+	}
+	got := conf.SensorConfigs()
+	if got[0].MetricPrefix != "attic" || got[1].MetricPrefix != "" {
+		t.Errorf("SensorConfigs() metric prefixes = %q, %q, want attic and none", got[0].MetricPrefix, got[1].MetricPrefix)
+	}
+
+	for _, prefix := range []string{BotName, "1st_floor", "attic:bme280", "attic-bme280"} {
+		conf.Sensors[0].MetricPrefix = prefix
+		if err := Validate(&conf); err == nil {
+			t.Errorf("Validate() expected error for metric prefix %q", prefix)
+		}
+	}
+}
+
+func TestConfig_DependencyWarnings(t *testing.T) {
+	conf := DefaultConfig()
+	conf.PublishSpecificHumidity = true
+	conf.PublishComfort = true
+	if warnings := conf.DependencyWarnings(); len(warnings) != 0 {
+		t.Errorf("expected no warnings if all measurements are exposed, got %v", warnings)
+	}
+
+	conf.MetricMeasurements = []string{"temperature"}
+	want := []string{
+		"specific humidity is enabled, but humidity, pressure are not part of metric_measurements",
+		"comfort is enabled, but humidity is not part of metric_measurements",
+	}
+	if got := conf.DependencyWarnings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("DependencyWarnings() = %v, want %v", got, want)
+	}
+}
+
+func TestConfig_Hash(t *testing.T) {
+	a := DefaultConfig()
+	a.Placement = "livingroom"
+	b := DefaultConfig()
+	b.Placement = "livingroom"
+
+	if a.Hash() != b.Hash() {
+		t.Errorf("expected identical configs to have the same hash")
+	}
+
+	b.Placement = "kitchen"
+	if a.Hash() == b.Hash() {
+		t.Errorf("expected different configs to have different hashes")
+	}
+}
+
+func TestConfig_ExposesMetric(t *testing.T) {
+	conf := DefaultConfig()
+	if !conf.ExposesMetric("pressure") {
+		t.Error("expected all measurements to be exposed by default")
+	}
+
+	conf.MetricMeasurements = []string{"temperature", "humidity"}
+	if !conf.ExposesMetric("humidity") || conf.ExposesMetric("pressure") {
+		t.Errorf("expected only %v to be exposed", conf.MetricMeasurements)
+	}
+}
+
+func TestConfig_SummaryQuantiles(t *testing.T) {
+	conf := DefaultConfig()
+	if got := conf.SummaryQuantiles(); !reflect.DeepEqual(got, []float64{0.5, 0.95}) {
+		t.Errorf("expected default quantiles, got %v", got)
+	}
+
+	conf.MetricQuantiles = []float64{0.99}
+	if got := conf.SummaryQuantiles(); !reflect.DeepEqual(got, []float64{0.99}) {
+		t.Errorf("expected configured quantiles, got %v", got)
+	}
+}
+
+func TestConfig_ResolvePlacement(t *testing.T) {
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Skipf("hostname not available: %v", err)
+	}
+
+	conf := DefaultConfig()
+	if err := conf.ResolvePlacement(); err != nil || conf.Placement != "" {
+		t.Errorf("expected placement to stay empty without fallback, got %q, %v", conf.Placement, err)
+	}
+
+	conf.PlacementFromHost = true
+	if err := conf.ResolvePlacement(); err != nil || conf.Placement != hostname {
+		t.Errorf("expected placement %q, got %q, %v", hostname, conf.Placement, err)
+	}
+
+	conf.Placement = "livingroom"
+	if err := conf.ResolvePlacement(); err != nil || conf.Placement != "livingroom" {
+		t.Errorf("expected explicit placement to be kept, got %q, %v", conf.Placement, err)
+	}
+}
+
+func TestSensorConfig_EffectiveSensorId(t *testing.T) {
+	conf := SensorConfig{GpioBus: 1, GpioAddress: 0x76}
+	derived := conf.EffectiveSensorId()
+	if len(derived) == 0 || derived != conf.EffectiveSensorId() {
+		t.Errorf("expected a stable derived sensor id, got %q", derived)
+	}
+
+	other := SensorConfig{GpioBus: 1, GpioAddress: 0x77}
+	if other.EffectiveSensorId() == derived {
+		t.Errorf("expected different addresses to derive different sensor ids")
+	}
+
+	conf.SensorId = "unit-42"
+	if got := conf.EffectiveSensorId(); got != "unit-42" {
+		t.Errorf("EffectiveSensorId() = %q, want %q", got, "unit-42")
+	}
+}
@@ -2,7 +2,7 @@ package config
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"reflect"
 	"strings"
 )
@@ -24,9 +24,9 @@ func PrintFields(data interface{}, ignoredKeys ...string) {
 		}
 
 		if sliceContains(ignoredKeys, field.Name) {
-			log.Printf("%s=%s", field.Name, "*** (redacted)")
+			slog.Debug("Config", "field", field.Name, "value", "*** (redacted)")
 		} else {
-			log.Printf("%s=%s", field.Name, fieldValueToString(field.Name, value))
+			slog.Debug("Config", "field", field.Name, "value", fieldValueToString(field.Name, value))
 		}
 	}
 }
@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+// defaultCsvColumns are written if no columns are configured.
+var defaultCsvColumns = []config.CsvColumn{
+	{Field: "timestamp", Header: "timestamp"},
+	{Field: "placement", Header: "placement"},
+	{Field: "temperature", Header: "temperature"},
+	{Field: "humidity", Header: "humidity"},
+	{Field: "pressure", Header: "pressure"},
+}
+
+// CsvSink appends a row for each measurement to a CSV file. Once the file exceeds the maximum size, it is moved to
+// a backup file with the suffix ".1" and a new file is started. To reduce the writes to SD cards, rows can be
+// buffered and written every flushEvery measurements.
+type CsvSink struct {
+	path         string
+	maxSizeBytes int64
+	flushEvery   int
+	columns      []config.CsvColumn
+
+	mu      sync.Mutex
+	pending [][]string
+}
+
+// NewCsvSink builds a sink writing the given columns in order, if no columns are given the default columns are written.
+func NewCsvSink(path string, maxSizeMb, flushEvery int, columns []config.CsvColumn) *CsvSink {
+	if len(columns) == 0 {
+		columns = defaultCsvColumns
+	}
+
+	return &CsvSink{
+		path:         path,
+		maxSizeBytes: int64(maxSizeMb) * 1024 * 1024,
+		flushEvery:   flushEvery,
+		columns:      columns,
+	}
+}
+
+// Write buffers the measurement and writes the buffered rows to the file once flushEvery rows are buffered.
+func (s *CsvSink) Write(m Measurement, placement string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row := make([]string, len(s.columns))
+	for i, column := range s.columns {
+		row[i] = csvField(m, placement, column.Field)
+	}
+	s.pending = append(s.pending, row)
+	if len(s.pending) < s.flushEvery {
+		return nil
+	}
+	return s.flush()
+}
+
+// Path returns the path of the csv file.
+func (s *CsvSink) Path() string {
+	return s.path
+}
+
+// Flush writes the buffered rows to the file. It must be called on shutdown, so no buffered rows are lost.
+func (s *CsvSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+// flush appends the buffered rows to the file, writing the header first if the file does not exist yet.
+func (s *CsvSink) flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	if err := s.rotate(); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("could not open csv file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("could not stat csv file: %w", err)
+	}
+
+	writer := csv.NewWriter(file)
+	if info.Size() == 0 {
+		_ = writer.Write(s.header())
+	}
+	_ = writer.WriteAll(s.pending)
+	if err := writer.Error(); err != nil {
+		return err
+	}
+	s.pending = s.pending[:0]
+	return nil
+}
+
+func (s *CsvSink) rotate() error {
+	if s.maxSizeBytes <= 0 {
+		return nil
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil || info.Size() < s.maxSizeBytes {
+		return nil
+	}
+
+	if err := os.Rename(s.path, s.path+".1"); err != nil {
+		return fmt.Errorf("could not rotate csv file: %w", err)
+	}
+	return nil
+}
+
+func (s *CsvSink) header() []string {
+	header := make([]string, len(s.columns))
+	for i, column := range s.columns {
+		header[i] = column.Header
+	}
+	return header
+}
+
+// csvField formats the field of the measurement for the column.
+func csvField(m Measurement, placement, field string) string {
+	switch field {
+	case "timestamp":
+		return strconv.FormatInt(m.Timestamp, 10)
+	case "placement":
+		return placement
+	case "temperature":
+		return csvValue(m, field, m.Temperature)
+	case "humidity":
+		return csvValue(m, field, m.Humidity)
+	case "pressure":
+		return csvValue(m, field, m.Pressure)
+	case "altitude":
+		return csvValue(m, field, m.Altitude)
+	}
+	return ""
+}
+
+// csvValue formats the value, leaving the column empty if reading the value failed.
+func csvValue(m Measurement, name string, value float32) string {
+	if m.Failed(name) {
+		return ""
+	}
+	return strconv.FormatFloat(float64(value), 'f', -1, 32)
+}
@@ -0,0 +1,107 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func TestCsvSink_Write(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.csv")
+	sink := NewCsvSink(path, 0, 1, nil)
+
+	m := Measurement{Timestamp: 1700000000, Temperature: 21.5, Humidity: 40, Pressure: 1013}
+	if err := sink.Write(m, "living_room"); err != nil {
+		t.Fatal(err)
+	}
+	m.AddHumidity(0, errors.New("humidity not available"))
+	if err := sink.Write(m, "living_room"); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "timestamp,placement,temperature,humidity,pressure\n" +
+		"1700000000,living_room,21.5,40,1013\n" +
+		"1700000000,living_room,21.5,,1013\n"
+	if string(content) != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+}
+
+func TestCsvSink_rotate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.csv")
+	sink := NewCsvSink(path, 1, 1, nil)
+	if err := os.WriteFile(path, make([]byte, 1024*1024), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := sink.Write(Measurement{Timestamp: 1}, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path + ".1"); err != nil {
+		t.Errorf("expected rotated file: %v", err)
+	}
+	content, _ := os.ReadFile(path)
+	if string(content) != "timestamp,placement,temperature,humidity,pressure\n1,test,0,0,0\n" {
+		t.Errorf("expected new file with header, got %q", content)
+	}
+}
+
+func TestCsvSink_Flush(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.csv")
+	sink := NewCsvSink(path, 0, 2, nil)
+
+	if err := sink.Write(Measurement{Timestamp: 1}, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("expected the row to be buffered, got %v", err)
+	}
+	if err := sink.Write(Measurement{Timestamp: 2}, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Write(Measurement{Timestamp: 3}, "test"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	content, _ := os.ReadFile(path)
+	want := "timestamp,placement,temperature,humidity,pressure\n1,test,0,0,0\n2,test,0,0,0\n3,test,0,0,0\n"
+	if string(content) != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+}
+
+func TestCsvSink_WriteColumns(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.csv")
+	columns := []config.CsvColumn{
+		{Field: "timestamp", Header: "Time"},
+		{Field: "placement", Header: "Room"},
+		{Field: "temperature", Header: "°C"},
+		{Field: "humidity", Header: "%RH"},
+		{Field: "altitude", Header: "m"},
+	}
+	sink := NewCsvSink(path, 0, 1, columns)
+
+	m := Measurement{Timestamp: 1700000000, Temperature: 21.5, Humidity: 40, Pressure: 1013, Altitude: 120}
+	if err := sink.Write(m, "living_room"); err != nil {
+		t.Fatal(err)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Time,Room,°C,%RH,m\n1700000000,living_room,21.5,40,120\n"
+	if string(content) != want {
+		t.Errorf("content = %q, want %q", content, want)
+	}
+}
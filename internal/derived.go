@@ -0,0 +1,72 @@
+package internal
+
+import "math"
+
+// seaLevelPressure reduces the station pressure to sea level (QNH) using the barometric formula, taking the
+// current temperature at the station into account.
+func seaLevelPressure(pressure, tempCelsius, altitudeMeters float64) float64 {
+	return pressure * math.Pow(1-(0.0065*altitudeMeters)/(tempCelsius+0.0065*altitudeMeters+273.15), -5.257)
+}
+
+// saturationVaporPressure calculates the saturation vapor pressure in hPa at the given temperature using the Magnus
+// formula.
+func saturationVaporPressure(tempCelsius float64) float64 {
+	return 6.112 * math.Exp(17.67*tempCelsius/(tempCelsius+243.5))
+}
+
+// absoluteHumidity calculates the absolute humidity in g/m³ from the temperature and the relative humidity.
+func absoluteHumidity(tempCelsius, relativeHumidity float64) float64 {
+	return saturationVaporPressure(tempCelsius) * relativeHumidity * 2.1674 / (273.15 + tempCelsius)
+}
+
+// dewPoint calculates the dew point in °C from the temperature and the relative humidity using the Magnus formula.
+func dewPoint(tempCelsius, relativeHumidity float64) float64 {
+	gamma := math.Log(relativeHumidity/100) + 17.67*tempCelsius/(tempCelsius+243.5)
+	return 243.5 * gamma / (17.67 - gamma)
+}
+
+// humidityDerived calculates the dew point and the absolute humidity. The relative humidity is clamped to (0, 100],
+// no values are returned for a relative humidity of 0 or below, as reported by some sensors while warming up, as the
+// dew point is undefined.
+func humidityDerived(tempCelsius, relativeHumidity float64) (dew, absolute float64, ok bool) {
+	if math.IsNaN(tempCelsius) || math.IsNaN(relativeHumidity) || relativeHumidity <= 0 {
+		return 0, 0, false
+	}
+	relativeHumidity = math.Min(100, relativeHumidity)
+	return dewPoint(tempCelsius, relativeHumidity), absoluteHumidity(tempCelsius, relativeHumidity), true
+}
+
+// specificHumidity calculates the mass of water vapor per mass of moist air in kg/kg from the temperature, the
+// relative humidity and the pressure in Pa.
+func specificHumidity(tempCelsius, relativeHumidity, pressure float64) float64 {
+	vaporPressure := relativeHumidity / 100 * saturationVaporPressure(tempCelsius)
+	pressureHpa := pressure / 100
+	return 0.622 * vaporPressure / (pressureHpa - 0.378*vaporPressure)
+}
+
+// roundTo rounds the value to the given amount of decimal places.
+func roundTo(value float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(value*factor) / factor
+}
+
+// clampHumidity limits the relative humidity to [0, 100], as the compensation may report values above 100% close
+// to condensation.
+func clampHumidity(humidity float32) float32 {
+	return float32(math.Min(100, math.Max(0, float64(humidity))))
+}
+
+// delta is the change of a single measured value since the previous reading.
+type delta struct {
+	name  string
+	value float64
+}
+
+// measurementDeltas returns the per-interval change of the measured values.
+func measurementDeltas(prev, cur Measurement) []delta {
+	return []delta{
+		{name: "temperature", value: float64(cur.Temperature - prev.Temperature)},
+		{name: "humidity", value: float64(cur.Humidity - prev.Humidity)},
+		{name: "pressure", value: float64(cur.Pressure - prev.Pressure)},
+	}
+}
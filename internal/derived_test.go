@@ -0,0 +1,137 @@
+package internal
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_seaLevelPressure(t *testing.T) {
+	tests := []struct {
+		name        string
+		pressure    float64
+		temperature float64
+		altitude    float64
+		want        float64
+	}{
+		{
+			name:        "sea level",
+			pressure:    101325,
+			temperature: 15,
+			altitude:    0,
+			want:        101325,
+		},
+		{
+			name:        "500m",
+			pressure:    95000,
+			temperature: 15,
+			altitude:    500,
+			want:        100768,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := seaLevelPressure(tt.pressure, tt.temperature, tt.altitude); math.Abs(got-tt.want) > 10 {
+				t.Errorf("seaLevelPressure() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_measurementDeltas(t *testing.T) {
+	prev := Measurement{Temperature: 21.5, Humidity: 50, Pressure: 100000}
+	cur := Measurement{Temperature: 20, Humidity: 52.5, Pressure: 100010}
+
+	want := map[string]float64{
+		"temperature": -1.5,
+		"humidity":    2.5,
+		"pressure":    10,
+	}
+	deltas := measurementDeltas(prev, cur)
+	if len(deltas) != len(want) {
+		t.Fatalf("measurementDeltas() returned %d deltas, want %d", len(deltas), len(want))
+	}
+	for _, d := range deltas {
+		if math.Abs(d.value-want[d.name]) > 0.001 {
+			t.Errorf("measurementDeltas() %s = %f, want %f", d.name, d.value, want[d.name])
+		}
+	}
+}
+
+func Test_clampHumidity(t *testing.T) {
+	tests := []struct {
+		humidity float32
+		want     float32
+	}{
+		{humidity: 55.5, want: 55.5},
+		{humidity: 101.2, want: 100},
+		{humidity: -0.5, want: 0},
+	}
+	for _, tt := range tests {
+		if got := clampHumidity(tt.humidity); got != tt.want {
+			t.Errorf("clampHumidity(%f) = %f, want %f", tt.humidity, got, tt.want)
+		}
+	}
+}
+
+func Test_absoluteHumidity(t *testing.T) {
+	if got := absoluteHumidity(20, 50); math.Abs(got-8.64) > 0.05 {
+		t.Errorf("absoluteHumidity() = %f, want %f", got, 8.64)
+	}
+	if got := absoluteHumidity(0, 100); math.Abs(got-4.85) > 0.05 {
+		t.Errorf("absoluteHumidity() = %f, want %f", got, 4.85)
+	}
+}
+
+func Test_dewPoint(t *testing.T) {
+	if got := dewPoint(20, 50); math.Abs(got-9.3) > 0.05 {
+		t.Errorf("dewPoint() = %f, want %f", got, 9.3)
+	}
+	if got := dewPoint(20, 100); math.Abs(got-20) > 0.01 {
+		t.Errorf("dewPoint() = %f, want %f", got, 20.0)
+	}
+}
+
+func Test_humidityDerived(t *testing.T) {
+	if _, _, ok := humidityDerived(20, 0); ok {
+		t.Error("expected no values for a relative humidity of 0")
+	}
+	if _, _, ok := humidityDerived(math.NaN(), 50); ok {
+		t.Error("expected no values for NaN")
+	}
+	dew, absolute, ok := humidityDerived(20, 104)
+	if !ok || math.Abs(dew-20) > 0.01 || math.Abs(absolute-absoluteHumidity(20, 100)) > 0.0001 {
+		t.Errorf("humidityDerived() = %f, %f, %t, want the values for 100%%", dew, absolute, ok)
+	}
+}
+
+func Test_specificHumidity(t *testing.T) {
+	if got := specificHumidity(20, 50, 101325); math.Abs(got-0.0072) > 0.0001 {
+		t.Errorf("specificHumidity() = %f, want %f", got, 0.0072)
+	}
+	if got := specificHumidity(20, 0, 101325); got != 0 {
+		t.Errorf("specificHumidity() = %f, want 0", got)
+	}
+}
+
+func Test_roundTo(t *testing.T) {
+	if got := roundTo(101325.4567, 2); got != 101325.46 {
+		t.Errorf("roundTo() = %f, want %f", got, 101325.46)
+	}
+	if got := roundTo(-0.125, 0); got != 0 {
+		t.Errorf("roundTo() = %f, want 0", got)
+	}
+}
+
+func Test_derivedValues_allocations(t *testing.T) {
+	m := NewMeasurement()
+	m.Temperature, m.Humidity, m.Pressure = 22, 50, 1013
+
+	allocs := testing.AllocsPerRun(100, func() {
+		m.AddSeaLevelPressure(500)
+		m.AddSpecificHumidity()
+		roundTo(float64(m.PressureSeaLevel), 2)
+	})
+	if allocs != 0 {
+		t.Errorf("expected computing the derived values to be allocation-free, got %v allocations", allocs)
+	}
+}
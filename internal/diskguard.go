@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// diskCheckInterval is the time the free disk space of a path is cached for, so not every write needs a syscall.
+const diskCheckInterval = 10 * time.Second
+
+// DiskGuard stops the file sinks from writing once the free space of the disk drops below a threshold, so a full disk
+// does not render the device unusable. Other sinks such as MQTT are not affected.
+type DiskGuard struct {
+	placement    string
+	minFreeBytes uint64
+	free         func(path string) (uint64, error)
+
+	mu     sync.Mutex
+	checks map[string]diskCheck
+}
+
+type diskCheck struct {
+	at  time.Time
+	low bool
+}
+
+func NewDiskGuard(placement string, minFreeMb int) *DiskGuard {
+	return &DiskGuard{
+		placement:    placement,
+		minFreeBytes: uint64(minFreeMb) * 1024 * 1024,
+		free:         freeDiskBytes,
+		checks:       map[string]diskCheck{},
+	}
+}
+
+// check returns whether the file at path may be written to and whether the state changed since the last check. A nil
+// guard allows all writes.
+func (g *DiskGuard) check(path, sink string) (allowed bool, changed bool) {
+	if g == nil || g.minFreeBytes == 0 {
+		return true, false
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := time.Now()
+	prev, found := g.checks[path]
+	current := prev
+	if !found || now.Sub(prev.at) >= diskCheckInterval {
+		free, err := g.free(filepath.Dir(path))
+		// if the free space can not be determined, writing is attempted anyway
+		current = diskCheck{at: now, low: err == nil && free < g.minFreeBytes}
+		g.checks[path] = current
+	}
+
+	if current.low {
+		metricDiskWritesSkipped.WithLabelValues(g.placement, sink).Inc()
+	}
+	return !current.low, current.low != prev.low
+}
+
+// Writer returns a writer that writes to w as long as the disk of path has enough free space, and to fallback
+// otherwise.
+func (g *DiskGuard) Writer(w io.Writer, path, sink string, fallback io.Writer) io.Writer {
+	return &diskGuardedWriter{guard: g, w: w, path: path, sink: sink, fallback: fallback}
+}
+
+type diskGuardedWriter struct {
+	guard    *DiskGuard
+	w        io.Writer
+	path     string
+	sink     string
+	fallback io.Writer
+}
+
+func (d *diskGuardedWriter) Write(p []byte) (int, error) {
+	allowed, changed := d.guard.check(d.path, d.sink)
+	if allowed {
+		return d.w.Write(p)
+	}
+
+	// this writer is used as output of the logger, so the warning can not be logged using the logger
+	if changed {
+		_, _ = fmt.Fprintf(d.fallback, "Free disk space of %s below threshold, not writing to %s\n", filepath.Dir(d.path), d.path)
+	}
+	return d.fallback.Write(p)
+}
+
+func freeDiskBytes(path string) (uint64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, err
+	}
+	return uint64(stat.Bavail) * uint64(stat.Bsize), nil
+}
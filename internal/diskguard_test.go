@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestDiskGuard_check(t *testing.T) {
+	guard := NewDiskGuard("test", 1)
+	var free uint64 = 2 * 1024 * 1024
+	guard.free = func(string) (uint64, error) { return free, nil }
+
+	if allowed, changed := guard.check("/data/readings.csv", "csv"); !allowed || changed {
+		t.Errorf("check() = %t, %t, want true, false", allowed, changed)
+	}
+
+	free = 1024
+	delete(guard.checks, "/data/readings.csv")
+	if allowed, changed := guard.check("/data/readings.csv", "csv"); allowed || !changed {
+		t.Errorf("check() = %t, %t, want false, true", allowed, changed)
+	}
+	if allowed, changed := guard.check("/data/readings.csv", "csv"); allowed || changed {
+		t.Errorf("expected cached result, got %t, %t", allowed, changed)
+	}
+}
+
+func TestDiskGuard_checkDisabled(t *testing.T) {
+	var guard *DiskGuard
+	if allowed, _ := guard.check("/data/readings.csv", "csv"); !allowed {
+		t.Error("expected nil guard to allow writes")
+	}
+
+	guard = NewDiskGuard("test", 0)
+	guard.free = func(string) (uint64, error) { return 0, nil }
+	if allowed, _ := guard.check("/data/readings.csv", "csv"); !allowed {
+		t.Error("expected guard without threshold to allow writes")
+	}
+}
+
+func TestDiskGuard_Writer(t *testing.T) {
+	guard := NewDiskGuard("test", 1)
+	guard.free = func(string) (uint64, error) { return 0, nil }
+
+	var file, fallback bytes.Buffer
+	w := guard.Writer(&file, "/var/log/bot.log", "log", &fallback)
+	_, _ = w.Write([]byte("first\n"))
+	_, _ = w.Write([]byte("second\n"))
+
+	if file.Len() != 0 {
+		t.Errorf("expected nothing to be written to the file, got %q", file.String())
+	}
+	if strings.Count(fallback.String(), "below threshold") != 1 || !strings.HasSuffix(fallback.String(), "first\nsecond\n") {
+		t.Errorf("unexpected fallback output %q", fallback.String())
+	}
+}
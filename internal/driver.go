@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+	"gobot.io/x/gobot/v2/drivers/i2c"
+)
+
+// oversamplingSettings maps the oversampling factors to the setting of the ctrl_meas and ctrl_hum registers, which
+// is the same for all channels.
+var oversamplingSettings = map[int]uint8{1: 0x01, 2: 0x02, 4: 0x03, 8: 0x04, 16: 0x05}
+
+// iirFilterSettings maps the coefficients of the IIR filter to the setting of the config register.
+var iirFilterSettings = map[int]i2c.BMP280IIRFilter{
+	2:  i2c.BMP280ConfFilter2,
+	4:  i2c.BMP280ConfFilter4,
+	8:  i2c.BMP280ConfFilter8,
+	16: i2c.BMP280ConfFilter16,
+}
+
+// DriverOptions returns the options of the driver for the sensor. Oversampling factors that are not configured are
+// left at the defaults of the driver, a missing IIR filter coefficient turns the filter off.
+func DriverOptions(conf config.SensorConfig) []func(i2c.Config) {
+	options := []func(i2c.Config){i2c.WithBus(conf.GpioBus), i2c.WithAddress(conf.GpioAddress)}
+	if setting, ok := oversamplingSettings[conf.TemperatureOversampling]; ok {
+		options = append(options, i2c.WithBME280TemperatureOversampling(i2c.BMP280TemperatureOversampling(setting)))
+	}
+	if setting, ok := oversamplingSettings[conf.PressureOversampling]; ok {
+		options = append(options, i2c.WithBME280PressureOversampling(i2c.BMP280PressureOversampling(setting)))
+	}
+	if setting, ok := oversamplingSettings[conf.HumidityOversampling]; ok {
+		options = append(options, i2c.WithBME280HumidityOversampling(i2c.BME280HumidityOversampling(setting)))
+	}
+	if setting, ok := iirFilterSettings[conf.IirFilter]; ok {
+		options = append(options, i2c.WithBME280IIRFilter(setting))
+	}
+	return options
+}
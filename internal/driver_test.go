@@ -0,0 +1,22 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func TestDriverOptions(t *testing.T) {
+	conf := config.DefaultConfig().SensorConfig
+	if got := len(DriverOptions(conf)); got != 2 {
+		t.Errorf("expected only the bus and the address by default, got %d options", got)
+	}
+
+	conf.TemperatureOversampling = 2
+	conf.PressureOversampling = 16
+	conf.HumidityOversampling = 1
+	conf.IirFilter = 4
+	if got := len(DriverOptions(conf)); got != 6 {
+		t.Errorf("expected an option per setting, got %d options", got)
+	}
+}
@@ -0,0 +1,73 @@
+package internal
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var metadataKeyPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// LoadMetadata reads the metadata of the sensor at the given bus and address from a CSV file. The first column of
+// the file identifies the sensor as "bus:address", e.g. "1:0x76", the names of the remaining columns are taken from
+// the header. If the file contains no entry for the sensor, no metadata is returned.
+func LoadMetadata(path string, bus, address int) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not open metadata file: %w", err)
+	}
+	defer file.Close()
+
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("could not parse metadata file: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	for _, key := range header[1:] {
+		if !metadataKeyPattern.MatchString(key) {
+			return nil, fmt.Errorf("invalid metadata key %q", key)
+		}
+	}
+
+	for _, record := range records[1:] {
+		recordBus, recordAddress, err := parseSensorLocation(record[0])
+		if err != nil {
+			return nil, err
+		}
+		if recordBus != bus || recordAddress != address {
+			continue
+		}
+
+		metadata := make(map[string]string, len(header)-1)
+		for i, key := range header[1:] {
+			metadata[key] = record[i+1]
+		}
+		return metadata, nil
+	}
+	return nil, nil
+}
+
+// parseSensorLocation parses a location of the form "bus:address", the address can be given in hex or decimal.
+func parseSensorLocation(location string) (int, int, error) {
+	parts := strings.SplitN(strings.TrimSpace(location), ":", 2)
+	if len(parts) != 2 {
+		return 0, 0, fmt.Errorf("invalid sensor location %q, expected bus:address", location)
+	}
+
+	bus, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid bus in sensor location %q", location)
+	}
+	address, err := strconv.ParseInt(parts[1], 0, 0)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid address in sensor location %q", location)
+	}
+	return bus, int(address), nil
+}
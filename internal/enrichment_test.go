@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestLoadMetadata(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sensors.csv")
+	content := "sensor,room,model,installed\n" +
+		"1:0x76,living_room,BME280,2023-05-01\n" +
+		"1:119,kitchen,BME280,2023-06-12\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		bus     int
+		address int
+		want    map[string]string
+	}{
+		{
+			name:    "hex address",
+			bus:     1,
+			address: 0x76,
+			want:    map[string]string{"room": "living_room", "model": "BME280", "installed": "2023-05-01"},
+		},
+		{
+			name:    "decimal address",
+			bus:     1,
+			address: 0x77,
+			want:    map[string]string{"room": "kitchen", "model": "BME280", "installed": "2023-06-12"},
+		},
+		{
+			name:    "unknown sensor",
+			bus:     0,
+			address: 0x76,
+			want:    nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadMetadata(path, tt.bus, tt.address)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadMetadata() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMetadata_invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+	}{
+		{
+			name:    "invalid key",
+			content: "sensor,install date\n1:0x76,2023-05-01\n",
+		},
+		{
+			name:    "invalid location",
+			content: "sensor,room\n0x76,kitchen\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "sensors.csv")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := LoadMetadata(path, 1, 0x76); err == nil {
+				t.Error("expected error")
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package internal
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// temperatureExtremes tracks the lowest and highest temperature of each placement since the last reset. It is shared
+// between the bots and the metrics server, which offers resetting it via HTTP.
+var temperatureExtremes = &extremesRegistry{trackers: map[string]*extremesTracker{}}
+
+type extremesRegistry struct {
+	mu       sync.Mutex
+	trackers map[string]*extremesTracker
+}
+
+// forPlacement returns the tracker of the extremes of the placement, creating it if needed.
+func (r *extremesRegistry) forPlacement(placement string) *extremesTracker {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tracker, ok := r.trackers[placement]
+	if !ok {
+		tracker = &extremesTracker{placement: placement}
+		r.trackers[placement] = tracker
+	}
+	return tracker
+}
+
+// reset resets the extremes of all placements.
+func (r *extremesRegistry) reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, tracker := range r.trackers {
+		tracker.reset()
+	}
+}
+
+type extremesTracker struct {
+	mu        sync.Mutex
+	placement string
+	min       float64
+	max       float64
+	valid     bool
+	resetAt   *time.Time
+	nextReset time.Time
+}
+
+// schedule sets the local time of day the extremes are reset at. A nil time disables the scheduled reset.
+func (e *extremesTracker) schedule(resetAt *time.Time, now time.Time) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.resetAt = resetAt
+	if resetAt != nil {
+		e.nextReset = nextTimeOfDay(*resetAt, now)
+	}
+}
+
+// record updates the extremes with the given value and returns the current extremes.
+func (e *extremesTracker) record(value float64, now time.Time) (min, max float64) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.resetAt != nil && !now.Before(e.nextReset) {
+		e.valid = false
+		e.nextReset = nextTimeOfDay(*e.resetAt, now)
+	}
+
+	if !e.valid || value < e.min {
+		e.min = value
+	}
+	if !e.valid || value > e.max {
+		e.max = value
+	}
+	e.valid = true
+	return e.min, e.max
+}
+
+func (e *extremesTracker) reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.valid = false
+	metricTemperatureMin.DeleteLabelValues(e.placement)
+	metricTemperatureMax.DeleteLabelValues(e.placement)
+}
+
+// nextTimeOfDay returns the next occurrence of the hour and minute of timeOfDay after now, in now's location.
+func nextTimeOfDay(timeOfDay time.Time, now time.Time) time.Time {
+	next := time.Date(now.Year(), now.Month(), now.Day(), timeOfDay.Hour(), timeOfDay.Minute(), 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next
+}
+
+func handleResetExtremes(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	slog.Info("Resetting recorded extremes")
+	temperatureExtremes.reset()
+	w.WriteHeader(http.StatusNoContent)
+}
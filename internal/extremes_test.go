@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestExtremesTracker_record(t *testing.T) {
+	midnight := time.Date(0, 1, 1, 0, 0, 0, 0, time.UTC)
+	now := time.Date(2023, 10, 1, 22, 0, 0, 0, time.UTC)
+
+	tracker := &extremesTracker{}
+	tracker.schedule(&midnight, now)
+
+	tracker.record(20, now)
+	tracker.record(25, now.Add(time.Hour))
+	min, max := tracker.record(18, now.Add(90*time.Minute))
+	if min != 18 || max != 25 {
+		t.Errorf("record() = %f, %f, want 18, 25", min, max)
+	}
+
+	min, max = tracker.record(21, now.Add(2*time.Hour))
+	if min != 21 || max != 21 {
+		t.Errorf("record() after scheduled reset = %f, %f, want 21, 21", min, max)
+	}
+}
+
+func TestHandleResetExtremes(t *testing.T) {
+	tracker := temperatureExtremes.forPlacement("test")
+	tracker.record(30, time.Now())
+
+	rec := httptest.NewRecorder()
+	handleResetExtremes(rec, httptest.NewRequest(http.MethodGet, "/reset-extremes", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("GET status = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+
+	rec = httptest.NewRecorder()
+	handleResetExtremes(rec, httptest.NewRequest(http.MethodPost, "/reset-extremes", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("POST status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+
+	if min, max := tracker.record(10, time.Now()); min != 10 || max != 10 {
+		t.Errorf("record() after reset = %f, %f, want 10, 10", min, max)
+	}
+}
@@ -0,0 +1,119 @@
+package internal
+
+import (
+	"sync"
+	"time"
+)
+
+const (
+	healthWindowSize = 10
+
+	// readings younger than this amount of intervals are considered fresh
+	healthFreshIntervals = 2
+	// readings older than this amount of intervals are considered entirely stale
+	healthStaleIntervals = 10
+
+	// healthUpdatePeriod is the period the health score is updated in independently of the readings, so it decays
+	// while the read loop hangs
+	healthUpdatePeriod = 10 * time.Second
+)
+
+// healthTracker summarizes the health of the sensor as a score in [0, 1]. The score is computed as
+//
+//	health = (1 - errorRatio) * freshness
+//
+// where errorRatio is the fraction of failed readings within the last healthWindowSize readings and freshness
+// is 1 as long as the last successful reading is at most healthFreshIntervals intervals old, decaying linearly
+// to 0 once it is healthStaleIntervals intervals old.
+type healthTracker struct {
+	mu          sync.Mutex
+	interval    time.Duration
+	window      []bool
+	pos         int
+	lastSuccess time.Time
+}
+
+func newHealthTracker(interval time.Duration) *healthTracker {
+	return &healthTracker{
+		interval:    interval,
+		window:      make([]bool, 0, healthWindowSize),
+		lastSuccess: time.Now(),
+	}
+}
+
+// setInterval changes the interval the freshness of the readings is measured in.
+func (h *healthTracker) setInterval(interval time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.interval = interval
+}
+
+// record adds the outcome of a reading and returns the updated health score.
+func (h *healthTracker) record(success bool, now time.Time) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.window) < healthWindowSize {
+		h.window = append(h.window, success)
+	} else {
+		h.window[h.pos] = success
+		h.pos = (h.pos + 1) % healthWindowSize
+	}
+
+	if success {
+		h.lastSuccess = now
+	}
+
+	return h.score(now)
+}
+
+// missedIntervals returns the amount of intervals since the last successful reading.
+func (h *healthTracker) missedIntervals(now time.Time) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.interval <= 0 {
+		return 0
+	}
+	return float64(now.Sub(h.lastSuccess)) / float64(h.interval)
+}
+
+// current returns the health score at the given time.
+func (h *healthTracker) current(now time.Time) float64 {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.score(now)
+}
+
+func (h *healthTracker) score(now time.Time) float64 {
+	if len(h.window) == 0 {
+		return 0
+	}
+
+	errors := 0
+	for _, success := range h.window {
+		if !success {
+			errors++
+		}
+	}
+	errorRatio := float64(errors) / float64(len(h.window))
+
+	return (1 - errorRatio) * h.freshness(now)
+}
+
+func (h *healthTracker) freshness(now time.Time) float64 {
+	if h.interval <= 0 {
+		return 1
+	}
+
+	age := float64(now.Sub(h.lastSuccess)) / float64(h.interval)
+	if age <= healthFreshIntervals {
+		return 1
+	}
+	if age >= healthStaleIntervals {
+		return 0
+	}
+	return 1 - (age-healthFreshIntervals)/(healthStaleIntervals-healthFreshIntervals)
+}
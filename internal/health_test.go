@@ -0,0 +1,67 @@
+package internal
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestHealthTracker_record(t *testing.T) {
+	interval := 30 * time.Second
+	start := time.Now()
+
+	tests := []struct {
+		name     string
+		outcomes []bool
+		want     float64
+	}{
+		{
+			name:     "all good",
+			outcomes: []bool{true, true, true},
+			want:     1,
+		},
+		{
+			name:     "single error",
+			outcomes: []bool{true, true, true, false},
+			want:     0.75,
+		},
+		{
+			name:     "errors rotated out of the window",
+			outcomes: []bool{false, false, true, true, true, true, true, true, true, true, true, true},
+			want:     1,
+		},
+		{
+			name:     "stale",
+			outcomes: []bool{true, false, false, false, false, false, false},
+			want:     (1 - 6.0/7.0) * (1 - 4.0/8.0),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := newHealthTracker(interval)
+			h.lastSuccess = start
+			var got float64
+			for i, success := range tt.outcomes {
+				got = h.record(success, start.Add(time.Duration(i)*interval))
+			}
+			if math.Abs(got-tt.want) > 0.0001 {
+				t.Errorf("record() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHealthTracker_current(t *testing.T) {
+	interval := 30 * time.Second
+	start := time.Now()
+	h := newHealthTracker(interval)
+	h.record(true, start)
+
+	// without further readings, the score decays with the age of the last successful reading
+	if got := h.current(start.Add(6 * interval)); math.Abs(got-0.5) > 0.0001 {
+		t.Errorf("current() = %f, want %f", got, 0.5)
+	}
+	if got := h.current(start.Add(healthStaleIntervals * interval)); got != 0 {
+		t.Errorf("current() = %f, want 0", got)
+	}
+}
@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"syscall"
+)
+
+const (
+	hintI2cPermission = "the user lacks permissions to access the I2C bus, add it to the 'i2c' group (sudo usermod -aG i2c $USER) or add a udev rule granting access to /dev/i2c-*"
+	hintI2cMissing    = "the I2C bus device does not exist, enable I2C (e.g. using raspi-config) and verify the configured bus"
+)
+
+// isFatalReadError returns whether the error indicates that the sensor is gone rather than a flaky bus. gobot does
+// not wrap the errno of failed ioctls, so the message is inspected as well.
+func isFatalReadError(err error) bool {
+	for _, errno := range []syscall.Errno{syscall.ENODEV, syscall.ENXIO} {
+		if errors.Is(err, errno) || strings.HasSuffix(err.Error(), errno.Error()) {
+			return true
+		}
+	}
+	return false
+}
+
+// StartupErrorHint returns a hint about the likely cause of common startup errors, or an empty string if there is
+// no hint for the error.
+func StartupErrorHint(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	msg := err.Error()
+	switch {
+	case errors.Is(err, os.ErrPermission) || strings.Contains(msg, "permission denied"):
+		return hintI2cPermission
+	case strings.Contains(msg, "/dev/i2c") && (errors.Is(err, os.ErrNotExist) || strings.Contains(msg, "no such file or directory")):
+		return hintI2cMissing
+	}
+	return ""
+}
@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestStartupErrorHint(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "permission denied",
+			err:  fmt.Errorf("could not start: %w", &os.PathError{Op: "open", Path: "/dev/i2c-1", Err: syscall.EACCES}),
+			want: hintI2cPermission,
+		},
+		{
+			name: "permission denied without error chain",
+			err:  errors.New("open /dev/i2c-1: permission denied"),
+			want: hintI2cPermission,
+		},
+		{
+			name: "missing bus",
+			err:  &os.PathError{Op: "open", Path: "/dev/i2c-1", Err: syscall.ENOENT},
+			want: hintI2cMissing,
+		},
+		{
+			name: "other error",
+			err:  errors.New("sensor not found"),
+			want: "",
+		},
+		{
+			name: "no error",
+			err:  nil,
+			want: "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := StartupErrorHint(tt.err); got != tt.want {
+				t.Errorf("StartupErrorHint() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_isFatalReadError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "wrapped errno",
+			err:  fmt.Errorf("read failed: %w", syscall.ENODEV),
+			want: true,
+		},
+		{
+			name: "formatted errno",
+			err:  fmt.Errorf("Read byte data failed with syscall.Errno %v", syscall.ENXIO),
+			want: true,
+		},
+		{
+			name: "transient errno",
+			err:  fmt.Errorf("Read byte data failed with syscall.Errno %v", syscall.EIO),
+			want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isFatalReadError(tt.err); got != tt.want {
+				t.Errorf("isFatalReadError() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"sync"
+
+	"gobot.io/x/gobot/v2"
+	"gobot.io/x/gobot/v2/drivers/i2c"
+	"gobot.io/x/gobot/v2/system"
+)
+
+// I2cAdaptor is a platform adaptor providing access to the i2c buses.
+type I2cAdaptor interface {
+	gobot.Connection
+	i2c.Connector
+}
+
+type i2cDevice interface {
+	gobot.I2cSystemDevicer
+	Close() error
+}
+
+// I2cDeviceAdaptor wraps a platform adaptor but accesses the i2c bus at an explicit device path instead of the
+// path derived from the bus number, for systems where the numbering of /dev/i2c-N differs.
+type I2cDeviceAdaptor struct {
+	I2cAdaptor
+	path   string
+	sys    *system.Accesser
+	mutex  sync.Mutex
+	device i2cDevice
+}
+
+func NewI2cDeviceAdaptor(adaptor I2cAdaptor, path string) *I2cDeviceAdaptor {
+	return &I2cDeviceAdaptor{
+		I2cAdaptor: adaptor,
+		path:       path,
+		sys:        system.NewAccesser(),
+	}
+}
+
+// GetI2cConnection returns a connection to the device at the given address on the bus at the configured path,
+// ignoring the bus number.
+func (a *I2cDeviceAdaptor) GetI2cConnection(address int, _ int) (i2c.Connection, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	if a.device == nil {
+		device, err := a.sys.NewI2cDevice(a.path)
+		if err != nil {
+			return nil, err
+		}
+		a.device = device
+	}
+	return i2c.NewConnection(a.device, address), nil
+}
+
+func (a *I2cDeviceAdaptor) Finalize() error {
+	a.mutex.Lock()
+	if a.device != nil {
+		_ = a.device.Close()
+		a.device = nil
+	}
+	a.mutex.Unlock()
+
+	return a.I2cAdaptor.Finalize()
+}
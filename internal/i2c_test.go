@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"testing"
+
+	"gobot.io/x/gobot/v2/drivers/i2c"
+)
+
+type fakeI2cAdaptor struct {
+	FakeMqttAdapter
+	requestedBus int
+	finalized    bool
+}
+
+func (a *fakeI2cAdaptor) GetI2cConnection(_ int, busNr int) (i2c.Connection, error) {
+	a.requestedBus = busNr
+	return nil, nil
+}
+
+func (a *fakeI2cAdaptor) DefaultI2cBus() int {
+	return 1
+}
+
+func (a *fakeI2cAdaptor) Finalize() error {
+	a.finalized = true
+	return nil
+}
+
+func TestI2cDeviceAdaptor(t *testing.T) {
+	platform := &fakeI2cAdaptor{requestedBus: -1}
+	adaptor := NewI2cDeviceAdaptor(platform, "/dev/i2c-20")
+
+	conn, err := adaptor.GetI2cConnection(0x76, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conn == nil {
+		t.Fatal("expected a connection")
+	}
+	if platform.requestedBus != -1 {
+		t.Errorf("expected the bus of the platform not to be used")
+	}
+
+	if err := adaptor.Finalize(); err != nil {
+		t.Fatal(err)
+	}
+	if !platform.finalized || adaptor.device != nil {
+		t.Errorf("expected the device to be closed and the platform adaptor to be finalized")
+	}
+}
@@ -0,0 +1,167 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+const (
+	influxWriteTimeout = 5 * time.Second
+	// maximum amount of points buffered while the endpoint is unreachable, older points are dropped
+	influxMaxPending = 10000
+)
+
+// influxTagEscaper escapes the characters with a special meaning in tag keys and values of the line protocol.
+var influxTagEscaper = strings.NewReplacer(",", `\,`, "=", `\=`, " ", `\ `)
+
+// InfluxSink writes measurements as line protocol to the write endpoint of InfluxDB v2. Points are buffered and
+// written once batchSize points are buffered or the flush interval has passed since the last write.
+type InfluxSink struct {
+	conf      config.InfluxConfig
+	precision string
+	client    *http.Client
+
+	mu        sync.Mutex
+	pending   []string
+	lastFlush time.Time
+}
+
+// NewInfluxSink builds a sink for the endpoint, the timestamps of the measurements are written in the given precision.
+func NewInfluxSink(conf config.InfluxConfig, timestampPrecision string) *InfluxSink {
+	precision := "s"
+	if timestampPrecision == config.TimestampPrecisionMillisecond {
+		precision = "ms"
+	}
+
+	return &InfluxSink{
+		conf:      conf,
+		precision: precision,
+		client:    &http.Client{Timeout: influxWriteTimeout},
+		lastFlush: time.Now(),
+	}
+}
+
+// Write buffers the measurement and writes the buffered points once the batch is full or the flush interval passed.
+func (s *InfluxSink) Write(m Measurement, placement string) error {
+	line, ok := influxLine(s.conf, m, placement)
+	if !ok {
+		return nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending = append(s.pending, line)
+	if len(s.pending) > influxMaxPending {
+		s.pending = s.pending[len(s.pending)-influxMaxPending:]
+	}
+	interval := time.Duration(s.conf.InfluxFlushIntervalSecs) * time.Second
+	if len(s.pending) < s.conf.InfluxBatchSize && time.Since(s.lastFlush) < interval {
+		return nil
+	}
+	return s.flush()
+}
+
+// Flush writes the buffered points. It must be called on shutdown, so no buffered points are lost.
+func (s *InfluxSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.flush()
+}
+
+func (s *InfluxSink) flush() error {
+	s.lastFlush = time.Now()
+	if len(s.pending) == 0 {
+		return nil
+	}
+
+	endpoint, err := url.Parse(strings.TrimSuffix(s.conf.InfluxUrl, "/") + "/api/v2/write")
+	if err != nil {
+		return err
+	}
+	query := endpoint.Query()
+	query.Set("org", s.conf.InfluxOrg)
+	query.Set("bucket", s.conf.InfluxBucket)
+	query.Set("precision", s.precision)
+	endpoint.RawQuery = query.Encode()
+
+	body := strings.Join(s.pending, "\n") + "\n"
+	req, err := http.NewRequest(http.MethodPost, endpoint.String(), bytes.NewBufferString(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Token "+s.conf.InfluxToken)
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// points are dropped if the endpoint rejects them, retrying malformed points would block all further writes
+	points := len(s.pending)
+	s.pending = s.pending[:0]
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("influx endpoint returned status %d, dropped %d points", resp.StatusCode, points)
+	}
+	return nil
+}
+
+// influxLine formats the measurement as line protocol with the placement and the static tags, omitting the values that
+// could not be read. It returns false if none of the values could be read.
+func influxLine(conf config.InfluxConfig, m Measurement, placement string) (string, bool) {
+	var fields []string
+	for _, v := range []struct {
+		name  string
+		value float32
+	}{
+		{"temperature", m.Temperature},
+		{"humidity", m.Humidity},
+		{"pressure", m.Pressure},
+	} {
+		if m.Failed(v.name) {
+			continue
+		}
+		fields = append(fields, v.name+"="+influxField(float64(v.value), conf.InfluxFieldPrecision, v.name))
+	}
+	if len(fields) == 0 {
+		return "", false
+	}
+
+	tags := conf.InfluxTags
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	series := influxTagEscaper.Replace(conf.InfluxMeasurement) + ",placement=" + influxTagEscaper.Replace(placement)
+	for _, key := range keys {
+		series += "," + influxTagEscaper.Replace(key) + "=" + influxTagEscaper.Replace(tags[key])
+	}
+
+	return fmt.Sprintf("%s %s %d", series, strings.Join(fields, ","), m.Timestamp), true
+}
+
+// influxField formats the value of the field with its configured precision, so the type of the field does not change
+// between points. Fields with a precision of 0 are written as integers, fields without a precision as floats.
+func influxField(value float64, precision map[string]int, name string) string {
+	places, ok := precision[name]
+	if !ok {
+		return strconv.FormatFloat(value, 'f', -1, 32)
+	}
+	if places == 0 {
+		return strconv.FormatInt(int64(math.Round(value)), 10) + "i"
+	}
+	return strconv.FormatFloat(value, 'f', places, 64)
+}
@@ -0,0 +1,151 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func Test_influxLine(t *testing.T) {
+	m := Measurement{Timestamp: 1700000000, Temperature: 21.5, Humidity: 40, Pressure: 1013.25}
+	got, ok := influxLine(config.InfluxConfig{InfluxMeasurement: "bme280"}, m, "living room")
+	want := `bme280,placement=living\ room temperature=21.5,humidity=40,pressure=1013.25 1700000000`
+	if !ok || got != want {
+		t.Errorf("influxLine() = %q, %t, want %q", got, ok, want)
+	}
+
+	m.AddHumidity(0, errors.New("humidity not available"))
+	got, _ = influxLine(config.InfluxConfig{InfluxMeasurement: "bme280"}, m, "attic")
+	want = "bme280,placement=attic temperature=21.5,pressure=1013.25 1700000000"
+	if got != want {
+		t.Errorf("influxLine() with failed humidity = %q, want %q", got, want)
+	}
+
+	if _, ok := influxLine(config.InfluxConfig{InfluxMeasurement: "bme280"}, placeholderMeasurement(), "attic"); ok {
+		t.Errorf("influxLine() expected no line for a failed measurement")
+	}
+}
+
+func Test_influxLine_tags(t *testing.T) {
+	m := Measurement{Timestamp: 1700000000, Temperature: 21.5, Humidity: 40, Pressure: 1013.25}
+	conf := config.InfluxConfig{
+		InfluxMeasurement: "bme280",
+		InfluxTags:        map[string]string{"site": "home", "floor": "first floor", "building": "a,b"},
+	}
+	got, _ := influxLine(conf, m, "attic")
+	want := `bme280,placement=attic,building=a\,b,floor=first\ floor,site=home temperature=21.5,humidity=40,pressure=1013.25 1700000000`
+	if got != want {
+		t.Errorf("influxLine() = %q, want %q", got, want)
+	}
+}
+
+func Test_influxLine_precision(t *testing.T) {
+	m := Measurement{Timestamp: 1700000000, Temperature: 21.5, Humidity: 40.25, Pressure: 101325.4}
+	conf := config.InfluxConfig{
+		InfluxMeasurement:    "bme280",
+		InfluxFieldPrecision: map[string]int{"temperature": 2, "pressure": 0},
+	}
+	got, _ := influxLine(conf, m, "attic")
+	want := "bme280,placement=attic temperature=21.50,humidity=40.25,pressure=101325i 1700000000"
+	if got != want {
+		t.Errorf("influxLine() = %q, want %q", got, want)
+	}
+}
+
+func TestInfluxSink_Write(t *testing.T) {
+	var requests []*http.Request
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink := NewInfluxSink(config.InfluxConfig{
+		InfluxUrl:               server.URL,
+		InfluxOrg:               "home",
+		InfluxBucket:            "sensors",
+		InfluxToken:             "secret",
+		InfluxMeasurement:       "bme280",
+		InfluxBatchSize:         2,
+		InfluxFlushIntervalSecs: 3600,
+	}, config.TimestampPrecisionSecond)
+
+	if err := sink.Write(Measurement{Timestamp: 1, Temperature: 20}, "attic"); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 0 {
+		t.Fatalf("expected the point to be buffered, got %d requests", len(requests))
+	}
+	if err := sink.Write(Measurement{Timestamp: 2, Temperature: 21}, "attic"); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 1 {
+		t.Fatalf("expected the batch to be written, got %d requests", len(requests))
+	}
+
+	req := requests[0]
+	if req.URL.Path != "/api/v2/write" {
+		t.Errorf("path = %q, want /api/v2/write", req.URL.Path)
+	}
+	query := req.URL.Query()
+	if query.Get("org") != "home" || query.Get("bucket") != "sensors" || query.Get("precision") != "s" {
+		t.Errorf("unexpected query %q", req.URL.RawQuery)
+	}
+	if req.Header.Get("Authorization") != "Token secret" {
+		t.Errorf("unexpected authorization %q", req.Header.Get("Authorization"))
+	}
+	want := "bme280,placement=attic temperature=20,humidity=0,pressure=0 1\n" +
+		"bme280,placement=attic temperature=21,humidity=0,pressure=0 2\n"
+	if bodies[0] != want {
+		t.Errorf("body = %q, want %q", bodies[0], want)
+	}
+
+	if err := sink.Write(Measurement{Timestamp: 3}, "attic"); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(requests) != 2 {
+		t.Errorf("expected the buffered point to be flushed, got %d requests", len(requests))
+	}
+}
+
+func TestInfluxSink_flushInterval(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	sink := NewInfluxSink(config.InfluxConfig{InfluxUrl: server.URL, InfluxMeasurement: "bme280", InfluxBatchSize: 100}, config.TimestampPrecisionMillisecond)
+	if err := sink.Write(Measurement{Timestamp: 1}, "attic"); err != nil {
+		t.Fatal(err)
+	}
+	if requests != 1 {
+		t.Errorf("expected the point to be written once the flush interval passed, got %d requests", requests)
+	}
+}
+
+func TestInfluxSink_errorStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	sink := NewInfluxSink(config.InfluxConfig{InfluxUrl: server.URL, InfluxMeasurement: "bme280"}, config.TimestampPrecisionSecond)
+	if err := sink.Write(Measurement{Timestamp: 1}, "attic"); err == nil {
+		t.Errorf("Write() expected error for status 400")
+	}
+	if len(sink.pending) != 0 {
+		t.Errorf("expected rejected points to be dropped, %d pending", len(sink.pending))
+	}
+}
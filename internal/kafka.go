@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"github.com/segmentio/kafka-go"
+	"github.com/segmentio/kafka-go/sasl"
+	"github.com/segmentio/kafka-go/sasl/plain"
+	"github.com/segmentio/kafka-go/sasl/scram"
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+const kafkaWriteTimeout = 5 * time.Second
+
+// KafkaSink produces a message for each measurement keyed by the placement. Messages are produced asynchronously,
+// so an unavailable cluster does not block the readings.
+type KafkaSink struct {
+	writer    *kafka.Writer
+	placement string
+}
+
+func NewKafkaSink(conf config.KafkaConfig, placement string) (*KafkaSink, error) {
+	transport := &kafka.Transport{}
+	if conf.KafkaTls {
+		transport.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	}
+	if len(conf.KafkaSaslMechanism) > 0 {
+		mechanism, err := kafkaSaslMechanism(conf)
+		if err != nil {
+			return nil, err
+		}
+		transport.SASL = mechanism
+	}
+
+	sink := &KafkaSink{placement: placement}
+	sink.writer = &kafka.Writer{
+		Addr:         kafka.TCP(conf.KafkaBrokers...),
+		Topic:        conf.KafkaTopic,
+		Balancer:     &kafka.Hash{},
+		Async:        true,
+		WriteTimeout: kafkaWriteTimeout,
+		Transport:    transport,
+		Completion:   sink.completed,
+	}
+	return sink, nil
+}
+
+func kafkaSaslMechanism(conf config.KafkaConfig) (sasl.Mechanism, error) {
+	switch conf.KafkaSaslMechanism {
+	case "plain":
+		return plain.Mechanism{Username: conf.KafkaUsername, Password: conf.KafkaPassword}, nil
+	case "scram-sha-256":
+		return scram.Mechanism(scram.SHA256, conf.KafkaUsername, conf.KafkaPassword)
+	case "scram-sha-512":
+		return scram.Mechanism(scram.SHA512, conf.KafkaUsername, conf.KafkaPassword)
+	}
+	return nil, fmt.Errorf("unknown sasl mechanism %q", conf.KafkaSaslMechanism)
+}
+
+// Publish hands the message to the producer without waiting for it to be written.
+func (s *KafkaSink) Publish(msg []byte) error {
+	return s.writer.WriteMessages(context.Background(), kafka.Message{
+		Key:   []byte(s.placement),
+		Value: msg,
+	})
+}
+
+func (s *KafkaSink) completed(messages []kafka.Message, err error) {
+	if err != nil {
+		metricsKafkaPublishErrors.WithLabelValues(s.placement).Add(float64(len(messages)))
+		return
+	}
+	metricsKafkaPublished.WithLabelValues(s.placement).Add(float64(len(messages)))
+}
+
+func (s *KafkaSink) Close() error {
+	return s.writer.Close()
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/segmentio/kafka-go"
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func Test_kafkaSaslMechanism(t *testing.T) {
+	tests := []struct {
+		mechanism string
+		want      string
+	}{
+		{mechanism: "plain", want: "PLAIN"},
+		{mechanism: "scram-sha-256", want: "SCRAM-SHA-256"},
+		{mechanism: "scram-sha-512", want: "SCRAM-SHA-512"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mechanism, func(t *testing.T) {
+			conf := config.KafkaConfig{KafkaSaslMechanism: tt.mechanism, KafkaUsername: "user", KafkaPassword: "secret"}
+			got, err := kafkaSaslMechanism(conf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Name() != tt.want {
+				t.Errorf("kafkaSaslMechanism() = %s, want %s", got.Name(), tt.want)
+			}
+		})
+	}
+}
+
+func TestKafkaSink_completed(t *testing.T) {
+	sink, err := NewKafkaSink(config.KafkaConfig{KafkaBrokers: []string{"localhost:9092"}, KafkaTopic: "readings"}, "kafka_test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sink.completed([]kafka.Message{{}, {}}, nil)
+	sink.completed([]kafka.Message{{}}, errors.New("broker not available"))
+	if got := testutil.ToFloat64(metricsKafkaPublished.WithLabelValues("kafka_test")); got != 2 {
+		t.Errorf("expected 2 published messages, got %f", got)
+	}
+	if got := testutil.ToFloat64(metricsKafkaPublishErrors.WithLabelValues("kafka_test")); got != 1 {
+		t.Errorf("expected 1 publish error, got %f", got)
+	}
+}
@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"io"
+	"log/slog"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+// NewLogHandler builds the handler writing records of at least the given level to w, either as logfmt or as json.
+func NewLogHandler(w io.Writer, level, format string) slog.Handler {
+	opts := &slog.HandlerOptions{Level: logLevel(level)}
+	if format == config.LogFormatJson {
+		return slog.NewJSONHandler(w, opts)
+	}
+	return slog.NewTextHandler(w, opts)
+}
+
+// logLevel parses the name of the level, defaulting to info.
+func logLevel(level string) slog.Level {
+	switch level {
+	case config.LogLevelDebug:
+		return slog.LevelDebug
+	case config.LogLevelWarn:
+		return slog.LevelWarn
+	case config.LogLevelError:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func TestNewLogHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		level  string
+		format string
+		want   []string
+	}{
+		{name: "default", level: "", format: "", want: []string{"level=INFO msg=info", "level=WARN msg=warn", "level=ERROR msg=error"}},
+		{name: "debug", level: config.LogLevelDebug, format: config.LogFormatText, want: []string{"msg=debug", "msg=info", "msg=warn", "msg=error"}},
+		{name: "warn", level: config.LogLevelWarn, format: config.LogFormatText, want: []string{"msg=warn", "msg=error"}},
+		{name: "error", level: config.LogLevelError, format: config.LogFormatText, want: []string{"msg=error"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewLogHandler(&buf, tt.level, tt.format))
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.Warn("warn")
+			logger.Error("error")
+
+			lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+			if len(lines) != len(tt.want) {
+				t.Fatalf("got %d records, want %d: %v", len(lines), len(tt.want), lines)
+			}
+			for i, want := range tt.want {
+				if !strings.Contains(lines[i], want) {
+					t.Errorf("record %q does not contain %q", lines[i], want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewLogHandler_json(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewLogHandler(&buf, config.LogLevelInfo, config.LogFormatJson))
+	logger.Info("Read sensor", "placement", "attic")
+
+	record := map[string]interface{}{}
+	if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+		t.Fatalf("expected a json record, got %q: %v", buf.String(), err)
+	}
+	if record["msg"] != "Read sensor" || record["placement"] != "attic" || record["level"] != "INFO" {
+		t.Errorf("unexpected record %v", record)
+	}
+}
@@ -1,18 +1,37 @@
 package internal
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"encoding/json"
-	"log"
+	"fmt"
+	"log/slog"
+	"math"
 	"time"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
 )
 
+const errNoReadingYet = "no reading yet"
+
 type Measurement struct {
-	Altitude    float32  `json:"alt"`
-	Humidity    float32  `json:"humidity"`
-	Pressure    float32  `json:"pressure"`
-	Temperature float32  `json:"temp"`
-	Timestamp   int64    `json:"timestamp"`
-	Errors      []string `json:"errors,omitempty"`
+	Altitude         float32           `json:"alt"`
+	Humidity         float32           `json:"humidity"`
+	Pressure         float32           `json:"pressure"`
+	PressureSeaLevel float32           `json:"pressure_sealevel,omitempty"`
+	Temperature      float32           `json:"temp"`
+	SpecificHumidity float32           `json:"specific_humidity,omitempty"`
+	Voltage          float32           `json:"voltage,omitempty"`
+	Timestamp        int64             `json:"timestamp"`
+	CorrelationId    string            `json:"correlation_id,omitempty"`
+	Errors           []string          `json:"errors,omitempty"`
+	Metadata         map[string]string `json:"metadata,omitempty"`
+
+	failed []string
+	// rawTemperature is the temperature as read from the sensor, before the self-heating compensation
+	rawTemperature float32
+	// unsupported are the values the sensor can not measure, they are treated as failed without being an error
+	unsupported []string
 }
 
 func NewMeasurement() Measurement {
@@ -26,10 +45,18 @@ func NewMeasurement() Measurement {
 	}
 }
 
+// placeholderMeasurement returns a measurement that marks all values as not read yet.
+func placeholderMeasurement() Measurement {
+	m := NewMeasurement()
+	m.Errors = []string{errNoReadingYet}
+	m.failed = []string{"altitude", "humidity", "pressure", "temperature"}
+	return m
+}
+
 func (m Measurement) AsJson() ([]byte, error) {
 	msg, err := json.Marshal(m)
 	if err != nil {
-		log.Println("Error marshalling json")
+		slog.Error("Could not marshal measurement", "err", err)
 	}
 	return msg, err
 }
@@ -40,7 +67,8 @@ func (m *Measurement) AddAltitude(alt float32, err error) {
 			m.Errors = make([]string, 4)
 		}
 		m.Errors = append(m.Errors, err.Error())
-		log.Printf("Error occurred while reading altitude from sensor: %v", err)
+		m.failed = append(m.failed, "altitude")
+		m.logger().Warn("Could not read altitude from sensor", "err", err)
 	} else {
 		m.Altitude = alt
 	}
@@ -52,7 +80,8 @@ func (m *Measurement) AddHumidity(hum float32, err error) {
 			m.Errors = make([]string, 4)
 		}
 		m.Errors = append(m.Errors, err.Error())
-		log.Printf("Error while reading humidity from sensor: %v", err)
+		m.failed = append(m.failed, "humidity")
+		m.logger().Warn("Could not read humidity from sensor", "err", err)
 	} else {
 		m.Humidity = hum
 	}
@@ -64,7 +93,8 @@ func (m *Measurement) AddPressure(pressure float32, err error) {
 			m.Errors = make([]string, 4)
 		}
 		m.Errors = append(m.Errors, err.Error())
-		log.Printf("Error while reading pressure from sensor: %v", err)
+		m.failed = append(m.failed, "pressure")
+		m.logger().Warn("Could not read pressure from sensor", "err", err)
 	} else {
 		m.Pressure = pressure
 	}
@@ -76,8 +106,168 @@ func (m *Measurement) AddTemperature(temp float32, err error) {
 			m.Errors = make([]string, 4)
 		}
 		m.Errors = append(m.Errors, err.Error())
-		log.Printf("Error while reading temperature from sensor: %v", err)
+		m.failed = append(m.failed, "temperature")
+		m.logger().Warn("Could not read temperature from sensor", "err", err)
 	} else {
 		m.Temperature = temp
 	}
 }
+
+// AddVoltage adds the voltage of an external source. Unlike the values of the sensor, a failure to read the voltage
+// is only logged and does not mark the measurement as erroneous.
+func (m *Measurement) AddVoltage(voltage float64, err error) {
+	if err != nil {
+		m.logger().Warn("Could not read voltage", "err", err)
+		return
+	}
+	m.Voltage = float32(voltage)
+}
+
+// InTemperatureUnit returns the measurement with the temperature converted from degrees celsius to the given unit.
+func (m Measurement) InTemperatureUnit(unit string) Measurement {
+	if !m.Failed("temperature") {
+		m.Temperature = float32(toTemperatureUnit(float64(m.Temperature), unit))
+	}
+	return m
+}
+
+// Scaled returns a copy of the measurement with the linear transform value*scale+offset applied to the values that
+// have a scale or an offset configured. Values that could not be read are left untouched. The sea level pressure is
+// transformed like the pressure.
+func (m Measurement) Scaled(scales, offsets map[string]float64) Measurement {
+	if len(scales) == 0 && len(offsets) == 0 {
+		return m
+	}
+
+	transform := func(name string, value float32) float32 {
+		scale, ok := scales[name]
+		if !ok {
+			scale = 1
+		}
+		return float32(float64(value)*scale + offsets[name])
+	}
+	if !m.Failed("altitude") {
+		m.Altitude = transform("altitude", m.Altitude)
+	}
+	if !m.Failed("humidity") {
+		m.Humidity = transform("humidity", m.Humidity)
+	}
+	if !m.Failed("pressure") {
+		m.Pressure = transform("pressure", m.Pressure)
+	}
+	if m.PressureSeaLevel > 0 {
+		m.PressureSeaLevel = transform("pressure", m.PressureSeaLevel)
+	}
+	if !m.Failed("temperature") {
+		m.Temperature = transform("temperature", m.Temperature)
+	}
+	return m
+}
+
+// newCorrelationId returns a short random id to match a reading across the published messages and the log lines.
+func newCorrelationId() string {
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(id)
+}
+
+// logger returns the default logger, annotated with the correlation id if it's set.
+func (m *Measurement) logger() *slog.Logger {
+	if len(m.CorrelationId) == 0 {
+		return slog.Default()
+	}
+	return slog.Default().With("correlation_id", m.CorrelationId)
+}
+
+// Rounded returns a copy of the measurement with the values rounded to the given amount of decimal places, so the
+// published values don't carry the noise of the arithmetics. Values that could not be read are left untouched.
+func (m Measurement) Rounded(places int) Measurement {
+	round := func(value float32) float32 {
+		return float32(roundTo(float64(value), places))
+	}
+	if !m.Failed("altitude") {
+		m.Altitude = round(m.Altitude)
+	}
+	if !m.Failed("humidity") {
+		m.Humidity = round(m.Humidity)
+	}
+	if !m.Failed("pressure") {
+		m.Pressure = round(m.Pressure)
+	}
+	if !m.Failed("temperature") {
+		m.Temperature = round(m.Temperature)
+	}
+	return m
+}
+
+// Failed returns whether the given value could not be read.
+func (m *Measurement) Failed(name string) bool {
+	for _, failed := range m.failed {
+		if failed == name {
+			return true
+		}
+	}
+	for _, unsupported := range m.unsupported {
+		if unsupported == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *Measurement) AddSeaLevelPressure(altitudeMeters float64) {
+	m.PressureSeaLevel = float32(seaLevelPressure(float64(m.Pressure), float64(m.Temperature), altitudeMeters))
+}
+
+func (m *Measurement) AddSpecificHumidity() {
+	m.SpecificHumidity = float32(specificHumidity(float64(m.Temperature), float64(m.Humidity), float64(m.Pressure)))
+}
+
+// RemoveNonFinite replaces NaN and infinite values, which can not be encoded as JSON, the same way as values that
+// could not be read and returns the names of the affected values.
+func (m *Measurement) RemoveNonFinite() []string {
+	values := []struct {
+		name  string
+		value *float32
+		unset float32
+	}{
+		{name: "altitude", value: &m.Altitude, unset: -1},
+		{name: "humidity", value: &m.Humidity, unset: -1},
+		{name: "pressure", value: &m.Pressure, unset: -1},
+		{name: "pressure_sealevel", value: &m.PressureSeaLevel, unset: 0},
+		{name: "specific_humidity", value: &m.SpecificHumidity, unset: 0},
+		{name: "temperature", value: &m.Temperature, unset: -1},
+		{name: "voltage", value: &m.Voltage, unset: 0},
+	}
+
+	var affected []string
+	for _, v := range values {
+		f := float64(*v.value)
+		if !math.IsNaN(f) && !math.IsInf(f, 0) {
+			continue
+		}
+
+		m.logger().Warn("Not publishing non-finite value", "measurement", v.name, "value", f)
+		*v.value = v.unset
+		m.Errors = append(m.Errors, fmt.Sprintf("%s is not a finite number", v.name))
+		m.failed = append(m.failed, v.name)
+		affected = append(affected, v.name)
+	}
+	return affected
+}
+
+// formatTimestamp converts the time to a unix timestamp of the given precision. Interval precision truncates the
+// time to the start of the interval, in seconds.
+func formatTimestamp(t time.Time, precision string, interval time.Duration) int64 {
+	switch precision {
+	case config.TimestampPrecisionMillisecond:
+		return t.UnixNano() / int64(time.Millisecond)
+	case config.TimestampPrecisionInterval:
+		if interval > 0 {
+			return t.Truncate(interval).Unix()
+		}
+	}
+	return t.Unix()
+}
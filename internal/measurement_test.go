@@ -0,0 +1,115 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func Test_formatTimestamp(t *testing.T) {
+	ts := time.Date(2023, 10, 1, 12, 0, 47, 500*int(time.Millisecond), time.UTC)
+	tests := []struct {
+		name      string
+		precision string
+		interval  time.Duration
+		want      int64
+	}{
+		{
+			name:      "second",
+			precision: config.TimestampPrecisionSecond,
+			want:      1696161647,
+		},
+		{
+			name:      "default",
+			precision: "",
+			want:      1696161647,
+		},
+		{
+			name:      "millisecond",
+			precision: config.TimestampPrecisionMillisecond,
+			want:      1696161647500,
+		},
+		{
+			name:      "interval",
+			precision: config.TimestampPrecisionInterval,
+			interval:  30 * time.Second,
+			want:      1696161630,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatTimestamp(ts, tt.precision, tt.interval); got != tt.want {
+				t.Errorf("formatTimestamp() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMeasurement_RemoveNonFinite(t *testing.T) {
+	m := NewMeasurement()
+	m.Temperature = 21.5
+	m.Humidity = float32(math.NaN())
+	m.PressureSeaLevel = float32(math.Inf(1))
+
+	affected := m.RemoveNonFinite()
+	if want := []string{"humidity", "pressure_sealevel"}; !reflect.DeepEqual(affected, want) {
+		t.Errorf("RemoveNonFinite() = %v, want %v", affected, want)
+	}
+	if m.Humidity != -1 || m.PressureSeaLevel != 0 || m.Temperature != 21.5 {
+		t.Errorf("RemoveNonFinite() left %+v", m)
+	}
+	if len(m.Errors) != 2 {
+		t.Errorf("expected 2 errors, got %v", m.Errors)
+	}
+	if _, err := m.AsJson(); err != nil {
+		t.Errorf("AsJson() error = %v", err)
+	}
+}
+
+func TestMeasurement_Scaled(t *testing.T) {
+	m := NewMeasurement()
+	m.Temperature = 20
+	m.Pressure = 1000
+	m.PressureSeaLevel = 1010
+	m.AddHumidity(0, errors.New("humidity not available"))
+
+	got := m.Scaled(map[string]float64{"pressure": 0.1}, map[string]float64{"temperature": 273.15, "humidity": 1})
+	if got.Temperature != float32(293.15) {
+		t.Errorf("temperature = %f, want 293.15", got.Temperature)
+	}
+	if got.Pressure != 100 || got.PressureSeaLevel != 101 {
+		t.Errorf("pressure = %f and %f, want 100 and 101", got.Pressure, got.PressureSeaLevel)
+	}
+	if got.Humidity != -1 {
+		t.Errorf("expected the failed humidity to be left untouched, got %f", got.Humidity)
+	}
+	if got.Altitude != m.Altitude {
+		t.Errorf("expected the altitude without transform to be left untouched, got %f", got.Altitude)
+	}
+	if m.Temperature != 20 {
+		t.Errorf("expected the original measurement to be left untouched, got %f", m.Temperature)
+	}
+}
+
+func TestMeasurement_Rounded(t *testing.T) {
+	m := NewMeasurement()
+	m.Temperature = 21.299999
+	m.Pressure = 101325.4567
+	m.AddHumidity(0, errors.New("humidity not available"))
+
+	got := m.Rounded(1)
+	msg, err := got.AsJson()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := fmt.Sprintf(`"alt":-1,"humidity":-1,"pressure":101325.5,"temp":21.3,"timestamp":%d`, m.Timestamp)
+	if !strings.Contains(string(msg), want) {
+		t.Errorf("json = %s, want it to contain %s", msg, want)
+	}
+}
@@ -0,0 +1,87 @@
+package internal
+
+import (
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// metricPrefixes holds the prefixes that replace the namespace in the names of the metrics of a placement.
+var metricPrefixes = struct {
+	mu       sync.RWMutex
+	prefixes map[string]string
+}{prefixes: map[string]string{}}
+
+// useMetricPrefix exposes the metrics of the placement with the given prefix instead of the namespace.
+func useMetricPrefix(placement, prefix string) {
+	metricPrefixes.mu.Lock()
+	defer metricPrefixes.mu.Unlock()
+
+	metricPrefixes.prefixes[placement] = prefix
+}
+
+// metricsGatherer gathers the metrics of the default registry with the metric prefixes of the placements applied.
+var metricsGatherer prometheus.Gatherer = prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	metricPrefixes.mu.RLock()
+	defer metricPrefixes.mu.RUnlock()
+
+	return applyMetricPrefixes(families, metricPrefixes.prefixes), err
+})
+
+// applyMetricPrefixes moves the metrics of the placements with a prefix to families named by the prefix instead of the
+// namespace, the metrics of the other placements keep their names.
+func applyMetricPrefixes(families []*dto.MetricFamily, prefixes map[string]string) []*dto.MetricFamily {
+	if len(prefixes) == 0 {
+		return families
+	}
+
+	result := make([]*dto.MetricFamily, 0, len(families))
+	prefixed := map[string]*dto.MetricFamily{}
+	for _, family := range families {
+		name, ok := strings.CutPrefix(family.GetName(), namespace+"_")
+		if !ok {
+			result = append(result, family)
+			continue
+		}
+
+		var shared []*dto.Metric
+		for _, metric := range family.GetMetric() {
+			prefix, ok := prefixes[placementOf(metric)]
+			if !ok {
+				shared = append(shared, metric)
+				continue
+			}
+
+			prefixedName := prefix + "_" + name
+			prefixedFamily, ok := prefixed[prefixedName]
+			if !ok {
+				prefixedFamily = &dto.MetricFamily{Name: &prefixedName, Help: family.Help, Type: family.Type}
+				prefixed[prefixedName] = prefixedFamily
+				result = append(result, prefixedFamily)
+			}
+			prefixedFamily.Metric = append(prefixedFamily.Metric, metric)
+		}
+		if len(shared) > 0 {
+			family.Metric = shared
+			result = append(result, family)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].GetName() < result[j].GetName()
+	})
+	return result
+}
+
+func placementOf(metric *dto.Metric) string {
+	for _, label := range metric.GetLabel() {
+		if label.GetName() == "placement" {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
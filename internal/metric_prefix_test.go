@@ -0,0 +1,63 @@
+package internal
+
+import (
+	"testing"
+
+	dto "github.com/prometheus/client_model/go"
+)
+
+func testFamily(name string, placements ...string) *dto.MetricFamily {
+	family := &dto.MetricFamily{Name: &name}
+	for _, placement := range placements {
+		labelName, labelValue := "placement", placement
+		family.Metric = append(family.Metric, &dto.Metric{
+			Label: []*dto.LabelPair{{Name: &labelName, Value: &labelValue}},
+		})
+	}
+	return family
+}
+
+func Test_applyMetricPrefixes(t *testing.T) {
+	families := []*dto.MetricFamily{
+		testFamily("go_goroutines"),
+		testFamily("gobot_bme280_sensor_temperature_celsius", "attic", "cellar", "garage"),
+		testFamily("gobot_bme280_sensor_humidity_percent", "attic"),
+	}
+	got := applyMetricPrefixes(families, map[string]string{"attic": "legacy", "garage": "legacy"})
+
+	want := map[string][]string{
+		"go_goroutines": nil,
+		"gobot_bme280_sensor_temperature_celsius": {"cellar"},
+		"legacy_sensor_humidity_percent":          {"attic"},
+		"legacy_sensor_temperature_celsius":       {"attic", "garage"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("applyMetricPrefixes() returned %d families, want %d", len(got), len(want))
+	}
+	for i, family := range got {
+		if i > 0 && got[i-1].GetName() >= family.GetName() {
+			t.Errorf("families are not sorted: %s before %s", got[i-1].GetName(), family.GetName())
+		}
+		placements, ok := want[family.GetName()]
+		if !ok {
+			t.Errorf("unexpected family %s", family.GetName())
+			continue
+		}
+		if len(family.GetMetric()) != len(placements) {
+			t.Errorf("family %s has %d metrics, want %d", family.GetName(), len(family.GetMetric()), len(placements))
+			continue
+		}
+		for j, metric := range family.GetMetric() {
+			if placementOf(metric) != placements[j] {
+				t.Errorf("family %s metric %d has placement %s, want %s", family.GetName(), j, placementOf(metric), placements[j])
+			}
+		}
+	}
+}
+
+func Test_applyMetricPrefixes_none(t *testing.T) {
+	families := []*dto.MetricFamily{testFamily("gobot_bme280_sensor_temperature_celsius", "attic")}
+	if got := applyMetricPrefixes(families, nil); len(got) != 1 || got[0].GetName() != "gobot_bme280_sensor_temperature_celsius" {
+		t.Errorf("applyMetricPrefixes() without prefixes changed the families: %v", got)
+	}
+}
@@ -2,17 +2,106 @@ package internal
 
 import (
 	"errors"
+	"fmt"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promauto"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/soerenschneider/gobot-bme280/internal/config"
-	"log"
+	"log/slog"
+	"net"
 	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
 const namespace = config.BotName
 
+// registerMetadataInfo exports the metadata of the sensor as labels of an info metric.
+func registerMetadataInfo(placement string, metadata map[string]string) error {
+	keys := make([]string, 0, len(metadata))
+	for key := range metadata {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	info := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "metadata_info",
+		Subsystem: "sensor",
+		Help:      "Metadata of the physical sensor read from the metadata file",
+	}, append([]string{"placement"}, keys...))
+	if err := prometheus.Register(info); err != nil {
+		// with multiple sensors, the collector has been registered with the metadata of another sensor
+		already, ok := err.(prometheus.AlreadyRegisteredError)
+		if !ok {
+			return err
+		}
+		info = already.ExistingCollector.(*prometheus.GaugeVec)
+	}
+
+	values := []string{placement}
+	for _, key := range keys {
+		values = append(values, metadata[key])
+	}
+	info.WithLabelValues(values...).Set(1)
+	return nil
+}
+
+// the options of the gauges of the measured values are reused if the values are exported as summaries or in another
+// unit
+var (
+	altitudeOpts = prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "altitude_meters",
+		Subsystem: "sensor",
+		Help:      "The measured altitude in meters",
+	}
+
+	humidityOpts = prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "humidity_percent",
+		Subsystem: "sensor",
+		Help:      "The measured humidity in percent",
+	}
+
+	temperatureOpts = prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "temperature_celsius",
+		Subsystem: "sensor",
+		Help:      "The measured temperature in degrees celsius",
+	}
+
+	pressureOpts = prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pressure_pa",
+		Subsystem: "sensor",
+		Help:      "The measured pressure in pascal",
+	}
+
+	dewPointOpts = prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "dew_point_celsius",
+		Subsystem: "sensor",
+		Help:      "The dew point in degrees celsius, derived from temperature and humidity",
+	}
+
+	temperatureMinOpts = prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "temperature_min_celsius",
+		Subsystem: "sensor",
+		Help:      "The lowest measured temperature in degrees celsius since the last reset",
+	}
+
+	temperatureMaxOpts = prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "temperature_max_celsius",
+		Subsystem: "sensor",
+		Help:      "The highest measured temperature in degrees celsius since the last reset",
+	}
+)
+
 var (
 	versionInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
@@ -20,6 +109,25 @@ var (
 		Help:      "Version information of this robot",
 	}, []string{"version", "commit"})
 
+	configInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "config_info",
+		Help:      "Hash of the effective config of this robot",
+	}, []string{"placement", "config_hash"})
+
+	unitsInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "units_info",
+		Help:      "Units of the measured values exposed as metrics",
+	}, []string{"placement", "temperature_unit", "pressure_unit", "humidity_unit"})
+
+	sensorInfo = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "info",
+		Subsystem: "sensor",
+		Help:      "Identity of the physical sensor at the placement",
+	}, []string{"placement", "sensor_id"})
+
 	metricsHeartbeat = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
 		Name:      "heartbeat_timestamp_seconds",
@@ -33,32 +141,184 @@ var (
 		Help:      "Total amount of errors while reading from the sensor",
 	}, []string{"placement"})
 
-	metricAltitude = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	metricReadErrors = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: namespace,
-		Name:      "altitude_meters",
+		Name:      "read_errors_total",
 		Subsystem: "sensor",
-		Help:      "The measured altitude in meters",
+		Help:      "Total amount of failed attempts to read a value from the sensor, including the retried ones",
 	}, []string{"placement"})
 
-	metricHumidity = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	metricChannelErrors = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: namespace,
-		Name:      "humidity_percent",
+		Name:      "channel_errors_total",
 		Subsystem: "sensor",
-		Help:      "The measured humidity in percent",
+		Help:      "Total amount of errors per measured value",
+	}, []string{"placement", "measurement"})
+
+	metricSensorReconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconnects_total",
+		Subsystem: "sensor",
+		Help:      "Total amount of reconnects to the sensor after repeated errors",
 	}, []string{"placement"})
 
-	metricTemperature = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	metricNonFiniteValues = promauto.NewCounterVec(prometheus.CounterOpts{
 		Namespace: namespace,
-		Name:      "temperature_celsius",
+		Name:      "non_finite_values_total",
 		Subsystem: "sensor",
-		Help:      "The measured temperature in degrees celsius",
+		Help:      "Total amount of NaN or infinite values that were not published",
+	}, []string{"placement", "measurement"})
+
+	metricPanics = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "recovered_panics_total",
+		Help:      "Total amount of panics that were recovered from while reading and publishing a measurement",
 	}, []string{"placement"})
 
-	metricPressure = promauto.NewGaugeVec(prometheus.GaugeOpts{
+	metricConsecutiveErrors = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Namespace: namespace,
-		Name:      "pressure_pa",
+		Name:      "consecutive_read_errors",
+		Help:      "Amount of consecutive failed readings, reset on the first successful reading",
+	}, []string{"placement"})
+
+	metricHealth = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "health",
+		Help:      "Health score of the sensor in [0, 1], based on the error ratio and the freshness of the readings",
+	}, []string{"placement"})
+
+	metricReadDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "read_duration_seconds",
 		Subsystem: "sensor",
-		Help:      "The measured pressure in pascal",
+		Help:      "Duration of reading all values from the sensor",
+		Buckets:   []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+	}, []string{"placement"})
+
+	metricDerivedDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "derived_compute_duration_seconds",
+		Help:      "Duration of computing the derived values of a reading",
+		Buckets:   []float64{.000001, .0000025, .000005, .00001, .000025, .00005, .0001, .00025, .0005, .001},
+	}, []string{"placement"})
+
+	metricLoopSleep = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "loop_sleep_seconds",
+		Help:      "The actual time between the end of the previous reading and the start of the current one, including alignment to the clock",
+	}, []string{"placement"})
+
+	metricSensorMode = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "mode",
+		Subsystem: "sensor",
+		Help:      "The power mode of the sensor (0=sleep, 1=forced, 3=normal)",
+	}, []string{"placement"})
+
+	metricAltitude = promauto.NewGaugeVec(altitudeOpts, []string{"placement"})
+
+	metricHumidity = promauto.NewGaugeVec(humidityOpts, []string{"placement"})
+
+	metricTemperature = promauto.NewGaugeVec(temperatureOpts, []string{"placement"})
+
+	metricPressure = promauto.NewGaugeVec(pressureOpts, []string{"placement"})
+
+	metricPressureSeaLevel = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pressure_sealevel_pa",
+		Subsystem: "sensor",
+		Help:      "The measured pressure reduced to sea level in pascal",
+	}, []string{"placement"})
+
+	metricDewPoint = promauto.NewGaugeVec(dewPointOpts, []string{"placement"})
+
+	metricAbsoluteHumidity = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "absolute_humidity_grams_per_cubic_meter",
+		Subsystem: "sensor",
+		Help:      "The absolute humidity in g/m³, derived from temperature and humidity",
+	}, []string{"placement"})
+
+	metricPressureTendency = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "pressure_tendency_hpa_per_3h",
+		Subsystem: "sensor",
+		Help:      "The change of the pressure in hPa per 3 hours, fitted over the readings of the last 3 hours",
+	}, []string{"placement"})
+
+	metricSpecificHumidity = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "specific_humidity_ratio",
+		Subsystem: "sensor",
+		Help:      "The mass of water vapor per mass of moist air in kg/kg, derived from temperature, humidity and pressure",
+	}, []string{"placement"})
+
+	metricVoltage = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "voltage_volts",
+		Help:      "The voltage of the external voltage source",
+	}, []string{"placement"})
+
+	metricTemperatureMin = promauto.NewGaugeVec(temperatureMinOpts, []string{"placement"})
+
+	metricTemperatureMax = promauto.NewGaugeVec(temperatureMaxOpts, []string{"placement"})
+
+	metricComfortIndex = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "comfort_index",
+		Help:      "Comfort index in [0, 100] based on the deviation from the ideal temperature and humidity",
+	}, []string{"placement"})
+
+	metricDelta = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "delta",
+		Subsystem: "sensor",
+		Help:      "The change of the measured value since the previous reading",
+	}, []string{"placement", "measurement"})
+
+	metricInstant = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "instant",
+		Subsystem: "sensor",
+		Help:      "The measured value of the latest reading before smoothing",
+	}, []string{"placement", "measurement"})
+
+	metricTemperatureRaw = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "temperature_raw_celsius",
+		Help:      "The temperature as read from the sensor, before smoothing, self-heating compensation, scaling and offsets",
+	}, []string{"placement"})
+
+	metricStuck = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "stuck",
+		Subsystem: "sensor",
+		Help:      "Whether the sensor returned the identical value for more than the configured amount of reads",
+	}, []string{"placement", "measurement"})
+
+	metricStddev = map[string]*prometheus.GaugeVec{
+		"humidity": promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "humidity_stddev",
+			Help:      "The standard deviation of the humidity over the smoothing window",
+		}, []string{"placement"}),
+		"pressure": promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "pressure_stddev",
+			Help:      "The standard deviation of the pressure over the smoothing window",
+		}, []string{"placement"}),
+		"temperature": promauto.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "temperature_stddev",
+			Help:      "The standard deviation of the temperature over the smoothing window",
+		}, []string{"placement"}),
+	}
+
+	metricLastRead = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_read_timestamp_seconds",
+		Subsystem: "sensor",
+		Help:      "Unix time of the last successful read of the sensor",
 	}, []string{"placement"})
 
 	metricsMessagesPublished = promauto.NewCounterVec(prometheus.CounterOpts{
@@ -74,24 +334,255 @@ var (
 		Subsystem: "mqtt",
 		Help:      "Total amount of errors while trying to publish messages over MQTT",
 	}, []string{"placement"})
+
+	metricsLastPublishSuccess = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_publish_success",
+		Subsystem: "mqtt",
+		Help:      "Whether the last MQTT publish succeeded (1) or failed (0)",
+	}, []string{"placement"})
+
+	metricsMessagesRetried = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_retried_total",
+		Subsystem: "mqtt",
+		Help:      "Total amount of measurements published after retrying a failed publish",
+	}, []string{"placement"})
+
+	metricsMessagesBuffered = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_buffered_total",
+		Subsystem: "mqtt",
+		Help:      "Total amount of measurements added to the offline buffer after all publish attempts failed",
+	}, []string{"placement"})
+
+	metricsOfflineBuffered = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "offline_buffered_messages",
+		Subsystem: "mqtt",
+		Help:      "The amount of measurements buffered while the MQTT broker is unavailable",
+	}, []string{"placement"})
+
+	metricsOfflineBufferDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "offline_buffer_dropped_total",
+		Subsystem: "mqtt",
+		Help:      "Total amount of buffered measurements dropped because the offline buffer was full",
+	}, []string{"placement"})
+
+	metricsSpoolDropped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "spool_dropped_total",
+		Help:      "Total amount of spooled measurements dropped because the spool exceeded its maximum size",
+	}, []string{"placement"})
+
+	metricsKafkaPublished = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_published_total",
+		Subsystem: "kafka",
+		Help:      "The amount of messages produced to Kafka",
+	}, []string{"placement"})
+
+	metricsKafkaPublishErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "message_publish_errors_total",
+		Subsystem: "kafka",
+		Help:      "Total amount of errors while trying to produce messages to Kafka",
+	}, []string{"placement"})
+
+	metricsInfluxWriteErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "write_errors_total",
+		Subsystem: "influx",
+		Help:      "Total amount of errors while trying to write measurements to InfluxDB",
+	}, []string{"placement"})
+
+	metricDiskWritesSkipped = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "disk_writes_skipped_total",
+		Help:      "Total amount of writes to file sinks skipped as the free disk space is below the threshold",
+	}, []string{"placement", "sink"})
+
+	metricsRemoteWritePushes = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_published_total",
+		Subsystem: "remote_write",
+		Help:      "The amount of metric pushes to the remote-write endpoint",
+	}, []string{"placement"})
+
+	metricsRemoteWritePushErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "message_publish_errors_total",
+		Subsystem: "remote_write",
+		Help:      "Total amount of errors while trying to push metrics to the remote-write endpoint",
+	}, []string{"placement"})
+
+	metricsAlerts = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "messages_published_total",
+		Subsystem: "alert",
+		Help:      "The amount of alerts sent to the alert webhook",
+	}, []string{"placement", "status"})
+
+	metricsAlertErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "message_publish_errors_total",
+		Subsystem: "alert",
+		Help:      "Total amount of errors while trying to send alerts to the alert webhook",
+	}, []string{"placement"})
 )
 
-func metricFromMeasurement(m Measurement, placement string) {
-	metricAltitude.WithLabelValues(placement).Set(float64(m.Altitude))
-	metricHumidity.WithLabelValues(placement).Set(float64(m.Humidity))
-	metricPressure.WithLabelValues(placement).Set(float64(m.Pressure))
-	metricTemperature.WithLabelValues(placement).Set(float64(m.Temperature))
-	if nil != m.Errors && len(m.Errors) > 0 {
-		metricSensorErrors.WithLabelValues(placement).Inc()
+type measurementMetric struct {
+	gauge *prometheus.GaugeVec
+	opts  prometheus.GaugeOpts
+}
+
+// measurementMetrics are the gauges of the measured values that can be exported as summaries instead.
+var measurementMetrics = map[string]measurementMetric{
+	"altitude":    {metricAltitude, altitudeOpts},
+	"humidity":    {metricHumidity, humidityOpts},
+	"pressure":    {metricPressure, pressureOpts},
+	"temperature": {metricTemperature, temperatureOpts},
+}
+
+// usesFahrenheit is set once the gauges of the temperatures have been replaced by gauges in degrees fahrenheit.
+var usesFahrenheit bool
+
+// metricSummaries holds the summaries that replace the gauges of the respective measured values.
+var metricSummaries = map[string]*prometheus.SummaryVec{}
+
+// useSummaries exports the given measured values as summaries with the given quantiles instead of gauges.
+func useSummaries(measurements []string, quantiles []float64) error {
+	objectives := make(map[float64]float64, len(quantiles))
+	for _, q := range quantiles {
+		objectives[q] = (1 - q) / 10
+	}
+
+	for _, name := range measurements {
+		metric, ok := measurementMetrics[name]
+		if !ok {
+			return fmt.Errorf("unknown measurement %q", name)
+		}
+		if _, ok := metricSummaries[name]; ok {
+			continue
+		}
+
+		// the help text has to match the one of the gauge, as the registry keeps the descriptors of unregistered metrics
+		summary := prometheus.NewSummaryVec(prometheus.SummaryOpts{
+			Namespace:  metric.opts.Namespace,
+			Subsystem:  metric.opts.Subsystem,
+			Name:       metric.opts.Name,
+			Help:       metric.opts.Help,
+			Objectives: objectives,
+		}, []string{"placement"})
+		prometheus.Unregister(metric.gauge)
+		if err := prometheus.Register(summary); err != nil {
+			return fmt.Errorf("could not register summary for %s: %w", name, err)
+		}
+		metricSummaries[name] = summary
+	}
+	return nil
+}
+
+// useFahrenheit replaces the gauges of the temperatures in degrees celsius with gauges in degrees fahrenheit, so the
+// unit of the exposed values is part of their name. Must be called before useSummaries.
+func useFahrenheit() error {
+	if usesFahrenheit {
+		return nil
+	}
+
+	replace := func(gauge *prometheus.GaugeVec, opts prometheus.GaugeOpts) (*prometheus.GaugeVec, prometheus.GaugeOpts, error) {
+		opts.Name = strings.Replace(opts.Name, "celsius", "fahrenheit", 1)
+		opts.Help = strings.Replace(opts.Help, "celsius", "fahrenheit", 1)
+		replacement := prometheus.NewGaugeVec(opts, []string{"placement"})
+		prometheus.Unregister(gauge)
+		if err := prometheus.Register(replacement); err != nil {
+			return nil, opts, fmt.Errorf("could not register %s: %w", opts.Name, err)
+		}
+		return replacement, opts, nil
+	}
+
+	var err error
+	if metricTemperature, temperatureOpts, err = replace(metricTemperature, temperatureOpts); err != nil {
+		return err
+	}
+	measurementMetrics["temperature"] = measurementMetric{metricTemperature, temperatureOpts}
+	if metricDewPoint, dewPointOpts, err = replace(metricDewPoint, dewPointOpts); err != nil {
+		return err
+	}
+	if metricTemperatureMin, temperatureMinOpts, err = replace(metricTemperatureMin, temperatureMinOpts); err != nil {
+		return err
+	}
+	if metricTemperatureMax, temperatureMaxOpts, err = replace(metricTemperatureMax, temperatureMaxOpts); err != nil {
+		return err
+	}
+	usesFahrenheit = true
+	return nil
+}
+
+// setMeasurementMetric updates the gauge of the measured value or observes it if it's exported as a summary.
+func setMeasurementMetric(name string, value float32, placement string) {
+	if summary, ok := metricSummaries[name]; ok {
+		summary.WithLabelValues(placement).Observe(float64(value))
+		return
+	}
+	measurementMetrics[name].gauge.WithLabelValues(placement).Set(float64(value))
+}
+
+// metricFromMeasurement updates the metrics of the measurement, skipping the measured values that are not exposed.
+func metricFromMeasurement(m Measurement, placement string, exposed func(string) bool) {
+	if !m.Failed("altitude") && exposed("altitude") {
+		setMeasurementMetric("altitude", m.Altitude, placement)
+	}
+	if !m.Failed("humidity") && exposed("humidity") {
+		setMeasurementMetric("humidity", m.Humidity, placement)
+	}
+	if !m.Failed("pressure") && exposed("pressure") {
+		setMeasurementMetric("pressure", m.Pressure, placement)
+	}
+	if !m.Failed("temperature") && exposed("temperature") {
+		setMeasurementMetric("temperature", m.Temperature, placement)
+	}
+	if m.PressureSeaLevel > 0 {
+		metricPressureSeaLevel.WithLabelValues(placement).Set(float64(m.PressureSeaLevel))
+	}
+	if m.SpecificHumidity > 0 {
+		metricSpecificHumidity.WithLabelValues(placement).Set(float64(m.SpecificHumidity))
+	}
+	if m.Voltage > 0 {
+		metricVoltage.WithLabelValues(placement).Set(float64(m.Voltage))
 	}
 }
 
-func StartMetricsServer(listenAddr string) {
-	log.Printf("Starting metrics listener at %s", listenAddr)
+// metricsHandler serves the metrics in the OpenMetrics format if requested by the scraper and falls back to the
+// legacy text format otherwise.
+func metricsHandler() http.Handler {
+	handler := promhttp.HandlerFor(metricsGatherer, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	})
+	return promhttp.InstrumentMetricHandler(prometheus.DefaultRegisterer, handler)
+}
+
+// metricsPortAttempts is the amount of consecutive ports that are tried if the configured port is taken and the
+// next port should be used.
+const metricsPortAttempts = 10
+
+// StartMetricsServer binds the metrics listener and serves the metrics in the background. The Addr of the returned
+// server is the address the listener is bound to, which differs from the configured one if the port was taken and
+// fallback is set to config.MetricsBindFallbackNextPort.
+func StartMetricsServer(listenAddr, fallback string) (*http.Server, error) {
+	listener, err := listenMetrics(listenAddr, fallback)
+	if err != nil {
+		return nil, err
+	}
+
 	mux := http.NewServeMux()
-	mux.Handle("/metrics", promhttp.Handler())
-	server := http.Server{
-		Addr:              listenAddr,
+	mux.Handle("/metrics", metricsHandler())
+	mux.HandleFunc("/reset-extremes", handleResetExtremes)
+	mux.HandleFunc("/healthz", probes.handleHealthz)
+	mux.HandleFunc("/readyz", probes.handleReadyz)
+	server := &http.Server{
+		Addr:              listener.Addr().String(),
 		ReadTimeout:       3 * time.Second,
 		ReadHeaderTimeout: 3 * time.Second,
 		WriteTimeout:      3 * time.Second,
@@ -99,7 +590,50 @@ func StartMetricsServer(listenAddr string) {
 		Handler:           mux,
 	}
 
-	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-		log.Fatalf("Could not start metrics listener: %v", err)
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Metrics listener stopped", "err", err)
+		}
+	}()
+	return server, nil
+}
+
+func listenMetrics(listenAddr, fallback string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", listenAddr)
+	if err == nil || fallback != config.MetricsBindFallbackNextPort {
+		return listener, err
+	}
+
+	host, portStr, splitErr := net.SplitHostPort(listenAddr)
+	if splitErr != nil {
+		return nil, err
+	}
+	port, convErr := strconv.Atoi(portStr)
+	if convErr != nil {
+		return nil, err
+	}
+	for next := port + 1; next < port+metricsPortAttempts; next++ {
+		slog.Warn("Could not bind metrics listener, trying next port", "port", next, "err", err)
+		listener, err = net.Listen("tcp", net.JoinHostPort(host, strconv.Itoa(next)))
+		if err == nil {
+			return listener, nil
+		}
+	}
+	return nil, fmt.Errorf("no free port in %d ports starting at %s: %w", metricsPortAttempts, listenAddr, err)
+}
+
+// metricInstantFromMeasurement updates the instant metrics of the unsmoothed measurement, skipping the measured values
+// that are not exposed.
+func metricInstantFromMeasurement(m Measurement, placement string, exposed func(string) bool) {
+	for name, value := range map[string]float32{
+		"altitude":    m.Altitude,
+		"humidity":    m.Humidity,
+		"pressure":    m.Pressure,
+		"temperature": m.Temperature,
+	} {
+		if m.Failed(name) || !exposed(name) {
+			continue
+		}
+		metricInstant.WithLabelValues(placement, name).Set(float64(value))
 	}
 }
@@ -0,0 +1,106 @@
+package internal
+
+import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func Test_metricsHandler(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   string
+	}{
+		{
+			name:   "openmetrics",
+			accept: "application/openmetrics-text; version=0.0.1",
+			want:   "application/openmetrics-text",
+		},
+		{
+			name:   "legacy text format",
+			accept: "",
+			want:   "text/plain",
+		},
+	}
+	handler := metricsHandler()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+			if len(tt.accept) > 0 {
+				req.Header.Set("Accept", tt.accept)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.want) {
+				t.Errorf("Content-Type = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_useSummaries(t *testing.T) {
+	t.Cleanup(func() {
+		if summary, ok := metricSummaries["altitude"]; ok {
+			prometheus.Unregister(summary)
+			delete(metricSummaries, "altitude")
+			prometheus.MustRegister(metricAltitude)
+		}
+	})
+
+	if err := useSummaries([]string{"altitude"}, []float64{0.5}); err != nil {
+		t.Fatal(err)
+	}
+	setMeasurementMetric("altitude", 100, "test")
+	setMeasurementMetric("altitude", 300, "test")
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, family := range families {
+		if family.GetName() != "gobot_bme280_sensor_altitude_meters" {
+			continue
+		}
+		summary := family.GetMetric()[0].GetSummary()
+		if summary == nil || summary.GetSampleCount() != 2 || summary.GetSampleSum() != 400 {
+			t.Errorf("expected a summary of both observations, got %v", family)
+		}
+		return
+	}
+	t.Error("summary not found")
+}
+
+func Test_useSummaries_unknown(t *testing.T) {
+	if err := useSummaries([]string{"voltage"}, []float64{0.5}); err == nil {
+		t.Error("expected error for unknown measurement")
+	}
+}
+
+func Test_listenMetrics(t *testing.T) {
+	taken, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer taken.Close()
+
+	if _, err := listenMetrics(taken.Addr().String(), config.MetricsBindFallbackFail); err == nil {
+		t.Error("expected error for a taken port")
+	}
+
+	listener, err := listenMetrics(taken.Addr().String(), config.MetricsBindFallbackNextPort)
+	if err != nil {
+		// the next ports may be taken by other processes, too
+		t.Skipf("no free port: %v", err)
+	}
+	defer listener.Close()
+	if listener.Addr().String() == taken.Addr().String() {
+		t.Errorf("expected a different port than %s", taken.Addr())
+	}
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+const (
+	regChipId = 0xD0
+
+	chipIdBme280 = 0x60
+	chipIdBmp280 = 0x58
+)
+
+// sensorModel returns the model identified by the chip id, or an empty string if the chip id is unknown.
+func sensorModel(chipId int) string {
+	switch chipId {
+	case chipIdBme280:
+		return config.SensorTypeBme280
+	case chipIdBmp280:
+		return config.SensorTypeBmp280
+	}
+	return ""
+}
+
+// readSensorModel reads the chip id register and returns the model of the sensor.
+func (station *WeatherBotAdaptors) readSensorModel() (string, error) {
+	if err := station.BusLock.lock(); err != nil {
+		return "", err
+	}
+	defer station.BusLock.unlock()
+
+	chipId, err := station.Driver.Read(strconv.Itoa(regChipId))
+	if err != nil {
+		return "", err
+	}
+	model := sensorModel(chipId)
+	if len(model) == 0 {
+		return "", fmt.Errorf("unknown chip id 0x%02x", chipId)
+	}
+	return model, nil
+}
+
+// configureSensorModel determines the model of the sensor and disables the values the model can not measure. If the
+// type is configured explicitly, it is used regardless of the detected model.
+func (station *WeatherBotAdaptors) configureSensorModel() {
+	configured := station.Config.SensorType
+	detected, err := station.readSensorModel()
+	switch {
+	case err != nil:
+		slog.Warn("Could not detect sensor model", "err", err)
+	case configured == config.SensorTypeAuto:
+		slog.Info("Detected sensor model", "model", detected)
+	case len(configured) > 0 && configured != detected:
+		slog.Warn("Configured sensor type does not match the detected sensor model", "configured", configured, "model", detected)
+	}
+
+	model := configured
+	if model == config.SensorTypeAuto {
+		model = detected
+	}
+	if model != config.SensorTypeBmp280 {
+		return
+	}
+
+	slog.Info("The BMP280 can not measure humidity, disabling humidity and the values derived from it")
+	station.humidityUnsupported = true
+	station.Config.PublishSpecificHumidity = false
+	station.Config.PublishDewPoint = false
+	station.Config.PublishAbsoluteHumidity = false
+	station.Config.PublishComfort = false
+	station.Config.PublishComfortIndex = false
+	station.Config.CondensationSurfaces = nil
+	station.Config.VentilationReferenceTopic = ""
+}
@@ -0,0 +1,49 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func TestWeatherBotAdaptors_configureSensorModel(t *testing.T) {
+	tests := []struct {
+		name           string
+		sensorType     string
+		chipId         int
+		wantNoHumidity bool
+	}{
+		{name: "default bme280", chipId: chipIdBme280},
+		{name: "auto bme280", sensorType: config.SensorTypeAuto, chipId: chipIdBme280},
+		{name: "auto bmp280", sensorType: config.SensorTypeAuto, chipId: chipIdBmp280, wantNoHumidity: true},
+		{name: "auto unknown chip", sensorType: config.SensorTypeAuto, chipId: 0x27},
+		{name: "configured bmp280", sensorType: config.SensorTypeBmp280, chipId: chipIdBme280, wantNoHumidity: true},
+		{name: "configured bme280 mismatch", sensorType: config.SensorTypeBme280, chipId: chipIdBmp280},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conf := config.DefaultConfig()
+			conf.Topic = "sensors/test"
+			conf.SensorType = tt.sensorType
+			conf.PublishComfort = true
+			station, _ := newTestStation(conf)
+			station.Driver.(*FakeBme280).ChipId = tt.chipId
+
+			station.configureSensorModel()
+			if station.humidityUnsupported != tt.wantNoHumidity {
+				t.Fatalf("humidityUnsupported = %t, want %t", station.humidityUnsupported, tt.wantNoHumidity)
+			}
+			if station.Config.PublishComfort == tt.wantNoHumidity {
+				t.Errorf("expected comfort to be disabled only if humidity is unsupported")
+			}
+
+			m := station.readMeasurement()
+			if m.Failed("humidity") != tt.wantNoHumidity {
+				t.Errorf("Failed(humidity) = %t, want %t", m.Failed("humidity"), tt.wantNoHumidity)
+			}
+			if len(m.Errors) > 0 {
+				t.Errorf("expected no errors, got %v", m.Errors)
+			}
+		})
+	}
+}
@@ -0,0 +1,202 @@
+package internal
+
+import (
+	"crypto/tls"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+const disconnectQuiesceMs = 500
+
+// ErrNotConnected is returned when trying to publish before connecting to the broker.
+var ErrNotConnected = errors.New("not connected to mqtt broker")
+
+// MqttAdaptor is a gobot connection to an MQTT broker. It waits a bounded amount of time for the broker to
+// acknowledge published messages so a stalled broker can not block the robot.
+type MqttAdaptor struct {
+	name           string
+	opts           *paho.ClientOptions
+	client         paho.Client
+	qos            int
+	publishTimeout time.Duration
+	onConnect      []func()
+	statusTopic    string
+	statusOnline   string
+	statusOffline  string
+
+	mu sync.Mutex
+	// unavailable contains the placements of the sensors that are currently unavailable
+	unavailable map[string]bool
+}
+
+func NewMqttAdaptor(host, clientId string, tlsConfig *tls.Config, qos int, publishTimeout time.Duration) *MqttAdaptor {
+	opts := paho.NewClientOptions()
+	opts.AddBroker(host)
+	opts.SetClientID(clientId)
+	opts.SetAutoReconnect(true)
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	adaptor := &MqttAdaptor{
+		name:           "MQTT",
+		opts:           opts,
+		qos:            qos,
+		publishTimeout: publishTimeout,
+	}
+	opts.SetOnConnectHandler(func(_ paho.Client) {
+		for _, handler := range adaptor.onConnect {
+			handler()
+		}
+	})
+	return adaptor
+}
+
+// UseStatusTopic publishes the availability of the bot as retained message to the given topic: the online payload
+// after each connect, the offline payload on graceful shutdown and the lost payload as last will on unexpected
+// disconnects. Must be called before connecting.
+func (a *MqttAdaptor) UseStatusTopic(topic, online, offline, lost string) {
+	a.statusTopic = topic
+	a.statusOnline = online
+	a.statusOffline = offline
+	a.opts.SetWill(topic, lost, byte(a.qos), true)
+	a.OnConnect(func() {
+		if !a.PublishRetained(topic, []byte(a.status())) {
+			slog.Warn("Could not publish status", "topic", topic)
+		}
+	})
+}
+
+// SetAvailable records whether the sensor at the placement is available. The offline payload is published to the
+// status topic as long as any sensor is unavailable, the online payload once all sensors are available again.
+func (a *MqttAdaptor) SetAvailable(placement string, available bool) {
+	a.mu.Lock()
+	before := a.statusLocked()
+	if a.unavailable == nil {
+		a.unavailable = map[string]bool{}
+	}
+	if available {
+		delete(a.unavailable, placement)
+	} else {
+		a.unavailable[placement] = true
+	}
+	status := a.statusLocked()
+	a.mu.Unlock()
+
+	if len(a.statusTopic) == 0 || status == before {
+		return
+	}
+	slog.Info("Availability changed", "placement", placement, "available", available, "status", status)
+	if !a.PublishRetained(a.statusTopic, []byte(status)) {
+		slog.Warn("Could not publish status", "topic", a.statusTopic)
+	}
+}
+
+// status returns the payload reflecting the availability of the sensors.
+func (a *MqttAdaptor) status() string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.statusLocked()
+}
+
+func (a *MqttAdaptor) statusLocked() string {
+	if len(a.unavailable) > 0 {
+		return a.statusOffline
+	}
+	return a.statusOnline
+}
+
+// Subscribe subscribes to the topic after each connect and passes the payload of received messages to the handler.
+// Must be called before connecting.
+func (a *MqttAdaptor) Subscribe(topic string, handler func(payload []byte)) {
+	a.OnConnect(func() {
+		token := a.client.Subscribe(topic, byte(a.qos), messageHandler(handler))
+		if token.Wait() && token.Error() != nil {
+			slog.Error("Could not subscribe", "topic", topic, "err", token.Error())
+		}
+	})
+}
+
+// messageHandler passes the payload to the handler in its own goroutine. Paho invokes the callbacks in order on its
+// router goroutine, so a handler that publishes and waits for the acknowledgement would block it and stall the
+// connection.
+func messageHandler(handler func(payload []byte)) paho.MessageHandler {
+	return func(_ paho.Client, msg paho.Message) {
+		go handler(msg.Payload())
+	}
+}
+
+// OnConnect registers a handler that is called after each successful (re-)connect to the broker. Must be called
+// before connecting.
+func (a *MqttAdaptor) OnConnect(handler func()) {
+	a.onConnect = append(a.onConnect, handler)
+}
+
+func (a *MqttAdaptor) Name() string {
+	return a.name
+}
+
+func (a *MqttAdaptor) SetName(name string) {
+	a.name = name
+}
+
+func (a *MqttAdaptor) Connect() error {
+	a.client = paho.NewClient(a.opts)
+	if token := a.client.Connect(); token.Wait() && token.Error() != nil {
+		return token.Error()
+	}
+	return nil
+}
+
+func (a *MqttAdaptor) Finalize() error {
+	if a.client != nil && len(a.statusTopic) > 0 {
+		if !a.PublishRetained(a.statusTopic, []byte(a.statusOffline)) {
+			slog.Warn("Could not publish status", "topic", a.statusTopic)
+		}
+	}
+	if a.client != nil {
+		a.client.Disconnect(disconnectQuiesceMs)
+	}
+	return nil
+}
+
+// IsConnected returns whether the connection to the broker is currently open.
+func (a *MqttAdaptor) IsConnected() bool {
+	return a.client != nil && a.client.IsConnectionOpen()
+}
+
+func (a *MqttAdaptor) Publish(topic string, msg []byte) bool {
+	return a.publish(topic, msg, false)
+}
+
+// PublishRetained publishes a message that is retained by the broker.
+func (a *MqttAdaptor) PublishRetained(topic string, msg []byte) bool {
+	return a.publish(topic, msg, true)
+}
+
+func (a *MqttAdaptor) publish(topic string, msg []byte, retained bool) bool {
+	if !a.IsConnected() {
+		slog.Warn("Could not publish message", "topic", topic, "err", ErrNotConnected)
+		return false
+	}
+
+	token := a.client.Publish(topic, byte(a.qos), retained, msg)
+	if a.publishTimeout <= 0 {
+		return true
+	}
+
+	if !token.WaitTimeout(a.publishTimeout) {
+		slog.Warn("Timed out publishing message", "topic", topic, "timeout", a.publishTimeout)
+		return false
+	}
+
+	if err := token.Error(); err != nil {
+		slog.Warn("Could not publish message", "topic", topic, "err", err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+)
+
+type fakeMessage struct {
+	paho.Message
+	payload []byte
+}
+
+func (m fakeMessage) Payload() []byte {
+	return m.payload
+}
+
+func Test_messageHandler(t *testing.T) {
+	received := make(chan []byte, 1)
+	release := make(chan struct{})
+	defer close(release)
+
+	// the handler blocks like a publish waiting for the acknowledgement that is only routed after the callback returns
+	callback := messageHandler(func(payload []byte) {
+		received <- payload
+		<-release
+	})
+
+	returned := make(chan struct{})
+	go func() {
+		callback(nil, fakeMessage{payload: []byte("60")})
+		close(returned)
+	}()
+
+	select {
+	case <-returned:
+	case <-time.After(time.Second):
+		t.Fatal("expected the callback to return while the handler is blocked")
+	}
+	select {
+	case got := <-received:
+		if string(got) != "60" {
+			t.Errorf("payload = %q, want %q", got, "60")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected the handler to be invoked")
+	}
+}
+
+func TestMqttAdaptor_SetAvailable(t *testing.T) {
+	adaptor := NewMqttAdaptor("tcp://localhost:1883", "test", nil, 1, time.Second)
+	adaptor.UseStatusTopic("status", "online", "offline", "lost")
+
+	adaptor.SetAvailable("attic", false)
+	adaptor.SetAvailable("kitchen", false)
+	adaptor.SetAvailable("attic", true)
+	if got := adaptor.status(); got != "offline" {
+		t.Errorf("status() = %q while a sensor is unavailable, want %q", got, "offline")
+	}
+	adaptor.SetAvailable("kitchen", true)
+	if got := adaptor.status(); got != "online" {
+		t.Errorf("status() = %q once all sensors are available, want %q", got, "online")
+	}
+}
@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"encoding/json"
+	"math"
+)
+
+// readingPayload is the message published per reading with the json payload format. Its values are in fixed units,
+// independent of the configured temperature unit and scales, values that could not be read are omitted.
+type readingPayload struct {
+	Placement    string   `json:"placement"`
+	Timestamp    int64    `json:"timestamp"`
+	TemperatureC *float64 `json:"temperature_c,omitempty"`
+	HumidityPct  *float64 `json:"humidity_pct,omitempty"`
+	PressureHpa  *float64 `json:"pressure_hpa,omitempty"`
+}
+
+// newReadingPayload builds the payload of the measurement in degrees celsius, percent and hectopascal, rounding the
+// values to decimals places unless decimals is 0.
+func newReadingPayload(m Measurement, placement string, decimals int) readingPayload {
+	value := func(name string, v float64) *float64 {
+		if m.Failed(name) || math.IsNaN(v) {
+			return nil
+		}
+		if decimals > 0 {
+			v = roundTo(v, decimals)
+		}
+		return &v
+	}
+
+	return readingPayload{
+		Placement:    placement,
+		Timestamp:    m.Timestamp,
+		TemperatureC: value("temperature", float64(m.Temperature)),
+		HumidityPct:  value("humidity", float64(m.Humidity)),
+		PressureHpa:  value("pressure", float64(m.Pressure)/100),
+	}
+}
+
+// readingPayloadJson marshals the payload of the measurement, see newReadingPayload.
+func readingPayloadJson(m Measurement, placement string, decimals int) ([]byte, error) {
+	return json.Marshal(newReadingPayload(m, placement, decimals))
+}
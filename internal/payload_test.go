@@ -0,0 +1,34 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+)
+
+func Test_readingPayloadJson(t *testing.T) {
+	m := Measurement{Timestamp: 1700000000, Temperature: 21.456, Humidity: 40.123, Pressure: 101325}
+	got, err := readingPayloadJson(m, "attic", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"placement":"attic","timestamp":1700000000,"temperature_c":21.46,"humidity_pct":40.12,"pressure_hpa":1013.25}`
+	if string(got) != want {
+		t.Errorf("readingPayloadJson() = %s, want %s", got, want)
+	}
+
+	m.AddHumidity(0, errors.New("humidity not available"))
+	got, _ = readingPayloadJson(m, "attic", 0)
+	want = `{"placement":"attic","timestamp":1700000000,"temperature_c":21.45599937438965,"pressure_hpa":1013.25}`
+	if string(got) != want {
+		t.Errorf("readingPayloadJson() with failed humidity = %s, want %s", got, want)
+	}
+}
+
+func Test_readingPayloadJson_placeholder(t *testing.T) {
+	m := placeholderMeasurement()
+	m.Timestamp = 1
+	got, _ := readingPayloadJson(m, "attic", 2)
+	if want := `{"placement":"attic","timestamp":1}`; string(got) != want {
+		t.Errorf("readingPayloadJson() = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,24 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+	"gobot.io/x/gobot/v2/platforms/jetson"
+	"gobot.io/x/gobot/v2/platforms/raspi"
+	"gobot.io/x/gobot/v2/platforms/tinkerboard"
+)
+
+// NewPlatformAdaptor returns the adaptor of the board the sensor is attached to, defaulting to the Raspberry Pi.
+func NewPlatformAdaptor(platform string) (I2cAdaptor, error) {
+	switch platform {
+	case "", config.PlatformRaspi:
+		return raspi.NewAdaptor(), nil
+	case config.PlatformTinkerboard:
+		return tinkerboard.NewAdaptor(), nil
+	case config.PlatformJetson:
+		return jetson.NewAdaptor(), nil
+	default:
+		return nil, fmt.Errorf("unknown platform %q", platform)
+	}
+}
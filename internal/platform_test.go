@@ -0,0 +1,20 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func TestNewPlatformAdaptor(t *testing.T) {
+	for _, platform := range []string{"", config.PlatformRaspi, config.PlatformTinkerboard, config.PlatformJetson} {
+		adaptor, err := NewPlatformAdaptor(platform)
+		if err != nil || adaptor == nil {
+			t.Errorf("NewPlatformAdaptor(%q) = %v, %v, want adaptor", platform, adaptor, err)
+		}
+	}
+
+	if _, err := NewPlatformAdaptor("beaglebone"); err == nil {
+		t.Errorf("NewPlatformAdaptor() expected error for unknown platform")
+	}
+}
@@ -0,0 +1,57 @@
+package internal
+
+import "math"
+
+// publishPolicy suppresses measurements that did not change by more than the deadband of any measured value since
+// the last published measurement. Regardless of changes, a measurement is published after heartbeatIntervals
+// suppressed intervals, so subscribers can tell that the robot is still alive.
+type publishPolicy struct {
+	deadbands          map[string]float64
+	heartbeatIntervals int
+	last               *Measurement
+	suppressed         int
+}
+
+func newPublishPolicy(deadbands map[string]float64, heartbeatIntervals int) *publishPolicy {
+	return &publishPolicy{
+		deadbands:          deadbands,
+		heartbeatIntervals: heartbeatIntervals,
+	}
+}
+
+// shouldPublish returns whether the measurement is to be published and records it as published if so.
+func (p *publishPolicy) shouldPublish(m Measurement) bool {
+	if len(p.deadbands) == 0 || p.last == nil || len(m.Errors) > 0 || p.changed(m) || p.heartbeatDue() {
+		p.last = &m
+		p.suppressed = 0
+		return true
+	}
+
+	p.suppressed++
+	return false
+}
+
+func (p *publishPolicy) changed(m Measurement) bool {
+	for name, deadband := range p.deadbands {
+		if math.Abs(measurementValue(m, name)-measurementValue(*p.last, name)) > deadband {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *publishPolicy) heartbeatDue() bool {
+	return p.heartbeatIntervals > 0 && p.suppressed+1 >= p.heartbeatIntervals
+}
+
+func measurementValue(m Measurement, name string) float64 {
+	switch name {
+	case "temperature":
+		return float64(m.Temperature)
+	case "humidity":
+		return float64(m.Humidity)
+	case "pressure":
+		return float64(m.Pressure)
+	}
+	return 0
+}
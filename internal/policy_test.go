@@ -0,0 +1,33 @@
+package internal
+
+import "testing"
+
+func Test_publishPolicy_shouldPublish(t *testing.T) {
+	policy := newPublishPolicy(map[string]float64{"temperature": 0.5}, 3)
+
+	temperatures := []float32{21, 21.2, 21.4, 21.6, 22.2, 22.2, 22.2, 22.2}
+	want := []bool{true, false, false, true, true, false, false, true}
+	for i, temperature := range temperatures {
+		got := policy.shouldPublish(Measurement{Temperature: temperature})
+		if got != want[i] {
+			t.Errorf("reading %d: shouldPublish() = %v, want %v", i, got, want[i])
+		}
+	}
+}
+
+func Test_publishPolicy_disabled(t *testing.T) {
+	policy := newPublishPolicy(nil, 0)
+	for i := 0; i < 3; i++ {
+		if !policy.shouldPublish(Measurement{Temperature: 21}) {
+			t.Errorf("expected all measurements to be published without deadbands")
+		}
+	}
+}
+
+func Test_publishPolicy_errors(t *testing.T) {
+	policy := newPublishPolicy(map[string]float64{"temperature": 0.5}, 0)
+	policy.shouldPublish(Measurement{Temperature: 21})
+	if !policy.shouldPublish(Measurement{Temperature: 21, Errors: []string{"humidity not available"}}) {
+		t.Errorf("expected erroneous measurements to be published")
+	}
+}
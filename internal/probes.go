@@ -0,0 +1,100 @@
+package internal
+
+import (
+	"net/http"
+	"sync"
+)
+
+// probes tracks the liveness and the readiness of the bot, which are offered to orchestrators by the metrics server.
+var probes = newProbeState()
+
+type probeState struct {
+	mu      sync.Mutex
+	started bool
+	sensors map[string]bool
+	// connected reports whether the connection to the broker is alive, nil if MQTT is disabled
+	connected func() bool
+}
+
+func newProbeState() *probeState {
+	return &probeState{sensors: map[string]bool{}}
+}
+
+// register adds the sensor at the placement, which has to be read successfully for the bot to be ready.
+func (p *probeState) register(placement string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.sensors[placement]; !ok {
+		p.sensors[placement] = false
+	}
+}
+
+// useBroker makes the readiness depend on the connection to the broker.
+func (p *probeState) useBroker(connected func() bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.connected = connected
+}
+
+func (p *probeState) start() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.started = true
+}
+
+// record updates the readiness of the sensor at the placement after a read. The sensor is ready after a successful
+// read until it failed maxFailedReads reads in a row.
+func (p *probeState) record(placement string, consecutiveErrors, maxFailedReads int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if maxFailedReads < 1 {
+		maxFailedReads = 1
+	}
+	if consecutiveErrors == 0 {
+		p.sensors[placement] = true
+	} else if consecutiveErrors >= maxFailedReads {
+		p.sensors[placement] = false
+	}
+}
+
+func (p *probeState) isLive() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.started
+}
+
+func (p *probeState) isReady() bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !p.started || len(p.sensors) == 0 {
+		return false
+	}
+	for _, ready := range p.sensors {
+		if !ready {
+			return false
+		}
+	}
+	return p.connected == nil || p.connected()
+}
+
+func (p *probeState) handleHealthz(w http.ResponseWriter, _ *http.Request) {
+	writeProbe(w, p.isLive())
+}
+
+func (p *probeState) handleReadyz(w http.ResponseWriter, _ *http.Request) {
+	writeProbe(w, p.isReady())
+}
+
+func writeProbe(w http.ResponseWriter, ok bool) {
+	if !ok {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func probeStatus(handler http.HandlerFunc, path string) int {
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, path, nil))
+	return rec.Code
+}
+
+func TestProbeState(t *testing.T) {
+	p := newProbeState()
+	p.register("attic")
+	connected := true
+	p.useBroker(func() bool {
+		return connected
+	})
+
+	if code := probeStatus(p.handleHealthz, "/healthz"); code != http.StatusServiceUnavailable {
+		t.Errorf("healthz before start = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	p.start()
+	if code := probeStatus(p.handleHealthz, "/healthz"); code != http.StatusOK {
+		t.Errorf("healthz after start = %d, want %d", code, http.StatusOK)
+	}
+	if code := probeStatus(p.handleReadyz, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz before first read = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+
+	p.record("attic", 0, 3)
+	if code := probeStatus(p.handleReadyz, "/readyz"); code != http.StatusOK {
+		t.Errorf("readyz after successful read = %d, want %d", code, http.StatusOK)
+	}
+
+	connected = false
+	if code := probeStatus(p.handleReadyz, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz while disconnected = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	connected = true
+
+	p.record("attic", 2, 3)
+	if code := probeStatus(p.handleReadyz, "/readyz"); code != http.StatusOK {
+		t.Errorf("readyz after 2 failed reads = %d, want %d", code, http.StatusOK)
+	}
+	p.record("attic", 3, 3)
+	if code := probeStatus(p.handleReadyz, "/readyz"); code != http.StatusServiceUnavailable {
+		t.Errorf("readyz after 3 failed reads = %d, want %d", code, http.StatusServiceUnavailable)
+	}
+	if code := probeStatus(p.handleHealthz, "/healthz"); code != http.StatusOK {
+		t.Errorf("healthz after failed reads = %d, want %d", code, http.StatusOK)
+	}
+}
+
+func TestProbeState_multipleSensors(t *testing.T) {
+	p := newProbeState()
+	p.register("attic")
+	p.register("cellar")
+	p.start()
+
+	p.record("attic", 0, 3)
+	if p.isReady() {
+		t.Errorf("expected not ready until all sensors have been read")
+	}
+	p.record("cellar", 0, 3)
+	if !p.isReady() {
+		t.Errorf("expected ready once all sensors have been read")
+	}
+}
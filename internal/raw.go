@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"strconv"
+)
+
+const (
+	regPressMsb = 0xF7
+	regTempMsb  = 0xFA
+	regHumMsb   = 0xFD
+)
+
+// rawReadings are the uncompensated ADC values of the sensor.
+type rawReadings struct {
+	Pressure    int
+	Temperature int
+	Humidity    int
+}
+
+// readRawRegisters reads the uncompensated ADC values from the data registers. The registers are read one by one
+// rather than in a burst, so the values may stem from different conversions. This is only meant for debugging.
+func readRawRegisters(sensor WeatherBotSensor) (rawReadings, error) {
+	var data [8]int
+	for i := range data {
+		val, err := sensor.Read(strconv.Itoa(regPressMsb + i))
+		if err != nil {
+			return rawReadings{}, err
+		}
+		data[i] = val
+	}
+
+	return rawReadings{
+		Pressure:    data[0]<<12 | data[1]<<4 | data[2]>>4,
+		Temperature: data[regTempMsb-regPressMsb]<<12 | data[regTempMsb-regPressMsb+1]<<4 | data[regTempMsb-regPressMsb+2]>>4,
+		Humidity:    data[regHumMsb-regPressMsb]<<8 | data[regHumMsb-regPressMsb+1],
+	}, nil
+}
@@ -0,0 +1,38 @@
+package internal
+
+import (
+	"strconv"
+	"testing"
+)
+
+type registerBme280 struct {
+	FakeBme280
+	registers map[int]int
+}
+
+func (driver *registerBme280) Read(register string) (val int, err error) {
+	reg, err := strconv.Atoi(register)
+	if err != nil {
+		return 0, err
+	}
+	return driver.registers[reg], nil
+}
+
+func Test_readRawRegisters(t *testing.T) {
+	sensor := &registerBme280{
+		registers: map[int]int{
+			0xF7: 0x65, 0xF8: 0x5A, 0xF9: 0xC0,
+			0xFA: 0x7E, 0xFB: 0xED, 0xFC: 0x00,
+			0xFD: 0x6D, 0xFE: 0x1C,
+		},
+	}
+
+	got, err := readRawRegisters(sensor)
+	if err != nil {
+		t.Fatalf("readRawRegisters() error = %v", err)
+	}
+	want := rawReadings{Pressure: 415148, Temperature: 519888, Humidity: 27932}
+	if got != want {
+		t.Errorf("readRawRegisters() = %+v, want %+v", got, want)
+	}
+}
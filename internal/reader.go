@@ -0,0 +1,54 @@
+package internal
+
+// Reading holds the values of a single reading of the sensor.
+type Reading struct {
+	Altitude    float32
+	Humidity    float32
+	Pressure    float32
+	Temperature float32
+	// Errors holds the errors of the values that could not be read by the names of the values
+	Errors map[string]error
+}
+
+// SensorReader reads the values of the sensor. If an error is returned, none of the values could be read.
+type SensorReader interface {
+	Read() (Reading, error)
+}
+
+// driverReader reads the values from the driver of the station, averaging the configured amount of samples and
+// retrying failed reads. The humidity is not read if the sensor can not measure it.
+type driverReader struct {
+	station *WeatherBotAdaptors
+}
+
+func (r driverReader) Read() (Reading, error) {
+	station := r.station
+	samples := max(station.Config.SamplesPerReading, 1)
+	lock := station.BusLock
+
+	reading := Reading{}
+	for _, v := range []struct {
+		name  string
+		value *float32
+		read  func() (float32, error)
+	}{
+		{"altitude", &reading.Altitude, station.Driver.Altitude},
+		{"humidity", &reading.Humidity, station.Driver.Humidity},
+		{"pressure", &reading.Pressure, station.Driver.Pressure},
+		{"temperature", &reading.Temperature, station.Driver.Temperature},
+	} {
+		if v.name == "humidity" && station.humidityUnsupported {
+			continue
+		}
+		value, err := readAveraged(samples, station.retryable, station.retrying(lock.locked(v.read)))
+		if err != nil {
+			if reading.Errors == nil {
+				reading.Errors = map[string]error{}
+			}
+			reading.Errors[v.name] = err
+			continue
+		}
+		*v.value = value
+	}
+	return reading, nil
+}
@@ -0,0 +1,138 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const remoteWriteTimeout = 5 * time.Second
+
+// RemoteWriteSink pushes the metrics of this robot to a Prometheus remote-write compatible endpoint.
+type RemoteWriteSink struct {
+	conf     config.RemoteWriteConfig
+	gatherer prometheus.Gatherer
+	client   *http.Client
+}
+
+func NewRemoteWriteSink(conf config.RemoteWriteConfig) *RemoteWriteSink {
+	return &RemoteWriteSink{
+		conf:     conf,
+		gatherer: metricsGatherer,
+		client:   &http.Client{Timeout: remoteWriteTimeout},
+	}
+}
+
+type remoteWriteSeries struct {
+	labels map[string]string
+	value  float64
+}
+
+// Push sends the current values of all gauges and counters of this robot to the remote-write endpoint.
+func (s *RemoteWriteSink) Push() error {
+	families, err := s.gatherer.Gather()
+	if err != nil {
+		return fmt.Errorf("could not gather metrics: %w", err)
+	}
+
+	body := snappy.Encode(nil, encodeWriteRequest(collectSeries(families), time.Now()))
+	req, err := http.NewRequest(http.MethodPost, s.conf.RemoteWriteUrl, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	req.Header.Set("X-Prometheus-Remote-Write-Version", "0.1.0")
+	if len(s.conf.RemoteWriteUsername) > 0 {
+		req.SetBasicAuth(s.conf.RemoteWriteUsername, s.conf.RemoteWritePassword)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		return fmt.Errorf("remote-write endpoint returned status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func collectSeries(families []*dto.MetricFamily) []remoteWriteSeries {
+	var series []remoteWriteSeries
+	for _, family := range families {
+		if !strings.HasPrefix(family.GetName(), namespace+"_") {
+			continue
+		}
+
+		for _, metric := range family.GetMetric() {
+			var value float64
+			switch family.GetType() {
+			case dto.MetricType_GAUGE:
+				value = metric.GetGauge().GetValue()
+			case dto.MetricType_COUNTER:
+				value = metric.GetCounter().GetValue()
+			default:
+				continue
+			}
+
+			labels := map[string]string{"__name__": family.GetName()}
+			for _, label := range metric.GetLabel() {
+				labels[label.GetName()] = label.GetValue()
+			}
+			series = append(series, remoteWriteSeries{labels: labels, value: value})
+		}
+	}
+	return series
+}
+
+// encodeWriteRequest encodes the series as a prometheus.WriteRequest protobuf message.
+func encodeWriteRequest(series []remoteWriteSeries, now time.Time) []byte {
+	timestamp := now.UnixNano() / int64(time.Millisecond)
+
+	var req []byte
+	for _, s := range series {
+		var ts []byte
+
+		// labels must be sorted by name
+		names := make([]string, 0, len(s.labels))
+		for name := range s.labels {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			var label []byte
+			label = protowire.AppendTag(label, 1, protowire.BytesType)
+			label = protowire.AppendString(label, name)
+			label = protowire.AppendTag(label, 2, protowire.BytesType)
+			label = protowire.AppendString(label, s.labels[name])
+
+			ts = protowire.AppendTag(ts, 1, protowire.BytesType)
+			ts = protowire.AppendBytes(ts, label)
+		}
+
+		var sample []byte
+		sample = protowire.AppendTag(sample, 1, protowire.Fixed64Type)
+		sample = protowire.AppendFixed64(sample, math.Float64bits(s.value))
+		sample = protowire.AppendTag(sample, 2, protowire.VarintType)
+		sample = protowire.AppendVarint(sample, uint64(timestamp))
+
+		ts = protowire.AppendTag(ts, 2, protowire.BytesType)
+		ts = protowire.AppendBytes(ts, sample)
+
+		req = protowire.AppendTag(req, 1, protowire.BytesType)
+		req = protowire.AppendBytes(req, ts)
+	}
+	return req
+}
@@ -0,0 +1,82 @@
+package internal
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang/snappy"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+func TestRemoteWriteSink_Push(t *testing.T) {
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "test",
+	}, []string{"placement"})
+	registry.MustRegister(gauge)
+	gauge.WithLabelValues("loc").Set(1)
+
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Encoding") != "snappy" {
+			t.Errorf("unexpected content encoding %q", r.Header.Get("Content-Encoding"))
+		}
+		if user, pass, _ := r.BasicAuth(); user != "user" || pass != "pass" {
+			t.Errorf("unexpected credentials %q:%q", user, pass)
+		}
+		compressed, _ := io.ReadAll(r.Body)
+		body, _ = snappy.Decode(nil, compressed)
+	}))
+	defer server.Close()
+
+	sink := NewRemoteWriteSink(config.RemoteWriteConfig{
+		RemoteWriteUrl:      server.URL,
+		RemoteWriteUsername: "user",
+		RemoteWritePassword: "pass",
+	})
+	sink.gatherer = registry
+
+	if err := sink.Push(); err != nil {
+		t.Fatalf("Push() error = %v", err)
+	}
+
+	num, typ, n := protowire.ConsumeTag(body)
+	if n < 0 || num != 1 || typ != protowire.BytesType {
+		t.Fatalf("expected a single timeseries, got field %d of type %d", num, typ)
+	}
+	series, m := protowire.ConsumeBytes(body[n:])
+	if m < 0 || n+m != len(body) {
+		t.Fatalf("expected a single timeseries")
+	}
+
+	labels := 0
+	for len(series) > 0 {
+		num, _, n := protowire.ConsumeTag(series)
+		_, m := protowire.ConsumeBytes(series[n:])
+		if num == 1 {
+			labels++
+		}
+		series = series[n+m:]
+	}
+	if labels != 2 {
+		t.Errorf("expected 2 labels, got %d", labels)
+	}
+}
+
+func TestRemoteWriteSink_PushError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	sink := NewRemoteWriteSink(config.RemoteWriteConfig{RemoteWriteUrl: server.URL})
+	sink.gatherer = prometheus.NewRegistry()
+	if err := sink.Push(); err == nil {
+		t.Fatal("expected error")
+	}
+}
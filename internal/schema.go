@@ -0,0 +1,133 @@
+package internal
+
+import (
+	"encoding/json"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+// maxAgeIntervals is the amount of intervals after which a reading is considered stale.
+const maxAgeIntervals = 3
+
+// Schema describes the published measurements, so consumers can discover the capabilities of the sensor.
+type Schema struct {
+	Placement    string              `json:"placement"`
+	SensorId     string              `json:"sensor_id"`
+	IntervalSecs int                 `json:"interval_s"`
+	MaxAgeSecs   int                 `json:"max_age_seconds"`
+	Measurements []SchemaMeasurement `json:"measurements"`
+}
+
+// SchemaMeasurement describes a published field. The unit is the unit before scaling, if a scale or an offset is
+// configured for the field the published value is value*scale+offset.
+type SchemaMeasurement struct {
+	Field  string  `json:"field"`
+	Unit   string  `json:"unit"`
+	Scale  float64 `json:"scale,omitempty"`
+	Offset float64 `json:"offset,omitempty"`
+}
+
+func NewSchema(conf config.Config) Schema {
+	scaled := func(field, unit, measurement string) SchemaMeasurement {
+		return SchemaMeasurement{
+			Field:  field,
+			Unit:   unit,
+			Scale:  conf.MeasurementScales[measurement],
+			Offset: conf.MeasurementOffsets[measurement],
+		}
+	}
+
+	measurements := []SchemaMeasurement{
+		scaled("alt", "m", "altitude"),
+		scaled("humidity", "%", "humidity"),
+		scaled("pressure", "Pa", "pressure"),
+		scaled("temp", temperatureUnitSymbol(conf.TemperatureUnit), "temperature"),
+	}
+	if conf.PublishSeaLevelPressure {
+		measurements = append(measurements, scaled("pressure_sealevel", "Pa", "pressure"))
+	}
+	if conf.PublishSpecificHumidity {
+		measurements = append(measurements, SchemaMeasurement{Field: "specific_humidity", Unit: "kg/kg"})
+	}
+	if len(conf.VoltageFile) > 0 {
+		measurements = append(measurements, SchemaMeasurement{Field: "voltage", Unit: "V"})
+	}
+
+	return Schema{
+		Placement:    conf.Placement,
+		SensorId:     conf.EffectiveSensorId(),
+		IntervalSecs: conf.IntervalSecs,
+		MaxAgeSecs:   maxAgeIntervals * conf.IntervalSecs,
+		Measurements: measurements,
+	}
+}
+
+func (s Schema) AsJson() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// StartupTestTopic is the topic a single test message is published to after connecting, to verify the MQTT path.
+const StartupTestTopic = "status/startup"
+
+type StartupTest struct {
+	Message   string `json:"message"`
+	Placement string `json:"placement"`
+}
+
+func NewStartupTest(placement string) StartupTest {
+	return StartupTest{
+		Message:   "test",
+		Placement: placement,
+	}
+}
+
+func (s StartupTest) AsJson() ([]byte, error) {
+	return json.Marshal(s)
+}
+
+// ConfigSnapshotTopic is the topic the config snapshot is published to.
+const ConfigSnapshotTopic = "meta/config"
+
+// ConfigSnapshot is the subset of the effective config that is published for auditing. It deliberately contains
+// no credentials.
+type ConfigSnapshot struct {
+	Placement      string `json:"placement"`
+	SensorId       string `json:"sensor_id"`
+	Version        string `json:"version"`
+	ConfigHash     string `json:"config_hash"`
+	IntervalSecs   int    `json:"interval_s"`
+	GpioBus        int    `json:"gpio_bus"`
+	GpioAddress    int    `json:"gpio_address"`
+	Topic          string `json:"mqtt_topic"`
+	RemoteWrite    bool   `json:"remote_write"`
+	Kafka          bool   `json:"kafka"`
+	SamplesPerRead int    `json:"samples_per_reading"`
+	// TemperatureUnit is the unit of the published temperature
+	TemperatureUnit    string             `json:"temperature_unit"`
+	MeasurementScales  map[string]float64 `json:"measurement_scales,omitempty"`
+	MeasurementOffsets map[string]float64 `json:"measurement_offsets,omitempty"`
+}
+
+func NewConfigSnapshot(conf config.Config) ConfigSnapshot {
+	return ConfigSnapshot{
+		Placement:      conf.Placement,
+		SensorId:       conf.EffectiveSensorId(),
+		Version:        BuildVersion,
+		ConfigHash:     conf.Hash(),
+		IntervalSecs:   conf.IntervalSecs,
+		GpioBus:        conf.GpioBus,
+		GpioAddress:    conf.GpioAddress,
+		Topic:          conf.MqttConfig.Topic,
+		RemoteWrite:    conf.RemoteWriteConfig.Enabled(),
+		Kafka:          conf.KafkaConfig.Enabled(),
+		SamplesPerRead: conf.SamplesPerReading,
+
+		TemperatureUnit:    conf.TemperatureUnit,
+		MeasurementScales:  conf.MeasurementScales,
+		MeasurementOffsets: conf.MeasurementOffsets,
+	}
+}
+
+func (s ConfigSnapshot) AsJson() ([]byte, error) {
+	return json.Marshal(s)
+}
@@ -0,0 +1,85 @@
+package internal
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func TestNewSchema(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Placement = "livingroom"
+
+	schema := NewSchema(conf)
+	if schema.Placement != "livingroom" || schema.IntervalSecs != conf.IntervalSecs || schema.MaxAgeSecs != 3*conf.IntervalSecs || len(schema.Measurements) != 4 {
+		t.Errorf("NewSchema() = %+v", schema)
+	}
+
+	conf.PublishSeaLevelPressure = true
+	if schema := NewSchema(conf); len(schema.Measurements) != 5 {
+		t.Errorf("NewSchema() expected sea level pressure, got %+v", schema.Measurements)
+	}
+}
+
+func TestNewConfigSnapshot(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Placement = "livingroom"
+	conf.RemoteWriteUrl = "https://prometheus/api/v1/write"
+	conf.RemoteWriteUsername = "user"
+	conf.RemoteWritePassword = "secret"
+
+	msg, err := NewConfigSnapshot(conf).AsJson()
+	if err != nil {
+		t.Fatalf("AsJson() error = %v", err)
+	}
+	if strings.Contains(string(msg), "secret") {
+		t.Errorf("config snapshot contains credentials: %s", msg)
+	}
+	if !strings.Contains(string(msg), `"placement":"livingroom"`) {
+		t.Errorf("config snapshot is missing the placement: %s", msg)
+	}
+}
+
+func TestNewConfigSnapshot_temperatureUnit(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.TemperatureUnit = config.TemperatureUnitFahrenheit
+
+	msg, err := NewConfigSnapshot(conf).AsJson()
+	if err != nil {
+		t.Fatalf("AsJson() error = %v", err)
+	}
+	if !strings.Contains(string(msg), `"temperature_unit":"fahrenheit"`) {
+		t.Errorf("config snapshot is missing the temperature unit: %s", msg)
+	}
+}
+
+func TestNewSchema_scaled(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.PublishSeaLevelPressure = true
+	conf.MeasurementScales = map[string]float64{"pressure": 0.1}
+	conf.MeasurementOffsets = map[string]float64{"temperature": -1.5}
+
+	for _, m := range NewSchema(conf).Measurements {
+		var want SchemaMeasurement
+		switch m.Field {
+		case "pressure", "pressure_sealevel":
+			want = SchemaMeasurement{Field: m.Field, Unit: "Pa", Scale: 0.1}
+		case "temp":
+			want = SchemaMeasurement{Field: m.Field, Unit: m.Unit, Offset: -1.5}
+		default:
+			want = SchemaMeasurement{Field: m.Field, Unit: m.Unit}
+		}
+		if m != want {
+			t.Errorf("NewSchema() measurement = %+v, want %+v", m, want)
+		}
+	}
+
+	msg, err := NewConfigSnapshot(conf).AsJson()
+	if err != nil {
+		t.Fatalf("AsJson() error = %v", err)
+	}
+	if !strings.Contains(string(msg), `"measurement_scales":{"pressure":0.1}`) || !strings.Contains(string(msg), `"measurement_offsets":{"temperature":-1.5}`) {
+		t.Errorf("config snapshot is missing the scales and offsets: %s", msg)
+	}
+}
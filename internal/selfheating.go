@@ -0,0 +1,36 @@
+package internal
+
+import "time"
+
+// selfHeatingWindow is the period the read density is determined over for the self-heating compensation.
+const selfHeatingWindow = 10 * time.Minute
+
+// readDensity tracks the readings within the window to determine the duty cycle of the sensor.
+type readDensity struct {
+	window time.Duration
+	reads  []time.Time
+}
+
+func newReadDensity(window time.Duration) *readDensity {
+	return &readDensity{window: window}
+}
+
+// record adds a reading and returns the amount of readings per minute within the window. Until the window has
+// passed, the density is lower, like the heating up of the sensor.
+func (d *readDensity) record(now time.Time) float64 {
+	start := now.Add(-d.window)
+	kept := d.reads[:0]
+	for _, read := range d.reads {
+		if read.After(start) {
+			kept = append(kept, read)
+		}
+	}
+	d.reads = append(kept, now)
+	return float64(len(d.reads)) / d.window.Minutes()
+}
+
+// selfHeatingBias returns the bias in °C of the temperature caused by heating up the sensor with the given readings
+// per minute, modelled as linear to the read density.
+func selfHeatingBias(readsPerMinute, coefficient float64) float64 {
+	return readsPerMinute * coefficient
+}
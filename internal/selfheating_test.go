@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestReadDensity_record(t *testing.T) {
+	density := newReadDensity(10 * time.Minute)
+	start := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	var got float64
+	for i := 0; i < 40; i++ {
+		got = density.record(start.Add(time.Duration(i) * 30 * time.Second))
+	}
+	// only the readings within the last 10 minutes are counted
+	if got != 2 {
+		t.Errorf("record() = %f, want 2 readings per minute", got)
+	}
+
+	if got := density.record(start.Add(time.Hour)); got != 0.1 {
+		t.Errorf("record() = %f, want 0.1 readings per minute after a pause", got)
+	}
+}
+
+func Test_selfHeatingBias(t *testing.T) {
+	if got := selfHeatingBias(2, 0.15); math.Abs(got-0.3) > 1e-9 {
+		t.Errorf("selfHeatingBias() = %f, want 0.3", got)
+	}
+}
@@ -0,0 +1,78 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+// rated operating range of the BME280
+const (
+	selfTestMinTemperature = -40
+	selfTestMaxTemperature = 85
+	selfTestMinPressure    = 30000
+	selfTestMaxPressure    = 110000
+)
+
+type SelfTestResult struct {
+	Model       string
+	Temperature float32
+	Humidity    float32
+	Pressure    float32
+}
+
+func (r SelfTestResult) String() string {
+	ret := fmt.Sprintf("model=%s temperature=%.2f°C", r.Model, r.Temperature)
+	if r.Model != config.SensorTypeBmp280 {
+		ret += fmt.Sprintf(" humidity=%.2f%%", r.Humidity)
+	}
+	return ret + fmt.Sprintf(" pressure=%.2fhPa", r.Pressure/100)
+}
+
+// SelfTest reads all values from the sensor once and returns an error if the sensor can not be read or a value is
+// outside the rated range of the sensor. The humidity is not read if the configured or detected model is a BMP280.
+func SelfTest(sensor WeatherBotSensor, sensorType string, lock *BusLock) (SelfTestResult, error) {
+	if err := lock.lock(); err != nil {
+		return SelfTestResult{}, err
+	}
+	defer lock.unlock()
+
+	chipId, err := sensor.Read(strconv.Itoa(regChipId))
+	if err != nil {
+		return SelfTestResult{}, fmt.Errorf("could not read chip id: %w", err)
+	}
+	result := SelfTestResult{Model: sensorType}
+	if len(sensorType) == 0 || sensorType == config.SensorTypeAuto {
+		result.Model = sensorModel(chipId)
+		if len(result.Model) == 0 {
+			result.Model = fmt.Sprintf("unknown (chip id 0x%02x)", chipId)
+		}
+	}
+
+	if result.Temperature, err = sensor.Temperature(); err != nil {
+		return result, fmt.Errorf("could not read temperature: %w", err)
+	}
+	if result.Temperature < selfTestMinTemperature || result.Temperature > selfTestMaxTemperature {
+		return result, fmt.Errorf("implausible temperature %.2f°C, expected %d to %d°C", result.Temperature,
+			selfTestMinTemperature, selfTestMaxTemperature)
+	}
+
+	if result.Model != config.SensorTypeBmp280 {
+		if result.Humidity, err = sensor.Humidity(); err != nil {
+			return result, fmt.Errorf("could not read humidity: %w", err)
+		}
+		if result.Humidity < 0 || result.Humidity > 100 {
+			return result, fmt.Errorf("implausible humidity %.2f%%, expected 0 to 100%%", result.Humidity)
+		}
+	}
+
+	if result.Pressure, err = sensor.Pressure(); err != nil {
+		return result, fmt.Errorf("could not read pressure: %w", err)
+	}
+	if result.Pressure < selfTestMinPressure || result.Pressure > selfTestMaxPressure {
+		return result, fmt.Errorf("implausible pressure %.2fhPa, expected %d to %dhPa", result.Pressure/100,
+			selfTestMinPressure/100, selfTestMaxPressure/100)
+	}
+	return result, nil
+}
@@ -0,0 +1,103 @@
+package internal
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+type selfTestSensor struct {
+	FakeBme280
+	temperature float32
+	humidity    float32
+	pressure    float32
+	err         error
+}
+
+func (s *selfTestSensor) Temperature() (float32, error) {
+	return s.temperature, s.err
+}
+
+func (s *selfTestSensor) Humidity() (float32, error) {
+	return s.humidity, nil
+}
+
+func (s *selfTestSensor) Pressure() (float32, error) {
+	return s.pressure, nil
+}
+
+func TestSelfTest(t *testing.T) {
+	tests := []struct {
+		name       string
+		sensor     *selfTestSensor
+		sensorType string
+		wantModel  string
+		wantErr    bool
+	}{
+		{
+			name:      "plausible",
+			sensor:    &selfTestSensor{FakeBme280: FakeBme280{ChipId: chipIdBme280}, temperature: 21.5, humidity: 40, pressure: 101325},
+			wantModel: config.SensorTypeBme280,
+		},
+		{
+			name:      "bmp280 without humidity",
+			sensor:    &selfTestSensor{FakeBme280: FakeBme280{ChipId: chipIdBmp280}, temperature: 21.5, humidity: -1, pressure: 101325},
+			wantModel: config.SensorTypeBmp280,
+		},
+		{
+			name:       "configured type",
+			sensor:     &selfTestSensor{FakeBme280: FakeBme280{ChipId: 0x42}, temperature: 21.5, humidity: 40, pressure: 101325},
+			sensorType: config.SensorTypeBme280,
+			wantModel:  config.SensorTypeBme280,
+		},
+		{
+			name:      "unknown chip id",
+			sensor:    &selfTestSensor{FakeBme280: FakeBme280{ChipId: 0x42}, temperature: 21.5, humidity: 40, pressure: 101325},
+			wantModel: "unknown (chip id 0x42)",
+		},
+		{
+			name:    "read error",
+			sensor:  &selfTestSensor{FakeBme280: FakeBme280{ChipId: chipIdBme280}, err: errors.New("i2c timeout")},
+			wantErr: true,
+		},
+		{
+			name:    "implausible temperature",
+			sensor:  &selfTestSensor{FakeBme280: FakeBme280{ChipId: chipIdBme280}, temperature: 180, humidity: 40, pressure: 101325},
+			wantErr: true,
+		},
+		{
+			name:    "implausible humidity",
+			sensor:  &selfTestSensor{FakeBme280: FakeBme280{ChipId: chipIdBme280}, temperature: 21.5, humidity: 120, pressure: 101325},
+			wantErr: true,
+		},
+		{
+			name:    "implausible pressure",
+			sensor:  &selfTestSensor{FakeBme280: FakeBme280{ChipId: chipIdBme280}, temperature: 21.5, humidity: 40, pressure: 13.37},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SelfTest(tt.sensor, tt.sensorType, nil)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SelfTest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got.Model != tt.wantModel {
+				t.Errorf("SelfTest() model = %q, want %q", got.Model, tt.wantModel)
+			}
+		})
+	}
+}
+
+func TestSelfTestResult_String(t *testing.T) {
+	result := SelfTestResult{Model: config.SensorTypeBme280, Temperature: 21.5, Humidity: 40.25, Pressure: 101325}
+	if got, want := result.String(), "model=bme280 temperature=21.50°C humidity=40.25% pressure=1013.25hPa"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+
+	result.Model = config.SensorTypeBmp280
+	if got, want := result.String(), "model=bmp280 temperature=21.50°C pressure=1013.25hPa"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
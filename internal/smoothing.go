@@ -0,0 +1,118 @@
+package internal
+
+import "math"
+
+// smoothedFields are the measured values that are replaced by their moving average.
+var smoothedFields = []string{"altitude", "humidity", "pressure", "temperature"}
+
+// movingAverage keeps the last samples values of a field in a ring buffer.
+type movingAverage struct {
+	samples int
+	window  []float64
+	pos     int
+}
+
+func newMovingAverage(samples int) *movingAverage {
+	return &movingAverage{
+		samples: samples,
+		window:  make([]float64, 0, samples),
+	}
+}
+
+// record adds the value and returns the mean of the recorded values. Until samples values are recorded, the mean of
+// the values recorded so far is returned.
+func (a *movingAverage) record(value float64) float64 {
+	if len(a.window) < a.samples {
+		a.window = append(a.window, value)
+	} else {
+		a.window[a.pos] = value
+		a.pos = (a.pos + 1) % a.samples
+	}
+
+	var sum float64
+	for _, v := range a.window {
+		sum += v
+	}
+	return sum / float64(len(a.window))
+}
+
+// stddev returns the standard deviation of the recorded values.
+func (a *movingAverage) stddev() float64 {
+	if len(a.window) == 0 {
+		return 0
+	}
+
+	var sum float64
+	for _, v := range a.window {
+		sum += v
+	}
+	mean := sum / float64(len(a.window))
+
+	var squares float64
+	for _, v := range a.window {
+		squares += (v - mean) * (v - mean)
+	}
+	return math.Sqrt(squares / float64(len(a.window)))
+}
+
+func (a *movingAverage) reset() {
+	a.window = a.window[:0]
+	a.pos = 0
+}
+
+// smoother replaces the values of the measurements by the moving average of the last samples readings of each field.
+type smoother struct {
+	averages map[string]*movingAverage
+}
+
+func newSmoother(samples int) *smoother {
+	s := &smoother{}
+	if samples <= 1 {
+		return s
+	}
+	s.averages = make(map[string]*movingAverage, len(smoothedFields))
+	for _, name := range smoothedFields {
+		s.averages[name] = newMovingAverage(samples)
+	}
+	return s
+}
+
+func (s *smoother) enabled() bool {
+	return len(s.averages) > 0
+}
+
+// smooth returns the measurement with its values replaced by their moving averages. Values that could not be read
+// are neither recorded nor replaced.
+func (s *smoother) smooth(m Measurement) Measurement {
+	if !s.enabled() {
+		return m
+	}
+
+	for name, value := range map[string]*float32{
+		"altitude":    &m.Altitude,
+		"humidity":    &m.Humidity,
+		"pressure":    &m.Pressure,
+		"temperature": &m.Temperature,
+	} {
+		if m.Failed(name) {
+			continue
+		}
+		*value = float32(s.averages[name].record(float64(*value)))
+	}
+	return m
+}
+
+// stddev returns the standard deviation of the field over the window, or false if the field is not smoothed.
+func (s *smoother) stddev(name string) (float64, bool) {
+	average, ok := s.averages[name]
+	if !ok {
+		return 0, false
+	}
+	return average.stddev(), true
+}
+
+func (s *smoother) reset() {
+	for _, average := range s.averages {
+		average.reset()
+	}
+}
@@ -0,0 +1,83 @@
+package internal
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func Test_movingAverage(t *testing.T) {
+	average := newMovingAverage(3)
+	for _, tt := range []struct {
+		value float64
+		want  float64
+	}{
+		{value: 3, want: 3},
+		{value: 6, want: 4.5},
+		{value: 9, want: 6},
+		{value: 12, want: 9},
+		{value: 0, want: 7},
+	} {
+		if got := average.record(tt.value); got != tt.want {
+			t.Errorf("record(%v) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	average.reset()
+	if got := average.record(1); got != 1 {
+		t.Errorf("record() after reset = %v, want 1", got)
+	}
+}
+
+func Test_movingAverage_stddev(t *testing.T) {
+	average := newMovingAverage(8)
+	if got := average.stddev(); got != 0 {
+		t.Errorf("stddev() without values = %v, want 0", got)
+	}
+	for _, value := range []float64{2, 4, 4, 4, 5, 5, 7, 9} {
+		average.record(value)
+	}
+	if got := average.stddev(); got != 2 {
+		t.Errorf("stddev() = %v, want 2", got)
+	}
+
+	// the oldest value drops out of the window
+	average.record(5)
+	if got := average.stddev(); math.Abs(got-math.Sqrt(2.734375)) > 0.0001 {
+		t.Errorf("stddev() = %v, want %v", got, math.Sqrt(2.734375))
+	}
+}
+
+func Test_smoother(t *testing.T) {
+	s := newSmoother(2)
+	s.smooth(Measurement{Temperature: 20, Humidity: 40, Pressure: 1000, Altitude: 100})
+
+	m := Measurement{Temperature: 22, Humidity: 50, Pressure: 1010, Altitude: 90}
+	m.AddHumidity(0, errors.New("humidity not available"))
+	got := s.smooth(m)
+	if got.Temperature != 21 || got.Pressure != 1005 || got.Altitude != 95 {
+		t.Errorf("smooth() = %+v, want the mean of both readings", got)
+	}
+	if got.Humidity != 50 {
+		t.Errorf("expected the failed humidity not to be smoothed, got %v", got.Humidity)
+	}
+
+	got = s.smooth(Measurement{Temperature: 24, Humidity: 60, Pressure: 1020, Altitude: 80})
+	if got.Temperature != 23 || got.Humidity != 50 {
+		t.Errorf("smooth() = %+v, want temperature 23 and humidity 50", got)
+	}
+}
+
+func Test_smoother_disabled(t *testing.T) {
+	for _, samples := range []int{0, 1} {
+		s := newSmoother(samples)
+		if s.enabled() {
+			t.Errorf("expected smoothing to be disabled for %d samples", samples)
+		}
+		m := Measurement{Temperature: 20}
+		if got := s.smooth(m); got.Temperature != 20 {
+			t.Errorf("smooth() = %v, want 20", got.Temperature)
+		}
+		s.reset()
+	}
+}
@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+)
+
+// readingsSocketWriteTimeout bounds the time a client may take to receive the readings.
+const readingsSocketWriteTimeout = 2 * time.Second
+
+// latestReadings holds the latest reading of each sensor, which is served on the readings socket.
+var latestReadings = &readingsSnapshot{readings: map[string]Measurement{}}
+
+type readingsSnapshot struct {
+	mu       sync.Mutex
+	readings map[string]Measurement
+}
+
+func (s *readingsSnapshot) record(placement string, m Measurement) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.readings[placement] = m
+}
+
+// AsJson returns the latest readings keyed by the placement of the sensor.
+func (s *readingsSnapshot) AsJson() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return json.Marshal(s.readings)
+}
+
+// ReadingsSocket serves the latest readings as json on a unix domain socket, so local scripts can poll them without
+// HTTP or MQTT. Each client receives the readings right after connecting, then the connection is closed.
+type ReadingsSocket struct {
+	listener net.Listener
+}
+
+// ListenReadingsSocket listens on the socket at path, replacing a stale socket file left behind by a previous run.
+func ListenReadingsSocket(path string) (*ReadingsSocket, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("could not remove stale socket: %w", err)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, fmt.Errorf("could not listen on socket: %w", err)
+	}
+
+	socket := &ReadingsSocket{listener: listener}
+	go socket.serve()
+	return socket, nil
+}
+
+// Close stops listening, which removes the socket file.
+func (s *ReadingsSocket) Close() error {
+	return s.listener.Close()
+}
+
+func (s *ReadingsSocket) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if errors.Is(err, net.ErrClosed) {
+			return
+		}
+		if err != nil {
+			slog.Warn("Could not accept connection on readings socket", "err", err)
+			continue
+		}
+		go handleReadingsClient(conn)
+	}
+}
+
+func handleReadingsClient(conn net.Conn) {
+	defer conn.Close()
+
+	msg, err := latestReadings.AsJson()
+	if err != nil {
+		slog.Error("Could not marshal readings", "err", err)
+		return
+	}
+	_ = conn.SetWriteDeadline(time.Now().Add(readingsSocketWriteTimeout))
+	if _, err := conn.Write(append(msg, '\n')); err != nil {
+		slog.Warn("Could not write readings to socket client", "err", err)
+	}
+}
@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadingsSocket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.sock")
+	latestReadings.record("socket_test", Measurement{Temperature: 21.5, Timestamp: 1700000000})
+
+	socket, err := ListenReadingsSocket(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, err := io.ReadAll(conn)
+	_ = conn.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	readings := map[string]Measurement{}
+	if err := json.Unmarshal(body, &readings); err != nil {
+		t.Fatalf("could not decode %q: %v", body, err)
+	}
+	if got := readings["socket_test"]; got.Temperature != 21.5 || got.Timestamp != 1700000000 {
+		t.Errorf("reading = %+v, want the recorded reading", got)
+	}
+
+	if err := socket.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected the socket file to be removed, got %v", err)
+	}
+}
+
+func TestListenReadingsSocket_stale(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "readings.sock")
+	if err := os.WriteFile(path, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	socket, err := ListenReadingsSocket(path)
+	if err != nil {
+		t.Fatalf("expected the stale socket file to be replaced, got %v", err)
+	}
+	_ = socket.Close()
+}
@@ -0,0 +1,203 @@
+package internal
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// maxSpoolRecordBytes is the maximum size of a single record that is read back from the spool.
+const maxSpoolRecordBytes = 1024 * 1024
+
+// Spool is an append-only file of messages that could not be published, so they survive outages of the broker and
+// restarts. Each message is stored as a json record per line, together with the time it was spooled. Once the file
+// exceeds the maximum size, the oldest records are dropped.
+type Spool struct {
+	path     string
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	records int
+}
+
+type spoolRecord struct {
+	Timestamp time.Time `json:"timestamp"`
+	Message   []byte    `json:"message"`
+}
+
+// NewSpool opens the spool at path, creating its directory if needed. Records that can not be decoded, such as a
+// partially written last record, are removed from the file. A maxBytes of 0 does not limit the size.
+func NewSpool(path string, maxBytes int64) (*Spool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		return nil, fmt.Errorf("could not create spool dir: %w", err)
+	}
+
+	spool := &Spool{path: path, maxBytes: maxBytes}
+	records, skipped, err := spool.read()
+	if err != nil {
+		return nil, err
+	}
+	if skipped > 0 {
+		slog.Warn("Skipping corrupt records in spool", "file", path, "count", skipped)
+		if err := spool.rewrite(records); err != nil {
+			return nil, err
+		}
+		return spool, nil
+	}
+	spool.records = len(records)
+	for _, record := range records {
+		line, _ := encodeSpoolRecord(record)
+		spool.size += int64(len(line))
+	}
+	return spool, nil
+}
+
+// Len returns the amount of spooled messages.
+func (s *Spool) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.records
+}
+
+// Append adds the message to the end of the spool and returns the amount of the oldest records that were dropped
+// to stay within the maximum size.
+func (s *Spool) Append(msg []byte, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line, err := encodeSpoolRecord(spoolRecord{Timestamp: now, Message: msg})
+	if err != nil {
+		return 0, err
+	}
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0640)
+	if err != nil {
+		return 0, fmt.Errorf("could not open spool: %w", err)
+	}
+	_, err = file.Write(line)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return 0, fmt.Errorf("could not write to spool: %w", err)
+	}
+	s.size += int64(len(line))
+	s.records++
+
+	if s.maxBytes <= 0 || s.size <= s.maxBytes {
+		return 0, nil
+	}
+	return s.truncate()
+}
+
+// Drain hands the spooled messages to publish in the order they were spooled, stopping at the first message that
+// fails to publish. Messages that were published successfully are removed from the spool.
+func (s *Spool) Drain(publish func(msg []byte) bool) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, _, err := s.read()
+	if err != nil {
+		return 0, err
+	}
+	drained := 0
+	for drained < len(records) && publish(records[drained].Message) {
+		drained++
+	}
+	if drained == 0 {
+		return 0, nil
+	}
+	return drained, s.rewrite(records[drained:])
+}
+
+// truncate drops the oldest records until the spool fits into the maximum size.
+func (s *Spool) truncate() (int, error) {
+	records, _, err := s.read()
+	if err != nil {
+		return 0, err
+	}
+	size := s.size
+	dropped := 0
+	for dropped < len(records) && size > s.maxBytes {
+		line, _ := encodeSpoolRecord(records[dropped])
+		size -= int64(len(line))
+		dropped++
+	}
+	return dropped, s.rewrite(records[dropped:])
+}
+
+// read returns the decodable records of the spool and the amount of records that were skipped.
+func (s *Spool) read() ([]spoolRecord, int, error) {
+	file, err := os.Open(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, 0, nil
+	}
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not open spool: %w", err)
+	}
+	defer file.Close()
+
+	var records []spoolRecord
+	skipped := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxSpoolRecordBytes)
+	for scanner.Scan() {
+		var record spoolRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			skipped++
+			continue
+		}
+		records = append(records, record)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, 0, fmt.Errorf("could not read spool: %w", err)
+	}
+	return records, skipped, nil
+}
+
+// rewrite atomically replaces the spool with the given records, removing the file if there are none.
+func (s *Spool) rewrite(records []spoolRecord) error {
+	if len(records) == 0 {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("could not remove spool: %w", err)
+		}
+		s.records = 0
+		s.size = 0
+		return nil
+	}
+
+	var data []byte
+	for _, record := range records {
+		line, err := encodeSpoolRecord(record)
+		if err != nil {
+			return err
+		}
+		data = append(data, line...)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0640); err != nil {
+		return fmt.Errorf("could not write spool: %w", err)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return fmt.Errorf("could not replace spool: %w", err)
+	}
+	s.records = len(records)
+	s.size = int64(len(data))
+	return nil
+}
+
+func encodeSpoolRecord(record spoolRecord) ([]byte, error) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return nil, fmt.Errorf("could not encode spool record: %w", err)
+	}
+	return append(line, '\n'), nil
+}
@@ -0,0 +1,124 @@
+package internal
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func drainAll(t *testing.T, spool *Spool) []string {
+	var drained []string
+	if _, err := spool.Drain(func(msg []byte) bool {
+		drained = append(drained, string(msg))
+		return true
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return drained
+}
+
+func TestSpool_Append(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "spool", "attic.spool")
+	spool, err := NewSpool(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, msg := range []string{"a", "b"} {
+		if dropped, err := spool.Append([]byte(msg), time.Unix(1700000000, 0)); err != nil || dropped != 0 {
+			t.Fatalf("Append() = %d, %v, want 0, nil", dropped, err)
+		}
+	}
+	if spool.Len() != 2 {
+		t.Errorf("Len() = %d, want 2", spool.Len())
+	}
+
+	reopened, err := NewSpool(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Len() != 2 {
+		t.Errorf("Len() after reopening = %d, want 2", reopened.Len())
+	}
+	records, _, _ := reopened.read()
+	if !records[0].Timestamp.Equal(time.Unix(1700000000, 0)) {
+		t.Errorf("timestamp = %v, want %v", records[0].Timestamp, time.Unix(1700000000, 0))
+	}
+}
+
+func TestSpool_Drain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "attic.spool")
+	spool, _ := NewSpool(path, 0)
+	for _, msg := range []string{"a", "b", "c"} {
+		_, _ = spool.Append([]byte(msg), time.Now())
+	}
+
+	var published []string
+	drained, err := spool.Drain(func(msg []byte) bool {
+		if len(published) == 1 {
+			return false
+		}
+		published = append(published, string(msg))
+		return true
+	})
+	if err != nil || drained != 1 || spool.Len() != 2 {
+		t.Fatalf("Drain() = %d, %v, Len() = %d, want 1, nil, 2", drained, err, spool.Len())
+	}
+
+	published = append(published, drainAll(t, spool)...)
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(published, want) {
+		t.Errorf("drained %v, want %v", published, want)
+	}
+	if spool.Len() != 0 {
+		t.Errorf("Len() = %d, want 0", spool.Len())
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected drained spool to be removed, got %v", err)
+	}
+}
+
+func TestSpool_truncate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "attic.spool")
+	line, _ := encodeSpoolRecord(spoolRecord{Timestamp: time.Unix(1700000000, 0), Message: []byte("a")})
+	spool, _ := NewSpool(path, int64(2*len(line)))
+
+	dropped := 0
+	for _, msg := range []string{"a", "b", "c", "d"} {
+		n, err := spool.Append([]byte(msg), time.Unix(1700000000, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		dropped += n
+	}
+	if dropped != 2 {
+		t.Errorf("dropped %d records, want 2", dropped)
+	}
+	if want := []string{"c", "d"}; !reflect.DeepEqual(drainAll(t, spool), want) {
+		t.Errorf("expected the oldest records to be dropped, want %v", want)
+	}
+}
+
+func TestSpool_skipsPartialRecord(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "attic.spool")
+	spool, _ := NewSpool(path, 0)
+	_, _ = spool.Append([]byte("a"), time.Now())
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0640)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, _ = file.WriteString(`{"timestamp":"2023-11-14T22:13:20Z","mess`)
+	_ = file.Close()
+
+	reopened, err := NewSpool(path, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if reopened.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", reopened.Len())
+	}
+	_, _ = reopened.Append([]byte("b"), time.Now())
+	if want := []string{"a", "b"}; !reflect.DeepEqual(drainAll(t, reopened), want) {
+		t.Errorf("expected the partial record to be skipped, want %v", want)
+	}
+}
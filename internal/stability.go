@@ -0,0 +1,57 @@
+package internal
+
+// stabilityGate considers readings stable once the temperatures of the last samples readings are within threshold
+// of each other.
+type stabilityGate struct {
+	samples   int
+	threshold float64
+	window    []float64
+	pos       int
+}
+
+func newStabilityGate(samples int, threshold float64) *stabilityGate {
+	return &stabilityGate{
+		samples:   samples,
+		threshold: threshold,
+		window:    make([]float64, 0, samples),
+	}
+}
+
+// record adds the temperature of a reading and returns whether the readings are considered stable.
+func (g *stabilityGate) record(temperature float64) bool {
+	if g.samples <= 1 {
+		return true
+	}
+
+	if len(g.window) < g.samples {
+		g.window = append(g.window, temperature)
+	} else {
+		g.window[g.pos] = temperature
+		g.pos = (g.pos + 1) % g.samples
+	}
+
+	return g.isStable()
+}
+
+// reset discards the recorded readings, so the readings have to stabilize again.
+func (g *stabilityGate) reset() {
+	g.window = g.window[:0]
+	g.pos = 0
+}
+
+func (g *stabilityGate) isStable() bool {
+	if len(g.window) < g.samples {
+		return false
+	}
+
+	min, max := g.window[0], g.window[0]
+	for _, val := range g.window[1:] {
+		if val < min {
+			min = val
+		}
+		if val > max {
+			max = val
+		}
+	}
+	return max-min <= g.threshold
+}
@@ -0,0 +1,73 @@
+package internal
+
+import "testing"
+
+func TestStabilityGate_record(t *testing.T) {
+	tests := []struct {
+		name         string
+		samples      int
+		threshold    float64
+		temperatures []float64
+		want         bool
+	}{
+		{
+			name:         "disabled",
+			samples:      0,
+			temperatures: []float64{20},
+			want:         true,
+		},
+		{
+			name:         "not enough samples",
+			samples:      3,
+			threshold:    0.5,
+			temperatures: []float64{20, 20},
+			want:         false,
+		},
+		{
+			name:         "stable",
+			samples:      3,
+			threshold:    0.5,
+			temperatures: []float64{20, 20.2, 20.5},
+			want:         true,
+		},
+		{
+			name:         "drifting",
+			samples:      3,
+			threshold:    0.5,
+			temperatures: []float64{20, 20.4, 20.8},
+			want:         false,
+		},
+		{
+			name:         "stabilized after drift",
+			samples:      3,
+			threshold:    0.5,
+			temperatures: []float64{18, 19, 20.8, 21, 21.1},
+			want:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			g := newStabilityGate(tt.samples, tt.threshold)
+			var got bool
+			for _, temp := range tt.temperatures {
+				got = g.record(temp)
+			}
+			if got != tt.want {
+				t.Errorf("record() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStabilityGate_reset(t *testing.T) {
+	gate := newStabilityGate(2, 0.5)
+	gate.record(20)
+	if !gate.record(20.1) {
+		t.Fatalf("expected readings to be stable")
+	}
+
+	gate.reset()
+	if gate.record(20.1) {
+		t.Errorf("expected readings to stabilize again after reset")
+	}
+}
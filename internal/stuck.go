@@ -0,0 +1,53 @@
+package internal
+
+import "math"
+
+// stuckDetector considers the values of a field stuck once more than threshold consecutive reads returned the bit for
+// bit identical value, as a degraded bus may keep returning the same registers.
+type stuckDetector struct {
+	threshold int
+	last      map[string]uint32
+	reads     map[string]int
+	stuck     map[string]bool
+}
+
+func newStuckDetector(threshold int) *stuckDetector {
+	return &stuckDetector{
+		threshold: threshold,
+		last:      map[string]uint32{},
+		reads:     map[string]int{},
+		stuck:     map[string]bool{},
+	}
+}
+
+func (d *stuckDetector) enabled() bool {
+	return d.threshold > 0
+}
+
+// record adds the value of the field and returns whether the field is considered stuck and whether that changed with
+// this read. The values are compared by their bits, so the check is not affected by rounding or NaN.
+func (d *stuckDetector) record(name string, value float32) (stuck, changed bool) {
+	if !d.enabled() {
+		return false, false
+	}
+
+	bits := math.Float32bits(value)
+	if last, ok := d.last[name]; ok && last == bits {
+		d.reads[name]++
+	} else {
+		d.reads[name] = 1
+	}
+	d.last[name] = bits
+
+	stuck = d.reads[name] > d.threshold
+	changed = stuck != d.stuck[name]
+	d.stuck[name] = stuck
+	return stuck, changed
+}
+
+// reset discards the recorded values, so a field is only considered stuck after further identical reads. A field that
+// was stuck is reported as changed on its next read.
+func (d *stuckDetector) reset() {
+	d.last = map[string]uint32{}
+	d.reads = map[string]int{}
+}
@@ -0,0 +1,59 @@
+package internal
+
+import (
+	"math"
+	"testing"
+)
+
+func Test_stuckDetector(t *testing.T) {
+	d := newStuckDetector(2)
+	for i, tt := range []struct {
+		value       float32
+		wantStuck   bool
+		wantChanged bool
+	}{
+		{value: 21.5},
+		{value: 21.5},
+		{value: 21.5, wantStuck: true, wantChanged: true},
+		{value: 21.5, wantStuck: true},
+		{value: 21.500002, wantChanged: true},
+		{value: 21.500002},
+	} {
+		stuck, changed := d.record("temperature", tt.value)
+		if stuck != tt.wantStuck || changed != tt.wantChanged {
+			t.Errorf("read %d: record(%v) = %t, %t, want %t, %t", i, tt.value, stuck, changed, tt.wantStuck, tt.wantChanged)
+		}
+	}
+
+	if stuck, _ := d.record("humidity", 40); stuck {
+		t.Errorf("expected the fields to be tracked independently")
+	}
+}
+
+func Test_stuckDetector_nan(t *testing.T) {
+	d := newStuckDetector(1)
+	nan := float32(math.NaN())
+	d.record("pressure", nan)
+	if stuck, _ := d.record("pressure", nan); !stuck {
+		t.Errorf("expected identical NaN values to be considered stuck")
+	}
+}
+
+func Test_stuckDetector_reset(t *testing.T) {
+	d := newStuckDetector(1)
+	d.record("pressure", 1000)
+	d.record("pressure", 1000)
+	d.reset()
+	if stuck, changed := d.record("pressure", 1000); stuck || !changed {
+		t.Errorf("record() after reset = %t, %t, want false, true", stuck, changed)
+	}
+}
+
+func Test_stuckDetector_disabled(t *testing.T) {
+	d := newStuckDetector(0)
+	for i := 0; i < 5; i++ {
+		if stuck, changed := d.record("pressure", 1000); stuck || changed {
+			t.Errorf("expected a disabled detector to never report values as stuck")
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package internal
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"log/syslog"
+	"strings"
+	"sync"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+var syslogFacilities = map[string]syslog.Priority{
+	"user":   syslog.LOG_USER,
+	"daemon": syslog.LOG_DAEMON,
+	"local0": syslog.LOG_LOCAL0,
+	"local1": syslog.LOG_LOCAL1,
+	"local2": syslog.LOG_LOCAL2,
+	"local3": syslog.LOG_LOCAL3,
+	"local4": syslog.LOG_LOCAL4,
+	"local5": syslog.LOG_LOCAL5,
+	"local6": syslog.LOG_LOCAL6,
+	"local7": syslog.LOG_LOCAL7,
+}
+
+// SyslogSink sends a structured message for each measurement to a syslog daemon. The connection is established
+// lazily and re-established after errors, so an unavailable daemon only causes the affected messages to be lost.
+type SyslogSink struct {
+	network  string
+	address  string
+	priority syslog.Priority
+	mu       sync.Mutex
+	writer   *syslog.Writer
+}
+
+func NewSyslogSink(network, address, facility string) *SyslogSink {
+	priority, ok := syslogFacilities[facility]
+	if !ok {
+		priority = syslog.LOG_USER
+	}
+	return &SyslogSink{
+		network:  network,
+		address:  address,
+		priority: priority | syslog.LOG_INFO,
+	}
+}
+
+// Write sends the measurement to the syslog daemon.
+func (s *SyslogSink) Write(m Measurement, placement string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.writer == nil {
+		writer, err := syslog.Dial(s.network, s.address, s.priority, config.BotName)
+		if err != nil {
+			return fmt.Errorf("could not connect to syslog: %w", err)
+		}
+		s.writer = writer
+	}
+
+	if err := s.writer.Info(syslogMessage(m, placement)); err != nil {
+		_ = s.writer.Close()
+		s.writer = nil
+		return fmt.Errorf("could not write to syslog: %w", err)
+	}
+	return nil
+}
+
+// syslogMessage formats the measurement with the attributes of the logged readings and its timestamp and errors. The
+// time and level are omitted, as they are part of the syslog message already.
+func syslogMessage(m Measurement, placement string) string {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && (a.Key == slog.TimeKey || a.Key == slog.LevelKey) {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	if len(m.CorrelationId) > 0 {
+		logger = logger.With("correlation_id", m.CorrelationId)
+	}
+
+	args := append(readingAttrs(m, placement), "timestamp", m.Timestamp)
+	var errs []string
+	for _, err := range m.Errors {
+		if len(err) > 0 {
+			errs = append(errs, err)
+		}
+	}
+	if len(errs) > 0 {
+		args = append(args, "errors", strings.Join(errs, "; "))
+	}
+	logger.Info(readingLogMessage, args...)
+	return strings.TrimSuffix(buf.String(), "\n")
+}
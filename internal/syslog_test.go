@@ -0,0 +1,48 @@
+package internal
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_syslogMessage(t *testing.T) {
+	m := Measurement{Timestamp: 1700000000, Temperature: 21.5, Humidity: 40, Pressure: 101300}
+	want := `msg="Read sensor" placement="living room" temperature=21.5 humidity=40 pressure=101300 timestamp=1700000000`
+	if got := syslogMessage(m, "living room"); got != want {
+		t.Errorf("syslogMessage() = %q, want %q", got, want)
+	}
+
+	m.AddHumidity(0, errors.New("humidity not available"))
+	want = `msg="Read sensor" placement="living room" temperature=21.5 humidity=40 pressure=101300 timestamp=1700000000 errors="humidity not available"`
+	if got := syslogMessage(m, "living room"); got != want {
+		t.Errorf("syslogMessage() = %q, want %q", got, want)
+	}
+}
+
+func TestSyslogSink_Write(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	sink := NewSyslogSink("udp", conn.LocalAddr().String(), "local3")
+	if err := sink.Write(Measurement{Timestamp: 1, Temperature: 20}, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := make([]byte, 1024)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := string(buf[:n])
+	// local3 (19) * 8 + info (6)
+	if !strings.HasPrefix(msg, "<158>") || !strings.Contains(msg, `placement=test temperature=20 humidity=0 pressure=0 timestamp=1`) {
+		t.Errorf("unexpected syslog message %q", msg)
+	}
+}
@@ -0,0 +1,62 @@
+package internal
+
+import "time"
+
+const (
+	// tendencyPeriod is the period the pressure tendency is normalized to, following the meteorological convention
+	tendencyPeriod = 3 * time.Hour
+	// minTendencySpan is the minimum span of the samples to report a tendency
+	minTendencySpan = 30 * time.Minute
+)
+
+type tendencySample struct {
+	at    time.Time
+	value float64
+}
+
+// tendencyWindow keeps the samples of a value within a window to determine its rate of change independent of the
+// interval of the readings.
+type tendencyWindow struct {
+	window  time.Duration
+	samples []tendencySample
+}
+
+func newTendencyWindow(window time.Duration) *tendencyWindow {
+	return &tendencyWindow{window: window}
+}
+
+func (w *tendencyWindow) record(value float64, now time.Time) {
+	start := now.Add(-w.window)
+	kept := w.samples[:0]
+	for _, sample := range w.samples {
+		if sample.at.After(start) {
+			kept = append(kept, sample)
+		}
+	}
+	w.samples = append(kept, tendencySample{at: now, value: value})
+}
+
+func (w *tendencyWindow) reset() {
+	w.samples = w.samples[:0]
+}
+
+// rate returns the change of the value per period, determined by a least squares fit of the samples within the
+// window. It returns false until the samples span at least minTendencySpan.
+func (w *tendencyWindow) rate(period time.Duration) (float64, bool) {
+	if len(w.samples) < 2 || w.samples[len(w.samples)-1].at.Sub(w.samples[0].at) < minTendencySpan {
+		return 0, false
+	}
+
+	first := w.samples[0].at
+	var sumX, sumY, sumXY, sumXX float64
+	for _, sample := range w.samples {
+		x := sample.at.Sub(first).Seconds()
+		sumX += x
+		sumY += sample.value
+		sumXY += x * sample.value
+		sumXX += x * x
+	}
+	n := float64(len(w.samples))
+	slope := (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+	return slope * period.Seconds(), true
+}
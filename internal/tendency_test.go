@@ -0,0 +1,31 @@
+package internal
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestTendencyWindow_rate(t *testing.T) {
+	window := newTendencyWindow(tendencyPeriod)
+	start := time.Date(2023, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	// falling by 1 hPa per hour, read every 5 minutes
+	for i := 0; i < 6; i++ {
+		window.record(1000-float64(i)/12, start.Add(time.Duration(i)*5*time.Minute))
+	}
+	if _, ok := window.rate(tendencyPeriod); ok {
+		t.Error("expected no tendency for samples spanning less than 30 minutes")
+	}
+
+	for i := 6; i < 60; i++ {
+		window.record(1000-float64(i)/12, start.Add(time.Duration(i)*5*time.Minute))
+	}
+	got, ok := window.rate(tendencyPeriod)
+	if !ok || math.Abs(got+3) > 1e-6 {
+		t.Errorf("rate() = %f, %v, want -3, true", got, ok)
+	}
+	if len(window.samples) != 36 {
+		t.Errorf("expected only the samples of the last 3 hours to be kept, got %d", len(window.samples))
+	}
+}
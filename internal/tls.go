@@ -0,0 +1,81 @@
+package internal
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+// BuildTlsConfig builds the TLS config for connecting to the MQTT broker using client certificates. Returns nil
+// if no client certificates are configured.
+func BuildTlsConfig(conf config.MqttConfig) (*tls.Config, error) {
+	if !conf.UsesSslCerts() {
+		return nil, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(conf.ClientCertFile, conf.ClientKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load client certificate: %w", err)
+	}
+
+	tlsConfig := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+
+	if len(conf.ServerCaFile) > 0 {
+		pool, err := loadCertPool(conf.ServerCaFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+// loadCertPool builds a cert pool from either a single file or all .pem and .crt files within a directory.
+func loadCertPool(path string) (*x509.CertPool, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("could not read server CA: %w", err)
+	}
+
+	files := []string{path}
+	if info.IsDir() {
+		entries, err := os.ReadDir(path)
+		if err != nil {
+			return nil, fmt.Errorf("could not read server CA directory: %w", err)
+		}
+
+		files = nil
+		for _, entry := range entries {
+			ext := strings.ToLower(filepath.Ext(entry.Name()))
+			if !entry.IsDir() && (ext == ".pem" || ext == ".crt") {
+				files = append(files, filepath.Join(path, entry.Name()))
+			}
+		}
+	}
+
+	pool := x509.NewCertPool()
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("could not read server CA: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificates found in %s", file)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, errors.New("no server CA certificates found in " + path)
+	}
+	return pool, nil
+}
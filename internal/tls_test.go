@@ -0,0 +1,90 @@
+package internal
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeCaCert(t *testing.T, path string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func Test_loadCertPool(t *testing.T) {
+	dir := t.TempDir()
+	writeCaCert(t, filepath.Join(dir, "a.pem"))
+	writeCaCert(t, filepath.Join(dir, "b.crt"))
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("not a cert"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	emptyDir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		path    string
+		wantErr bool
+	}{
+		{
+			name: "single file",
+			path: filepath.Join(dir, "a.pem"),
+		},
+		{
+			name: "directory",
+			path: dir,
+		},
+		{
+			name:    "empty directory",
+			path:    emptyDir,
+			wantErr: true,
+		},
+		{
+			name:    "no certs in file",
+			path:    filepath.Join(dir, "README"),
+			wantErr: true,
+		},
+		{
+			name:    "missing",
+			path:    filepath.Join(dir, "missing.pem"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pool, err := loadCertPool(tt.path)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("loadCertPool() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && pool == nil {
+				t.Errorf("loadCertPool() returned nil pool")
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+package internal
+
+import (
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+// toTemperatureUnit converts a temperature in degrees celsius to the given unit.
+func toTemperatureUnit(celsius float64, unit string) float64 {
+	if unit == config.TemperatureUnitFahrenheit {
+		return celsius*1.8 + 32
+	}
+	return celsius
+}
+
+// toTemperatureDeltaUnit converts a difference of temperatures in kelvin to the given unit.
+func toTemperatureDeltaUnit(kelvin float64, unit string) float64 {
+	if unit == config.TemperatureUnitFahrenheit {
+		return kelvin * 1.8
+	}
+	return kelvin
+}
+
+// temperatureUnitName returns the name of the unit, defaulting to celsius.
+func temperatureUnitName(unit string) string {
+	if unit == config.TemperatureUnitFahrenheit {
+		return config.TemperatureUnitFahrenheit
+	}
+	return config.TemperatureUnitCelsius
+}
+
+// temperatureUnitSymbol returns the symbol of the unit, defaulting to celsius.
+func temperatureUnitSymbol(unit string) string {
+	if unit == config.TemperatureUnitFahrenheit {
+		return "°F"
+	}
+	return "°C"
+}
@@ -0,0 +1,43 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func Test_toTemperatureUnit(t *testing.T) {
+	tests := []struct {
+		celsius float64
+		unit    string
+		want    float64
+	}{
+		{celsius: 0, unit: config.TemperatureUnitFahrenheit, want: 32},
+		{celsius: 100, unit: config.TemperatureUnitFahrenheit, want: 212},
+		{celsius: -40, unit: config.TemperatureUnitFahrenheit, want: -40},
+		{celsius: 21.5, unit: config.TemperatureUnitCelsius, want: 21.5},
+		{celsius: 21.5, unit: "", want: 21.5},
+	}
+	for _, tt := range tests {
+		if got := toTemperatureUnit(tt.celsius, tt.unit); got != tt.want {
+			t.Errorf("toTemperatureUnit(%f, %q) = %f, want %f", tt.celsius, tt.unit, got, tt.want)
+		}
+	}
+
+	if got := toTemperatureDeltaUnit(10, config.TemperatureUnitFahrenheit); got != 18 {
+		t.Errorf("toTemperatureDeltaUnit(10) = %f, want 18", got)
+	}
+}
+
+func TestMeasurement_InTemperatureUnit(t *testing.T) {
+	m := NewMeasurement()
+	m.Temperature = 20
+	if got := m.InTemperatureUnit(config.TemperatureUnitFahrenheit).Temperature; got != 68 {
+		t.Errorf("InTemperatureUnit() = %f, want 68", got)
+	}
+
+	m.failed = []string{"temperature"}
+	if got := m.InTemperatureUnit(config.TemperatureUnitFahrenheit).Temperature; got != 20 {
+		t.Errorf("InTemperatureUnit() of failed temperature = %f, want 20", got)
+	}
+}
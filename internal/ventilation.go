@@ -0,0 +1,60 @@
+package internal
+
+import (
+	"encoding/json"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// maxOutdoorReferenceAge is the age after which the outdoor reference is considered stale and not used anymore.
+const maxOutdoorReferenceAge = time.Hour
+
+// outdoorReference holds the absolute humidity of the latest outdoor reading, received via MQTT.
+type outdoorReference struct {
+	mu               sync.Mutex
+	absoluteHumidity float64
+	updated          time.Time
+}
+
+func (r *outdoorReference) set(absoluteHumidity float64, now time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.absoluteHumidity = absoluteHumidity
+	r.updated = now
+}
+
+// get returns the absolute humidity of the outdoor reference and whether it is recent enough to be used.
+func (r *outdoorReference) get(now time.Time) (float64, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.updated.IsZero() || now.Sub(r.updated) > maxOutdoorReferenceAge {
+		return 0, false
+	}
+	return r.absoluteHumidity, true
+}
+
+// UpdateOutdoorReference parses an outdoor reading in the JSON payload format of this bot and uses it as reference
+// for the ventilation recommendation.
+func (station *WeatherBotAdaptors) UpdateOutdoorReference(payload []byte) {
+	var m Measurement
+	if err := json.Unmarshal(payload, &m); err != nil {
+		slog.Warn("Could not parse outdoor reference", "err", err)
+		return
+	}
+
+	if len(m.Errors) > 0 || m.Humidity < 0 {
+		slog.Warn("Ignoring erroneous outdoor reference")
+		return
+	}
+
+	station.outdoor.set(absoluteHumidity(float64(m.Temperature), float64(m.Humidity)), time.Now())
+}
+
+// shouldVentilate recommends ventilating if the outdoor air holds less water than the indoor air, by at least the
+// given margin in g/m³.
+func shouldVentilate(indoorAbsoluteHumidity, outdoorAbsoluteHumidity, margin float64) bool {
+	return outdoorAbsoluteHumidity+margin < indoorAbsoluteHumidity
+}
@@ -0,0 +1,47 @@
+package internal
+
+import (
+	"testing"
+	"time"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+func Test_shouldVentilate(t *testing.T) {
+	if !shouldVentilate(10, 5, 1) {
+		t.Errorf("expected ventilating to be recommended for drier outdoor air")
+	}
+	if shouldVentilate(10, 9.5, 1) {
+		t.Errorf("expected no recommendation within the margin")
+	}
+}
+
+func TestOutdoorReference_get(t *testing.T) {
+	var ref outdoorReference
+	now := time.Now()
+	if _, ok := ref.get(now); ok {
+		t.Errorf("expected no reference before receiving one")
+	}
+
+	ref.set(5, now)
+	if val, ok := ref.get(now.Add(time.Minute)); !ok || val != 5 {
+		t.Errorf("get() = %f, %v, want 5, true", val, ok)
+	}
+	if _, ok := ref.get(now.Add(2 * time.Hour)); ok {
+		t.Errorf("expected stale reference to be ignored")
+	}
+}
+
+func TestWeatherBotAdaptors_publishVentilation(t *testing.T) {
+	conf := config.DefaultConfig()
+	conf.Topic = "sensors/test"
+	conf.VentilationReferenceTopic = "sensors/outdoor"
+	station, mqttAdaptor := newTestStation(conf)
+
+	station.UpdateOutdoorReference([]byte(`{"temp": 5, "humidity": 60}`))
+	station.readAndPublishMeasurement()
+
+	if mqttAdaptor.Topic != "sensors/test/ventilate" || string(mqttAdaptor.Msg) != "false" {
+		t.Errorf("published %s -> %s, want sensors/test/ventilate -> false", mqttAdaptor.Topic, mqttAdaptor.Msg)
+	}
+}
@@ -0,0 +1,41 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// VoltageSource supplies the voltage of an external source, e.g. the supply voltage of a battery powered node,
+// that is published alongside the readings of the sensor.
+type VoltageSource interface {
+	Voltage() (float64, error)
+}
+
+// FileVoltageSource reads the voltage from a file containing a single number, e.g. a sysfs attribute of an ADC
+// driver or a file that is periodically updated by a script. The value is multiplied by the scale.
+type FileVoltageSource struct {
+	path  string
+	scale float64
+}
+
+func NewFileVoltageSource(path string, scale float64) *FileVoltageSource {
+	if scale == 0 {
+		scale = 1
+	}
+	return &FileVoltageSource{path: path, scale: scale}
+}
+
+func (s *FileVoltageSource) Voltage() (float64, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return 0, err
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("could not parse voltage from %s: %v", s.path, err)
+	}
+	return value * s.scale, nil
+}
@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFileVoltageSource_Voltage(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "in_voltage0_raw")
+	if err := os.WriteFile(file, []byte("2048\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := NewFileVoltageSource(file, 0.00244).Voltage()
+	if err != nil {
+		t.Fatalf("Voltage() error = %v", err)
+	}
+	if math.Abs(got-4.997) > 0.001 {
+		t.Errorf("Voltage() = %f, want %f", got, 4.997)
+	}
+
+	if err := os.WriteFile(file, []byte("n/a"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewFileVoltageSource(file, 1).Voltage(); err == nil {
+		t.Errorf("Voltage() expected error for invalid content")
+	}
+}
@@ -0,0 +1,99 @@
+package internal
+
+import (
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// systemdWatchdog notifies systemd about liveness when running as a service with WatchdogSec set. Pings are sent
+// at half of the watchdog timeout as long as the last successful reading is recent, so systemd restarts the bot
+// if the read loop hangs.
+type systemdWatchdog struct {
+	socket   string
+	period   time.Duration
+	maxAge   int64
+	lastRead int64
+}
+
+// newSystemdWatchdog returns nil if the process is not supervised by a systemd watchdog.
+func newSystemdWatchdog(maxAge time.Duration) *systemdWatchdog {
+	socket := os.Getenv("NOTIFY_SOCKET")
+	usec, err := strconv.ParseInt(os.Getenv("WATCHDOG_USEC"), 10, 64)
+	if len(socket) == 0 || err != nil || usec <= 0 {
+		return nil
+	}
+
+	if pid := os.Getenv("WATCHDOG_PID"); len(pid) > 0 && pid != strconv.Itoa(os.Getpid()) {
+		return nil
+	}
+
+	return &systemdWatchdog{
+		socket: socket,
+		period: time.Duration(usec) * time.Microsecond / 2,
+		maxAge: int64(maxAge),
+	}
+}
+
+// setMaxAge changes the age of the last successful reading up to which systemd is notified.
+func (w *systemdWatchdog) setMaxAge(maxAge time.Duration) {
+	if w == nil {
+		return
+	}
+	atomic.StoreInt64(&w.maxAge, int64(maxAge))
+}
+
+// success records a successful reading.
+func (w *systemdWatchdog) success(now time.Time) {
+	if w == nil {
+		return
+	}
+	atomic.StoreInt64(&w.lastRead, now.UnixNano())
+}
+
+// start signals readiness to systemd and starts pinging the watchdog in the background.
+func (w *systemdWatchdog) start() {
+	if w == nil {
+		return
+	}
+
+	slog.Info("Notifying systemd watchdog", "period", w.period)
+	w.success(time.Now())
+	if err := sdNotify(w.socket, "READY=1"); err != nil {
+		slog.Warn("Could not notify systemd", "err", err)
+	}
+
+	go func() {
+		for now := range time.Tick(w.period) {
+			w.ping(now)
+		}
+	}()
+}
+
+func (w *systemdWatchdog) ping(now time.Time) bool {
+	lastRead := time.Unix(0, atomic.LoadInt64(&w.lastRead))
+	if now.Sub(lastRead) > time.Duration(atomic.LoadInt64(&w.maxAge)) {
+		slog.Warn("No recent successful reading, not notifying systemd watchdog", "last_read", lastRead)
+		return false
+	}
+
+	if err := sdNotify(w.socket, "WATCHDOG=1"); err != nil {
+		slog.Warn("Could not notify systemd watchdog", "err", err)
+		return false
+	}
+	return true
+}
+
+func sdNotify(socket, state string) error {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	_, err = conn.Write([]byte(state))
+	return err
+}
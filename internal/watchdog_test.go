@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestSystemdWatchdog(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "notify")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	os.Setenv("NOTIFY_SOCKET", socket)
+	os.Setenv("WATCHDOG_USEC", "10000000")
+	defer os.Unsetenv("NOTIFY_SOCKET")
+	defer os.Unsetenv("WATCHDOG_USEC")
+
+	watchdog := newSystemdWatchdog(time.Minute)
+	if watchdog == nil {
+		t.Fatal("expected watchdog to be enabled")
+	}
+	if watchdog.period != 5*time.Second {
+		t.Errorf("period = %v, want %v", watchdog.period, 5*time.Second)
+	}
+
+	now := time.Now()
+	watchdog.success(now)
+	if !watchdog.ping(now.Add(30 * time.Second)) {
+		t.Errorf("ping() expected to notify for a recent reading")
+	}
+
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := string(buf[:n]); got != "WATCHDOG=1" {
+		t.Errorf("received %q, want %q", got, "WATCHDOG=1")
+	}
+
+	if watchdog.ping(now.Add(2 * time.Minute)) {
+		t.Errorf("ping() expected to not notify for a stale reading")
+	}
+}
+
+func TestSystemdWatchdog_Disabled(t *testing.T) {
+	os.Unsetenv("NOTIFY_SOCKET")
+	if watchdog := newSystemdWatchdog(time.Minute); watchdog != nil {
+		t.Errorf("expected watchdog to be disabled")
+	}
+}
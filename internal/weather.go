@@ -0,0 +1,50 @@
+package internal
+
+import (
+	"encoding/json"
+
+	"github.com/soerenschneider/gobot-bme280/internal/config"
+)
+
+// WeatherTopic is the subtopic of the configured topic the composite weather object is published to.
+const WeatherTopic = "weather"
+
+// Weather aggregates the measurement and all derived values computed for a reading, so consumers don't have to
+// assemble them from the subtopics.
+type Weather struct {
+	Placement    string             `json:"placement"`
+	Timestamp    int64              `json:"timestamp"`
+	Measurement  Measurement        `json:"measurement"`
+	Units        map[string]string  `json:"units"`
+	Deltas       map[string]float64 `json:"deltas,omitempty"`
+	Comfort      map[string]string  `json:"comfort,omitempty"`
+	ComfortIndex *float64           `json:"comfort_index,omitempty"`
+}
+
+func NewWeather(conf config.Config, m Measurement) Weather {
+	units := map[string]string{}
+	for _, field := range NewSchema(conf).Measurements {
+		units[field.Field] = field.Unit
+	}
+
+	return Weather{
+		Placement:   conf.Placement,
+		Timestamp:   m.Timestamp,
+		Measurement: m,
+		Units:       units,
+	}
+}
+
+func (w *Weather) addDeltas(deltas []delta) {
+	if len(deltas) == 0 {
+		return
+	}
+	w.Deltas = make(map[string]float64, len(deltas))
+	for _, d := range deltas {
+		w.Deltas[d.name] = d.value
+	}
+}
+
+func (w Weather) AsJson() ([]byte, error) {
+	return json.Marshal(w)
+}